@@ -2,10 +2,14 @@ package assembly
 
 import (
 	"context"
+	"time"
 
 	"github.com/txix-open/isp-kit/rc"
+	"github.com/txix-open/isp-kit/worker"
 
 	"isp-system-service/conf"
+	"isp-system-service/repository"
+	"isp-system-service/service/secure"
 
 	"github.com/pkg/errors"
 	"github.com/txix-open/isp-kit/app"
@@ -17,22 +21,53 @@ import (
 	"github.com/txix-open/isp-kit/log"
 )
 
+const (
+	keyRotationInterval = time.Minute
+	tokenSweepInterval  = 5 * time.Minute
+	usageFlushInterval  = 10 * time.Second
+	eventRelayInterval  = time.Second
+)
+
 type Assembly struct {
-	boot   *bootstrap.Bootstrap
-	db     *dbrx.Client
-	server *grpc.Server
-	logger *log.Adapter
+	boot          *bootstrap.Bootstrap
+	db            *dbrx.Client
+	server        *grpc.Server
+	cacheListener *secure.CacheListener
+	logger        *log.Adapter
+
+	keyRotationJob *upgradableJob
+	keyRotation    *worker.Worker
+	tokenSweepJob  *upgradableJob
+	tokenSweep     *worker.Worker
+	usageFlushJob  *upgradableJob
+	usageFlush     *worker.Worker
+	eventRelayJob  *upgradableJob
+	eventRelay     *worker.Worker
 }
 
 func New(boot *bootstrap.Bootstrap) (*Assembly, error) {
 	logger := boot.App.Logger()
 	dbCli := dbrx.New(logger, dbx.WithMigrationRunner(boot.MigrationsDir, logger))
 	server := grpc.NewServer()
+	cacheListener := secure.NewCacheListener(repository.NewNotificationListener(dbCli), logger)
+	keyRotationJob := &upgradableJob{}
+	tokenSweepJob := &upgradableJob{}
+	usageFlushJob := &upgradableJob{}
+	eventRelayJob := &upgradableJob{}
 	return &Assembly{
-		boot:   boot,
-		db:     dbCli,
-		server: server,
-		logger: logger,
+		boot:           boot,
+		db:             dbCli,
+		server:         server,
+		cacheListener:  cacheListener,
+		logger:         logger,
+		keyRotationJob: keyRotationJob,
+		keyRotation:    worker.New(keyRotationJob, worker.WithInterval(keyRotationInterval)),
+		tokenSweepJob:  tokenSweepJob,
+		tokenSweep:     worker.New(tokenSweepJob, worker.WithInterval(tokenSweepInterval)),
+		usageFlushJob:  usageFlushJob,
+		usageFlush:     worker.New(usageFlushJob, worker.WithInterval(usageFlushInterval)),
+		eventRelayJob:  eventRelayJob,
+		eventRelay:     worker.New(eventRelayJob, worker.WithInterval(eventRelayInterval)),
 	}, nil
 }
 
@@ -50,7 +85,25 @@ func (a *Assembly) ReceiveConfig(ctx context.Context, remoteConfig []byte) error
 	}
 
 	locator := NewLocator(a.db, a.logger)
-	config := locator.Config(newCfg)
+	config, err := locator.Config(newCfg)
+	if err != nil {
+		a.boot.Fatal(errors.WithMessage(err, "create locator config"))
+	}
+
+	err = config.Token.HashLegacyTokens(a.boot.App.Context())
+	if err != nil {
+		a.boot.Fatal(errors.WithMessage(err, "hash legacy tokens"))
+	}
+
+	err = config.SigningKey.CheckEncryption(a.boot.App.Context())
+	if err != nil {
+		a.boot.Fatal(errors.WithMessage(err, "check signing key encryption"))
+	}
+
+	err = config.SigningKey.RotateIfNeeded(a.boot.App.Context())
+	if err != nil {
+		a.boot.Fatal(errors.WithMessage(err, "rotate signing keys"))
+	}
 
 	err = config.Baseline.Do(a.boot.App.Context())
 	if err != nil {
@@ -58,6 +111,11 @@ func (a *Assembly) ReceiveConfig(ctx context.Context, remoteConfig []byte) error
 	}
 
 	a.server.Upgrade(config.Handler)
+	a.cacheListener.Upgrade(config.SecureCache)
+	a.keyRotationJob.Upgrade(config.KeyRotationJob)
+	a.tokenSweepJob.Upgrade(config.TokenSweeper)
+	a.usageFlushJob.Upgrade(config.UsageFlushJob)
+	a.eventRelayJob.Upgrade(config.EventRelay)
 
 	return nil
 }
@@ -72,6 +130,17 @@ func (a *Assembly) Runners() []app.Runner {
 		app.RunnerFunc(func(ctx context.Context) error {
 			return a.boot.ClusterCli.Run(ctx, eventHandler)
 		}),
+		app.RunnerFunc(func(ctx context.Context) error {
+			a.cacheListener.Run(ctx)
+			return nil
+		}),
+		app.RunnerFunc(func(ctx context.Context) error {
+			a.keyRotation.Run(ctx)
+			a.tokenSweep.Run(ctx)
+			a.usageFlush.Run(ctx)
+			a.eventRelay.Run(ctx)
+			return nil
+		}),
 	}
 }
 
@@ -82,6 +151,15 @@ func (a *Assembly) Closers() []app.Closer {
 			a.server.Shutdown()
 			return nil
 		}),
+		app.CloserFunc(func() error {
+			a.keyRotation.Shutdown()
+			a.tokenSweep.Shutdown()
+			a.usageFlush.Shutdown()
+			a.eventRelay.Shutdown()
+			// usages collected after the last flush
+			a.usageFlushJob.Do(context.Background())
+			return nil
+		}),
 		a.db,
 	}
 }
@@ -0,0 +1,26 @@
+package assembly
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/txix-open/isp-kit/worker"
+)
+
+// upgradableJob runs the job built from the latest remote config,
+// workers are started by runners before the config is received, so until then it does nothing
+type upgradableJob struct {
+	job atomic.Pointer[worker.Job]
+}
+
+func (j *upgradableJob) Upgrade(job worker.Job) {
+	j.job.Store(&job)
+}
+
+func (j *upgradableJob) Do(ctx context.Context) {
+	job := j.job.Load()
+	if job == nil {
+		return
+	}
+	(*job).Do(ctx)
+}
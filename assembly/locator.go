@@ -1,15 +1,20 @@
 package assembly
 
 import (
+	"time"
+
 	"isp-system-service/conf"
 	"isp-system-service/controller"
 	"isp-system-service/repository"
 	"isp-system-service/routes"
 	"isp-system-service/service"
 	"isp-system-service/service/baseline"
+	"isp-system-service/service/jwt"
 	"isp-system-service/service/secure"
+	"isp-system-service/service/tokenhash"
 	"isp-system-service/transaction"
 
+	"github.com/pkg/errors"
 	"github.com/txix-open/isp-kit/db"
 	"github.com/txix-open/isp-kit/grpc"
 	"github.com/txix-open/isp-kit/grpc/endpoint"
@@ -35,27 +40,52 @@ func NewLocator(db DB, logger log.Logger) Locator {
 }
 
 type Config struct {
-	Handler  *grpc.Mux
-	Baseline baseline.Service
+	Handler        *grpc.Mux
+	Baseline       baseline.Service
+	Token          service.Token
+	SecureCache    *secure.Cache
+	SigningKey     service.SigningKey
+	KeyRotationJob service.SigningKeyRotationJob
+	TokenSweeper   service.TokenSweeper
+	UsageFlushJob  secure.UsageFlushJob
+	EventRelay     service.ChangeEventRelay
 }
 
-func (l Locator) Config(cfg conf.Remote) Config {
+func (l Locator) Config(cfg conf.Remote) (*Config, error) {
+	keyCipher, err := jwt.NewCipher(cfg.Token.Jwt.KeyEncryptionSecret)
+	if err != nil {
+		return nil, errors.WithMessage(err, "new signing key cipher")
+	}
+
 	txManager := transaction.NewManager(l.db)
 	accessListRep := repository.NewAccessList(l.db)
 	applicationRep := repository.NewApplication(l.db)
 	domainRep := repository.NewDomain(l.db)
 	appGroupRep := repository.NewAppGroup(l.db)
 	tokenRep := repository.NewToken(l.db)
+	notificationRep := repository.NewNotification(l.db)
+	signingKeyRep := repository.NewSigningKey(l.db)
+	rateLimitRep := repository.NewRateLimit(l.db)
+	auditRep := repository.NewAudit(l.db)
+	changeEventRep := repository.NewChangeEvent(l.db)
+	tokenHasher := tokenhash.New(cfg.Token.HashSecret)
 
-	secureService := secure.NewService(tokenRep, accessListRep)
-	accessListService := service.NewAccessList(txManager, accessListRep, applicationRep)
-	applicationService := service.NewApplication(txManager, applicationRep, domainRep, appGroupRep, tokenRep)
-	domainService := service.NewDomain(domainRep)
-	serviceService := service.NewService(domainRep, appGroupRep)
+	jwtKeys := jwt.NewKeyStore(signingKeyRep, keyCipher)
+	secureCache := secure.NewCache(cfg.Cache.MaxSize, time.Duration(cfg.Cache.TtlSeconds)*time.Second, jwtKeys)
+	invalidator := secure.NewInvalidator(secureCache, notificationRep, l.logger)
 
-	jwtService := service.NewTokenSource()
-	tokenService := service.NewToken(jwtService, applicationService, txManager,
-		applicationRep, domainRep, appGroupRep, tokenRep,
+	usageTracker := secure.NewUsageTracker(tokenRep)
+	secureService := secure.NewService(tokenRep, applicationRep, appGroupRep, accessListRep, rateLimitRep, secureCache, tokenHasher, jwtKeys, usageTracker)
+	accessListService := service.NewAccessList(txManager, accessListRep, applicationRep, appGroupRep, domainRep, invalidator)
+	applicationService := service.NewApplication(txManager, applicationRep, domainRep, appGroupRep, tokenRep, invalidator, tokenHasher)
+	domainService := service.NewDomain(txManager, domainRep, invalidator)
+	serviceService := service.NewService(domainRep, appGroupRep, invalidator)
+
+	jwtService := service.NewTokenSource(cfg.Token.Jwt.Enabled, jwtKeys)
+	tokenService := service.NewToken(
+		time.Duration(cfg.Token.RotationOverlapHours)*time.Hour,
+		jwtService, applicationService, txManager,
+		applicationRep, domainRep, appGroupRep, tokenRep, invalidator, tokenHasher,
 	)
 
 	secureController := controller.NewSecure(secureService)
@@ -65,23 +95,95 @@ func (l Locator) Config(cfg conf.Remote) Config {
 	serviceController := controller.NewService(serviceService)
 	tokenController := controller.NewToken(tokenService)
 
-	appGroupService := service.NewAppGroup(appGroupRep)
+	appGroupService := service.NewAppGroup(txManager, appGroupRep, invalidator)
 	appGroupController := controller.NewAppGroup(appGroupService)
+
+	roleRep := repository.NewRole(l.db)
+	roleService := service.NewRole(txManager, roleRep, applicationRep, appGroupRep, invalidator)
+	roleController := controller.NewRole(roleService)
+
+	signingKeyService := service.NewSigningKey(signingKeyRotation(cfg.Token.Jwt), txManager, signingKeyRep, keyCipher, invalidator, l.logger)
+	signingKeyController := controller.NewSigningKey(signingKeyService)
+
+	tokenSweeper := service.NewTokenSweeper(
+		cfg.Token.Sweep.Enabled,
+		time.Duration(cfg.Token.Sweep.GracePeriodHours)*time.Hour,
+		txManager,
+		tokenRep,
+		l.logger,
+	)
+	tokenSweeperController := controller.NewTokenSweeper(tokenSweeper)
+
+	rateLimitService := service.NewRateLimit(txManager, rateLimitRep, applicationRep, appGroupRep, invalidator)
+	rateLimitController := controller.NewRateLimit(rateLimitService)
+
+	auditService := service.NewAudit(auditRep)
+	auditController := controller.NewAudit(auditService)
+
+	changeEventBroker := service.NewChangeEventBroker()
+	changeEventRelay := service.NewChangeEventRelay(
+		time.Duration(cfg.Events.RetentionHours)*time.Hour,
+		txManager,
+		changeEventRep,
+		changeEventBroker,
+		l.logger,
+	)
+	changeEventService := service.NewChangeEvents(changeEventRep, changeEventBroker)
+	changeEventController := controller.NewChangeEvent(changeEventService)
+
+	registryService := service.NewRegistry(txManager, invalidator)
+	registryController := controller.NewRegistry(registryService)
 	c := routes.Controllers{
-		Secure:      secureController,
-		AccessList:  accessListController,
-		Domain:      domainController,
-		Service:     serviceController,
-		Application: applicationController,
-		Token:       tokenController,
-		AppGroup:    appGroupController,
+		Secure:       secureController,
+		AccessList:   accessListController,
+		Domain:       domainController,
+		Service:      serviceController,
+		Application:  applicationController,
+		Token:        tokenController,
+		AppGroup:     appGroupController,
+		Role:         roleController,
+		SigningKey:   signingKeyController,
+		TokenSweeper: tokenSweeperController,
+		RateLimit:    rateLimitController,
+		Audit:        auditController,
+		ChangeEvent:  changeEventController,
+		Registry:     registryController,
 	}
 	mapper := endpoint.DefaultWrapper(l.logger, grpclog.Log(l.logger, true))
 	server := routes.Handler(mapper, c)
 
-	baselineService := baseline.NewService(cfg.Baseline, txManager, l.logger)
-	return Config{
-		Handler:  server,
-		Baseline: baselineService,
+	baselineService := baseline.NewService(
+		cfg.Baseline,
+		cfg.Desired,
+		txManager,
+		registryService,
+		invalidator,
+		tokenHasher,
+		jwtService,
+		l.logger,
+	)
+	return &Config{
+		Handler:        server,
+		Baseline:       baselineService,
+		Token:          tokenService,
+		SecureCache:    secureCache,
+		SigningKey:     signingKeyService,
+		KeyRotationJob: service.NewSigningKeyRotationJob(signingKeyService, l.logger),
+		TokenSweeper:   tokenSweeper,
+		UsageFlushJob:  secure.NewUsageFlushJob(usageTracker, l.logger),
+		EventRelay:     changeEventRelay,
+	}, nil
+}
+
+func signingKeyRotation(cfg conf.Jwt) service.SigningKeyRotation {
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = jwt.AlgorithmEdDsa
+	}
+	return service.SigningKeyRotation{
+		Algorithm: algorithm,
+		Interval:  time.Duration(cfg.Rotation.IntervalHours) * time.Hour,
+		Overlap:   time.Duration(cfg.Rotation.OverlapHours) * time.Hour,
+		Bootstrap: cfg.Enabled,
 	}
 }
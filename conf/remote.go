@@ -20,9 +20,86 @@ func init() {
 type Remote struct {
 	Database dbx.Config `schema:"Настройка базы данных"`
 	Baseline Baseline
+	Cache    Cache     `schema:"Кэш аутентификации и авторизации"`
+	Token    Token     `schema:"Токены приложений"`
+	Events   Events    `schema:"События изменения реестра"`
+	Desired  Desired   `schema:"Желаемое состояние реестра,объявленные группы приложений, приложения и токены приводятся к нему при каждом получении конфигурации и не изменяются через API"`
 	LogLevel log.Level `schemaGen:"logLevel" schema:"Уровень логирования"`
 }
 
+type Token struct {
+	HashSecret           string `validate:"required" schema:"Секрет хэширования токенов,в базе данных хранится HMAC-SHA256 токена; при изменении все выпущенные токены становятся недействительными"`
+	Jwt                  Jwt    `schema:"Токены в формате JWT"`
+	Sweep                Sweep  `schema:"Очистка истекших токенов"`
+	RotationOverlapHours int    `validate:"min=0" schema:"Период действия предыдущего токена при ротации в часах,используется, если период не указан в запросе"`
+}
+
+type Sweep struct {
+	Enabled          bool `schema:"Удалять истекшие токены,очистка выполняется периодически одним из экземпляров сервиса"`
+	GracePeriodHours int  `validate:"min=0" schema:"Период хранения истекших токенов в часах,токены удаляются по истечении периода после окончания срока действия"`
+}
+
+type Jwt struct {
+	Enabled             bool        `schema:"Выпускать JWT,если выключено - выпускаются непрозрачные токены, ранее выпущенные JWT продолжают проверяться"`
+	Algorithm           string      `validate:"omitempty,oneof=RS256 EdDSA" schema:"Алгоритм новых ключей подписи,RS256 или EdDSA, по умолчанию EdDSA"`
+	KeyEncryptionSecret string      `validate:"required_if=Enabled true" schema:"Секрет шифрования ключей подписи,обязателен, если выпуск JWT включен или в базе данных есть действующие ключи; закрытые ключи хранятся в базе данных в зашифрованном виде; при изменении ключи становятся нечитаемыми"`
+	Rotation            JwtRotation `schema:"Плановая смена ключей подписи"`
+}
+
+type JwtRotation struct {
+	IntervalHours int `validate:"min=0" schema:"Период смены активного ключа в часах,0 - плановая смена выключена"`
+	OverlapHours  int `validate:"min=0" schema:"Период проверки токенов предыдущим ключом в часах,по истечении ключ выводится из оборота; 0 - ключи выводятся только вручную"`
+}
+
+type Events struct {
+	RetentionHours int `validate:"min=0" schema:"Период хранения событий в часах,по истечении события удаляются; 0 - события не удаляются"`
+}
+
+type Cache struct {
+	MaxSize    int `validate:"min=0" schema:"Максимальное количество записей,0 - кэш выключен"`
+	TtlSeconds int `validate:"min=0" schema:"Время жизни записи в секундах,0 - кэш выключен"`
+}
+
 type Baseline struct {
-	InitialAdminUiToken string
+	InitialAdminUiToken string                `schema:"Начальный токен администратора,выпускается приложению admin с доступом к admin/auth/login, если приложения с идентификатором 1 нет в списке начальных приложений - оно создается в домене root и группе rootService"`
+	Applications        []BaselineApplication `validate:"dive" schema:"Начальные приложения,создаются или обновляются при каждом получении конфигурации; правила доступа и токены только добавляются, ранее выданные права не отзываются"`
+}
+
+type BaselineApplication struct {
+	Domain      string              `validate:"required" schema:"Домен,создается, если не существует"`
+	AppGroup    string              `validate:"required" schema:"Группа приложений,создается в домене, если не существует"`
+	Id          int                 `validate:"required" schema:"Идентификатор приложения"`
+	Name        string              `validate:"required" schema:"Название приложения"`
+	Description string              `schema:"Описание приложения"`
+	Type        string              `validate:"required,oneof=SYSTEM MOBILE" schema:"Тип приложения,SYSTEM или MOBILE"`
+	AccessList  []DesiredAccessRule `validate:"dive" schema:"Правила доступа приложения"`
+	Tokens      []string            `schema:"Начальные токены приложения,сохраняются, если еще не выпущены"`
+}
+
+type Desired struct {
+	Enabled   bool              `schema:"Приводить реестр к желаемому состоянию,если выключено - ранее объявленные объекты остаются защищенными от изменений"`
+	AppGroups []DesiredAppGroup `validate:"dive" schema:"Группы приложений"`
+}
+
+type DesiredAppGroup struct {
+	Domain       string               `validate:"required" schema:"Домен,создается, если не существует"`
+	Name         string               `validate:"required" schema:"Название группы"`
+	Description  string               `schema:"Описание группы"`
+	AccessList   []DesiredAccessRule  `validate:"dive" schema:"Правила доступа группы"`
+	Applications []DesiredApplication `validate:"dive" schema:"Приложения группы,приложение, объявленное в другой группе, переносится в эту группу"`
+}
+
+type DesiredApplication struct {
+	Id          int                 `validate:"required" schema:"Идентификатор приложения"`
+	Name        string              `validate:"required" schema:"Название приложения"`
+	Description string              `schema:"Описание приложения"`
+	Type        string              `validate:"required,oneof=SYSTEM MOBILE" schema:"Тип приложения,SYSTEM или MOBILE"`
+	AccessList  []DesiredAccessRule `validate:"dive" schema:"Правила доступа приложения"`
+	TokenIdList []string            `schema:"Идентификаторы токенов приложения,токены выпускаются через API, объявленные токены нельзя отозвать или ротировать"`
+}
+
+type DesiredAccessRule struct {
+	HttpMethod string `schema:"HTTP метод,пусто - любой метод"`
+	Method     string `validate:"required" schema:"Метод или шаблон метода"`
+	Value      bool   `schema:"Доступ разрешен"`
 }
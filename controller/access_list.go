@@ -12,11 +12,15 @@ import (
 )
 
 type AccessListService interface {
-	GetById(ctx context.Context, appId int) ([]domain.MethodInfo, error)
+	GetById(ctx context.Context, req domain.AccessListGetByIdRequest) ([]domain.AccessListItem, error)
 	SetOne(ctx context.Context, request domain.AccessListSetOneRequest) (*domain.AccessListSetOneResponse, error)
 	SetList(ctx context.Context, req domain.AccessListSetListRequest) ([]domain.MethodInfo, error)
 	DeleteList(ctx context.Context, req domain.AccessListDeleteListRequest) error
 	DeleteListWithMethods(ctx context.Context, req domain.AccessListDeleteV2ListRequest) error
+	GetByAppGroupId(ctx context.Context, appGroupId int) ([]domain.MethodInfo, error)
+	SetListForAppGroup(ctx context.Context, req domain.AccessListSetListForAppGroupRequest) ([]domain.MethodInfo, error)
+	GetByDomainId(ctx context.Context, domainId int) ([]domain.MethodInfo, error)
+	SetListForDomain(ctx context.Context, req domain.AccessListSetListForDomainRequest) ([]domain.MethodInfo, error)
 }
 
 type AccessList struct {
@@ -33,16 +37,16 @@ func NewAccessList(service AccessListService) AccessList {
 //
 //	@Tags			accessList
 //	@Summary		Получить список доступности методов для приложения
-//	@Description	Возвращает список методов для приложения, для которых заданы настройки доступа
+//	@Description	Возвращает действующий для приложения список правил: собственные правила, правила ролей, группы приложений и домена. Для каждого правила указан источник `source` (`application`, `application_role`, `application_group`, `application_group_role`, `domain`) и его идентификатор `sourceId`. Если передан `endpoint`, то правило, которое будет применено при авторизации, помечается флагом `matched`
 //	@Accept			json
 //	@Produce		json
-//	@Param			body	body		domain.Identity		false	"идентификатор приложения"
-//	@Success		200		{array}		domain.MethodInfo	"список доступности методов"
+//	@Param			body	body		domain.AccessListGetByIdRequest	false	"идентификатор приложения"
+//	@Success		200		{array}		domain.AccessListItem			"список доступности методов"
 //	@Failure		404		{object}	apierrors.Error
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/access_list/get_by_id [POST]
-func (c AccessList) GetById(ctx context.Context, req domain.Identity) ([]domain.MethodInfo, error) {
-	result, err := c.service.GetById(ctx, req.Id)
+func (c AccessList) GetById(ctx context.Context, req domain.AccessListGetByIdRequest) ([]domain.AccessListItem, error) {
+	result, err := c.service.GetById(ctx, req)
 	switch {
 	case errors.Is(err, domain.ErrApplicationNotFound):
 		return nil, apierrors.New(
@@ -62,17 +66,26 @@ func (c AccessList) GetById(ctx context.Context, req domain.Identity) ([]domain.
 //
 //	@Tags			accessList
 //	@Summary		Настроить доступность метода для приложения
-//	@Description	Возвращает количество измененных строк
+//	@Description	Возвращает количество измененных строк. В поле `method` допускаются шаблоны: префиксные (`admin/*`) и с подстановками (`admin/**/get_*`)
 //	@Accept			json
 //	@Produce		json
 //	@Param			body	body		domain.AccessListSetOneRequest	false	"объект для настройки доступа"
 //	@Success		200		{object}	domain.AccessListSetOneResponse	"количество измененных строк"
+//	@Failure		400		{object}	apierrors.Error
 //	@Failure		404		{object}	apierrors.Error
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/access_list/set_one [POST]
 func (c AccessList) SetOne(ctx context.Context, req domain.AccessListSetOneRequest) (*domain.AccessListSetOneResponse, error) {
 	result, err := c.service.SetOne(ctx, req)
 	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	case errors.Is(err, domain.ErrInvalidMethodPattern):
+		return nil, apierrors.NewBusinessError(
+			domain.ErrCodeInvalidMethodPattern,
+			fmt.Sprintf("invalid method pattern %s", req.Method),
+			err,
+		)
 	case errors.Is(err, domain.ErrApplicationNotFound):
 		return nil, apierrors.New(
 			codes.NotFound,
@@ -91,17 +104,22 @@ func (c AccessList) SetOne(ctx context.Context, req domain.AccessListSetOneReque
 //
 //	@Tags			accessList
 //	@Summary		Настроить доступность списка методов для приложения
-//	@Description	Возвращает список методов для приложения, для которых заданы настройки доступа
+//	@Description	Возвращает список методов для приложения, для которых заданы настройки доступа. В поле `method` допускаются шаблоны: префиксные (`admin/*`) и с подстановками (`admin/**/get_*`)
 //	@Accept			json
 //	@Produce		json
 //	@Param			body	body		domain.AccessListSetListRequest	false	"объект настройки доступа"
 //	@Success		200		{array}		domain.MethodInfo				"список доступности методов"
+//	@Failure		400		{object}	apierrors.Error
 //	@Failure		404		{object}	apierrors.Error
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/access_list/set_list [POST]
 func (c AccessList) SetList(ctx context.Context, req domain.AccessListSetListRequest) ([]domain.MethodInfo, error) {
 	result, err := c.service.SetList(ctx, req)
 	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	case errors.Is(err, domain.ErrInvalidMethodPattern):
+		return nil, invalidMethodPatternError(err)
 	case errors.Is(err, domain.ErrApplicationNotFound):
 		return nil, apierrors.New(
 			codes.NotFound,
@@ -131,6 +149,8 @@ func (c AccessList) SetList(ctx context.Context, req domain.AccessListSetListReq
 func (c AccessList) DeleteList(ctx context.Context, req domain.AccessListDeleteListRequest) error {
 	err := c.service.DeleteList(ctx, req)
 	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return managedByConfigError(err)
 	case errors.Is(err, domain.ErrApplicationNotFound):
 		return apierrors.New(
 			codes.NotFound,
@@ -158,6 +178,8 @@ func (c AccessList) DeleteList(ctx context.Context, req domain.AccessListDeleteL
 func (c AccessList) DeleteListWithMethods(ctx context.Context, req domain.AccessListDeleteV2ListRequest) error {
 	err := c.service.DeleteListWithMethods(ctx, req)
 	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return managedByConfigError(err)
 	case errors.Is(err, domain.ErrApplicationNotFound):
 		return apierrors.New(
 			codes.NotFound,
@@ -169,3 +191,124 @@ func (c AccessList) DeleteListWithMethods(ctx context.Context, req domain.Access
 		return err
 	}
 }
+
+// GetByApplicationGroupId godoc
+//
+//	@Tags			accessList
+//	@Summary		Получить список доступности методов для группы приложений
+//	@Description	Возвращает правила доступа группы приложений, которые наследуют все приложения группы
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.Identity		true	"идентификатор группы приложений"
+//	@Success		200		{array}		domain.MethodInfo	"список доступности методов"
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/access_list/get_by_application_group_id [POST]
+func (c AccessList) GetByApplicationGroupId(ctx context.Context, req domain.Identity) ([]domain.MethodInfo, error) {
+	result, err := c.service.GetByAppGroupId(ctx, req.Id)
+	switch {
+	case errors.Is(err, domain.ErrAppGroupNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeAppGroupNotFound,
+			fmt.Sprintf("application group with id %d not found", req.Id),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// SetListForApplicationGroup godoc
+//
+//	@Tags			accessList
+//	@Summary		Настроить доступность списка методов для группы приложений
+//	@Description	Правила группы применяются к приложению, если для него не нашлось подходящего собственного правила или правила его ролей. Возвращает список правил группы приложений
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.AccessListSetListForAppGroupRequest	true	"объект настройки доступа"
+//	@Success		200		{array}		domain.MethodInfo							"список доступности методов"
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/access_list/set_list_for_application_group [POST]
+func (c AccessList) SetListForApplicationGroup(
+	ctx context.Context,
+	req domain.AccessListSetListForAppGroupRequest,
+) ([]domain.MethodInfo, error) {
+	result, err := c.service.SetListForAppGroup(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	case errors.Is(err, domain.ErrInvalidMethodPattern):
+		return nil, invalidMethodPatternError(err)
+	case errors.Is(err, domain.ErrAppGroupNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeAppGroupNotFound,
+			fmt.Sprintf("application group with id %d not found", req.AppGroupId),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// GetByDomainId godoc
+//
+//	@Tags			accessList
+//	@Summary		Получить список доступности методов для домена
+//	@Description	Возвращает правила доступа домена, которые наследуют все приложения домена
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.Identity		true	"идентификатор домена"
+//	@Success		200		{array}		domain.MethodInfo	"список доступности методов"
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/access_list/get_by_domain_id [POST]
+func (c AccessList) GetByDomainId(ctx context.Context, req domain.Identity) ([]domain.MethodInfo, error) {
+	result, err := c.service.GetByDomainId(ctx, req.Id)
+	switch {
+	case errors.Is(err, domain.ErrDomainNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeDomainNotFound,
+			fmt.Sprintf("domain with id %d not found", req.Id),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// SetListForDomain godoc
+//
+//	@Tags			accessList
+//	@Summary		Настроить доступность списка методов для домена
+//	@Description	Правила домена применяются к приложению, если для него не нашлось подходящего правила на уровне приложения и группы приложений. Возвращает список правил домена
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.AccessListSetListForDomainRequest	true	"объект настройки доступа"
+//	@Success		200		{array}		domain.MethodInfo							"список доступности методов"
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/access_list/set_list_for_domain [POST]
+func (c AccessList) SetListForDomain(ctx context.Context, req domain.AccessListSetListForDomainRequest) ([]domain.MethodInfo, error) {
+	result, err := c.service.SetListForDomain(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidMethodPattern):
+		return nil, invalidMethodPatternError(err)
+	case errors.Is(err, domain.ErrDomainNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeDomainNotFound,
+			fmt.Sprintf("domain with id %d not found", req.DomainId),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
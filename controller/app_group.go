@@ -15,7 +15,8 @@ type AppGroupService interface {
 	Update(ctx context.Context, req domain.UpdateAppGroupRequest) (*domain.AppGroup, error)
 	DeleteList(ctx context.Context, req domain.IdListRequest) (*domain.DeleteResponse, error)
 	GetByIdList(ctx context.Context, idList []int) ([]domain.AppGroup, error)
-	GetAll(ctx context.Context) ([]domain.AppGroup, error)
+	GetAll(ctx context.Context, req domain.ListRequest) (*domain.AppGroupListResponse, error)
+	SetStatus(ctx context.Context, id int, status string, reason string) (*domain.AppGroup, error)
 }
 
 type AppGroup struct {
@@ -73,6 +74,8 @@ func (c AppGroup) Create(ctx context.Context, req domain.CreateAppGroupRequest)
 func (c AppGroup) Update(ctx context.Context, req domain.UpdateAppGroupRequest) (*domain.AppGroup, error) {
 	result, err := c.service.Update(ctx, req)
 	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
 	case errors.Is(err, domain.ErrAppGroupDuplicateName):
 		return nil, apierrors.New(
 			codes.AlreadyExists,
@@ -105,7 +108,13 @@ func (c AppGroup) Update(ctx context.Context, req domain.UpdateAppGroupRequest)
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/application_group/delete_list [POST]
 func (c AppGroup) DeleteList(ctx context.Context, req domain.IdListRequest) (*domain.DeleteResponse, error) {
-	return c.service.DeleteList(ctx, req)
+	result, err := c.service.DeleteList(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	default:
+		return result, err
+	}
 }
 
 // GetByIdList godoc
@@ -128,12 +137,88 @@ func (c AppGroup) GetByIdList(ctx context.Context, req domain.IdListRequest) ([]
 //
 //	@Tags			application_group
 //	@Summary		Получить группы приложений
-//	@Description	Возвращает все группы приложений
+//	@Description	Возвращает страницу групп приложений, отобранных по подстроке названия или описания и времени создания, и общее количество отобранных групп. По умолчанию группы упорядочены от новых к старым. Для получения следующей страницы в `cursor` передается `nextCursor` из предыдущего ответа с той же сортировкой
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{array}		domain.AppGroup
-//	@Failure		500	{object}	apierrors.Error
+//	@Param			body	body		domain.ListRequest	true	"Фильтр, сортировка и курсор страницы"
+//	@Success		200		{object}	domain.AppGroupListResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
 //	@Router			/application_group/get_all [POST]
-func (c AppGroup) GetAll(ctx context.Context) ([]domain.AppGroup, error) {
-	return c.service.GetAll(ctx)
+func (c AppGroup) GetAll(ctx context.Context, req domain.ListRequest) (*domain.AppGroupListResponse, error) {
+	result, err := c.service.GetAll(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidListRequest):
+		return nil, invalidListRequestError(err)
+	case err != nil:
+		return nil, err
+	default:
+		return result, nil
+	}
+}
+
+// Suspend godoc
+//
+//	@Tags			application_group
+//	@Summary		Приостановить группу приложений
+//	@Description	Временно блокирует все приложения группы без отзыва токенов: методы `/secure/authenticate` и `/secure/check` отклоняют их токены с причиной `application is suspended`. Причина и инициатор изменения сохраняются, инициатор берется из заголовка `x-admin-id`
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.ChangeStatusRequest	true	"Идентификатор группы приложений и причина"
+//	@Success		200		{object}	domain.AppGroup
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/application_group/suspend [POST]
+func (c AppGroup) Suspend(ctx context.Context, req domain.ChangeStatusRequest) (*domain.AppGroup, error) {
+	return c.setStatus(ctx, req.Id, domain.StatusSuspended, req.Reason)
+}
+
+// Disable godoc
+//
+//	@Tags			application_group
+//	@Summary		Отключить группу приложений
+//	@Description	Блокирует все приложения группы без отзыва токенов: методы `/secure/authenticate` и `/secure/check` отклоняют их токены с причиной `application is disabled`. Причина и инициатор изменения сохраняются, инициатор берется из заголовка `x-admin-id`
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.ChangeStatusRequest	true	"Идентификатор группы приложений и причина"
+//	@Success		200		{object}	domain.AppGroup
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/application_group/disable [POST]
+func (c AppGroup) Disable(ctx context.Context, req domain.ChangeStatusRequest) (*domain.AppGroup, error) {
+	return c.setStatus(ctx, req.Id, domain.StatusDisabled, req.Reason)
+}
+
+// Resume godoc
+//
+//	@Tags			application_group
+//	@Summary		Возобновить работу группы приложений
+//	@Description	Возвращает группу в статус `active`, токены приложений группы снова принимаются, если сами приложения активны
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.Identity	true	"Идентификатор группы приложений"
+//	@Success		200		{object}	domain.AppGroup
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/application_group/resume [POST]
+func (c AppGroup) Resume(ctx context.Context, req domain.Identity) (*domain.AppGroup, error) {
+	return c.setStatus(ctx, req.Id, domain.StatusActive, "")
+}
+
+func (c AppGroup) setStatus(ctx context.Context, id int, status string, reason string) (*domain.AppGroup, error) {
+	result, err := c.service.SetStatus(ctx, id, status, reason)
+	switch {
+	case errors.Is(err, domain.ErrAppGroupNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeAppGroupNotFound,
+			fmt.Sprintf("application group with id %d not found", id),
+			err,
+		)
+	default:
+		return result, err
+	}
 }
@@ -15,15 +15,17 @@ import (
 type ApplicationService interface {
 	GetById(ctx context.Context, appId int) (*domain.ApplicationWithTokens, error)
 	GetByToken(ctx context.Context, token string) (*domain.GetApplicationByTokenResponse, error)
-	GetByIdList(ctx context.Context, idList []int) ([]*domain.ApplicationWithTokens, error)
+	GetByIdList(ctx context.Context, req domain.ApplicationIdListRequest) (*domain.ApplicationWithTokensListResponse, error)
 	GetByServiceId(ctx context.Context, id int) ([]*domain.ApplicationWithTokens, error)
 	SystemTree(ctx context.Context, systemId int) ([]*domain.DomainWithService, error)
 	CreateUpdate(ctx context.Context, req domain.ApplicationCreateUpdateRequest) (*domain.ApplicationWithTokens, error)
 	Delete(ctx context.Context, idList []int) (int, error)
 	NextId(ctx context.Context) (int, error)
-	GetAll(ctx context.Context) ([]domain.Application, error)
+	GetAll(ctx context.Context, req domain.ListRequest) (*domain.ApplicationListResponse, error)
 	Create(ctx context.Context, req domain.CreateApplicationRequest) (*domain.ApplicationWithTokens, error)
 	Update(ctx context.Context, req domain.UpdateApplicationRequest) (*domain.ApplicationWithTokens, error)
+	SetAllowedCidrs(ctx context.Context, req domain.SetAllowedCidrsRequest) (*domain.ApplicationWithTokens, error)
+	SetStatus(ctx context.Context, id int, status string, reason string) (*domain.ApplicationWithTokens, error)
 }
 
 type Application struct {
@@ -100,15 +102,27 @@ func (c Application) GetByToken(ctx context.Context, req domain.GetApplicationBy
 //
 //	@Tags			application
 //	@Summary		Получить список приложений
-//	@Description	Возвращает массив приложений с токенами по их идентификаторам
+//	@Description	Возвращает страницу приложений с токенами из списка идентификаторов и общее количество отобранных приложений. В `page` передаются те же фильтры, сортировка и курсор страницы, что и в методе `/application/get_all`
 //	@Accept			json
 //	@Produce		json
-//	@Param			body	body		[]integer	false	"Массив идентификаторов приложений"
-//	@Success		200		{array}		domain.ApplicationWithTokens
+//	@Param			body	body		domain.ApplicationIdListRequest	true	"Идентификаторы приложений и параметры страницы"
+//	@Success		200		{object}	domain.ApplicationWithTokensListResponse
+//	@Failure		400		{object}	apierrors.Error
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/application/get_applications [POST]
-func (c Application) GetByIdList(ctx context.Context, req []int) ([]*domain.ApplicationWithTokens, error) {
-	return c.service.GetByIdList(ctx, req)
+func (c Application) GetByIdList(
+	ctx context.Context,
+	req domain.ApplicationIdListRequest,
+) (*domain.ApplicationWithTokensListResponse, error) {
+	result, err := c.service.GetByIdList(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidListRequest):
+		return nil, invalidListRequestError(err)
+	case err != nil:
+		return nil, err
+	default:
+		return result, nil
+	}
 }
 
 // GetByServiceId godoc
@@ -157,6 +171,8 @@ func (c Application) GetSystemTree(ctx context.Context) ([]*domain.DomainWithSer
 func (c Application) CreateUpdate(ctx context.Context, req domain.ApplicationCreateUpdateRequest) (*domain.ApplicationWithTokens, error) {
 	result, err := c.service.CreateUpdate(ctx, req)
 	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
 	case errors.Is(err, domain.ErrAppGroupNotFound):
 		return nil, apierrors.NewBusinessError(
 			domain.ErrCodeAppGroupNotFound,
@@ -203,7 +219,10 @@ func (c Application) Delete(ctx context.Context, req []int) (*domain.DeleteRespo
 	}
 
 	result, err := c.service.Delete(ctx, req)
-	if err != nil {
+	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	case err != nil:
 		return nil, err
 	}
 
@@ -230,14 +249,24 @@ func (c Application) NextId(ctx context.Context) (int, error) {
 //
 //	@Tags			application
 //	@Summary		Получить список приложений
-//	@Description	Возвращает список приложений
+//	@Description	Возвращает страницу приложений, отобранных по подстроке названия или описания, типам, группам и времени создания, и общее количество отобранных приложений. По умолчанию приложения упорядочены от новых к старым. Для получения следующей страницы в `cursor` передается `nextCursor` из предыдущего ответа с той же сортировкой
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{array}		domain.Application
-//	@Failure		500	{object}	apierrors.Error
+//	@Param			body	body		domain.ListRequest	true	"Фильтр, сортировка и курсор страницы"
+//	@Success		200		{object}	domain.ApplicationListResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
 //	@Router			/application/get_all [POST]
-func (c Application) GetAll(ctx context.Context) ([]domain.Application, error) {
-	return c.service.GetAll(ctx)
+func (c Application) GetAll(ctx context.Context, req domain.ListRequest) (*domain.ApplicationListResponse, error) {
+	result, err := c.service.GetAll(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidListRequest):
+		return nil, invalidListRequestError(err)
+	case err != nil:
+		return nil, err
+	default:
+		return result, nil
+	}
 }
 
 // Create godoc
@@ -298,6 +327,8 @@ func (c Application) Create(ctx context.Context, req domain.CreateApplicationReq
 func (c Application) Update(ctx context.Context, req domain.UpdateApplicationRequest) (*domain.ApplicationWithTokens, error) {
 	result, err := c.service.Update(ctx, req)
 	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
 	case errors.Is(err, domain.ErrApplicationNotFound):
 		return nil, apierrors.New(
 			codes.NotFound,
@@ -323,3 +354,105 @@ func (c Application) Update(ctx context.Context, req domain.UpdateApplicationReq
 		return result, err
 	}
 }
+
+// SetAllowedCidrs godoc
+//
+//	@Tags			application
+//	@Summary		Ограничить адреса использования токенов приложения
+//	@Description	Заменяет список CIDR, из которых разрешено использовать токены приложения. Адрес проверяется в методах `/secure/authenticate` и `/secure/check`, для токена со своим списком CIDR адрес должен входить в оба списка. Пустой список снимает ограничение
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.SetAllowedCidrsRequest	true	"Список CIDR приложения"
+//	@Success		200		{object}	domain.ApplicationWithTokens
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/application/set_allowed_cidrs [POST]
+func (c Application) SetAllowedCidrs(ctx context.Context, req domain.SetAllowedCidrsRequest) (*domain.ApplicationWithTokens, error) {
+	result, err := c.service.SetAllowedCidrs(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrApplicationNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeApplicationNotFound,
+			fmt.Sprintf("application with id %d not found", req.AppId),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// Suspend godoc
+//
+//	@Tags			application
+//	@Summary		Приостановить приложение
+//	@Description	Временно блокирует приложение без отзыва токенов: методы `/secure/authenticate` и `/secure/check` отклоняют его токены с причиной `application is suspended`. Причина и инициатор изменения сохраняются, инициатор берется из заголовка `x-admin-id`
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.ChangeStatusRequest	true	"Идентификатор приложения и причина"
+//	@Success		200		{object}	domain.ApplicationWithTokens
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/application/suspend [POST]
+func (c Application) Suspend(ctx context.Context, req domain.ChangeStatusRequest) (*domain.ApplicationWithTokens, error) {
+	return c.setStatus(ctx, req.Id, domain.StatusSuspended, req.Reason)
+}
+
+// Disable godoc
+//
+//	@Tags			application
+//	@Summary		Отключить приложение
+//	@Description	Блокирует приложение без отзыва токенов: методы `/secure/authenticate` и `/secure/check` отклоняют его токены с причиной `application is disabled`. Причина и инициатор изменения сохраняются, инициатор берется из заголовка `x-admin-id`
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.ChangeStatusRequest	true	"Идентификатор приложения и причина"
+//	@Success		200		{object}	domain.ApplicationWithTokens
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/application/disable [POST]
+func (c Application) Disable(ctx context.Context, req domain.ChangeStatusRequest) (*domain.ApplicationWithTokens, error) {
+	return c.setStatus(ctx, req.Id, domain.StatusDisabled, req.Reason)
+}
+
+// Resume godoc
+//
+//	@Tags			application
+//	@Summary		Возобновить работу приложения
+//	@Description	Возвращает приложение в статус `active`, его токены снова принимаются, если группа приложения также активна
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.Identity	true	"Идентификатор приложения"
+//	@Success		200		{object}	domain.ApplicationWithTokens
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/application/resume [POST]
+func (c Application) Resume(ctx context.Context, req domain.Identity) (*domain.ApplicationWithTokens, error) {
+	return c.setStatus(ctx, req.Id, domain.StatusActive, "")
+}
+
+func (c Application) setStatus(ctx context.Context, id int, status string, reason string) (*domain.ApplicationWithTokens, error) {
+	result, err := c.service.SetStatus(ctx, id, status, reason)
+	switch {
+	case errors.Is(err, domain.ErrApplicationNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeApplicationNotFound,
+			fmt.Sprintf("application with id %d not found", id),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+func invalidListRequestError(err error) error {
+	return apierrors.NewBusinessError(
+		domain.ErrCodeInvalidListRequest,
+		err.Error(),
+		err,
+	)
+}
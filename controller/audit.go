@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+
+	"isp-system-service/domain"
+)
+
+type AuditService interface {
+	Search(ctx context.Context, req domain.AuditSearchRequest) (*domain.AuditSearchResponse, error)
+}
+
+type Audit struct {
+	service AuditService
+}
+
+func NewAudit(service AuditService) Audit {
+	return Audit{
+		service: service,
+	}
+}
+
+// Search godoc
+//
+//	@Tags			audit
+//	@Summary		Поиск по журналу изменений реестра
+//	@Description	Возвращает события изменения доменов, групп приложений, приложений, списков доступа и токенов от новых к старым. В событии сохраняются инициатор изменения из заголовков `x-admin-id` или `x-application-identity`, идентификатор запроса и состояние сущности до и после изменения. Для получения следующей страницы в `cursor` передается `nextCursor` из предыдущего ответа
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.AuditSearchRequest	true	"Фильтр событий"
+//	@Success		200		{object}	domain.AuditSearchResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/audit/search [POST]
+func (c Audit) Search(ctx context.Context, req domain.AuditSearchRequest) (*domain.AuditSearchResponse, error) {
+	return c.service.Search(ctx, req)
+}
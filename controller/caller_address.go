@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	forwardedForHeader = "x-forwarded-for"
+)
+
+// clientAddress returns the address passed in the request or the caller address,
+// the result is used only to track token usage: the caller address can't be trusted for CIDR checks
+func clientAddress(ctx context.Context, requested string) string {
+	if requested != "" {
+		return requested
+	}
+	return callerAddress(ctx)
+}
+
+// callerAddress returns the original client address passed by the gateway,
+// or the address of the direct caller if the gateway didn't pass it
+func callerAddress(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	forwardedFor := md.Get(forwardedForHeader)
+	if len(forwardedFor) > 0 {
+		address, _, _ := strings.Cut(forwardedFor[0], ",")
+		return strings.TrimSpace(address)
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+
+	"isp-system-service/domain"
+)
+
+type ChangeEventService interface {
+	Subscribe(ctx context.Context, req domain.SubscribeEventsRequest) (*domain.SubscribeEventsResponse, error)
+}
+
+type ChangeEvent struct {
+	service ChangeEventService
+}
+
+func NewChangeEvent(service ChangeEventService) ChangeEvent {
+	return ChangeEvent{
+		service: service,
+	}
+}
+
+// Subscribe godoc
+//
+//	@Tags			events
+//	@Summary		Получение событий изменения реестра
+//	@Description	Возвращает события после переданного `offset` в порядке их публикации: `token.created`, `token.revoked`, `access_list.changed`, `application.deleted`, `application.renamed`. События записываются в одной транзакции с изменением и публикуются с задержкой до секунды. Если новых событий нет, запрос ожидает их до `waitSeconds` секунд, таймаут клиента должен быть больше. Для продолжения чтения в следующем запросе передается `nextOffset`, события хранятся в течение `events.retentionHours`
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.SubscribeEventsRequest	true	"Позиция чтения"
+//	@Success		200		{object}	domain.SubscribeEventsResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/events/subscribe [POST]
+func (c ChangeEvent) Subscribe(ctx context.Context, req domain.SubscribeEventsRequest) (*domain.SubscribeEventsResponse, error) {
+	return c.service.Subscribe(ctx, req)
+}
@@ -132,7 +132,10 @@ func (c Domain) Delete(ctx context.Context, req []int) (*domain.DeleteResponse,
 	}
 
 	result, err := c.service.Delete(ctx, req)
-	if err != nil {
+	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	case err != nil:
 		return nil, err
 	}
 
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"isp-system-service/domain"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"google.golang.org/grpc/codes"
+)
+
+type RateLimitService interface {
+	GetByAppId(ctx context.Context, appId int) ([]domain.RateLimit, error)
+	GetByAppGroupId(ctx context.Context, appGroupId int) ([]domain.RateLimit, error)
+	GetEffective(ctx context.Context, appId int) ([]domain.RateLimit, error)
+	Set(ctx context.Context, req domain.SetRateLimitRequest) (*domain.RateLimit, error)
+	DeleteList(ctx context.Context, req domain.IdListRequest) (*domain.DeleteResponse, error)
+}
+
+type RateLimit struct {
+	service RateLimitService
+}
+
+func NewRateLimit(service RateLimitService) RateLimit {
+	return RateLimit{
+		service: service,
+	}
+}
+
+// GetByApplicationId godoc
+//
+//	@Tags			rate_limit
+//	@Summary		Получить ограничения частоты запросов приложения
+//	@Description	Возвращает политики, назначенные непосредственно приложению
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.Identity	true	"Идентификатор приложения"
+//	@Success		200		{array}		domain.RateLimit
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/rate_limit/get_by_application_id [POST]
+func (c RateLimit) GetByApplicationId(ctx context.Context, req domain.Identity) ([]domain.RateLimit, error) {
+	return c.service.GetByAppId(ctx, req.Id)
+}
+
+// GetByApplicationGroupId godoc
+//
+//	@Tags			rate_limit
+//	@Summary		Получить ограничения частоты запросов группы приложений
+//	@Description	Возвращает политики, назначенные группе приложений
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.Identity	true	"Идентификатор группы приложений"
+//	@Success		200		{array}		domain.RateLimit
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/rate_limit/get_by_application_group_id [POST]
+func (c RateLimit) GetByApplicationGroupId(ctx context.Context, req domain.Identity) ([]domain.RateLimit, error) {
+	return c.service.GetByAppGroupId(ctx, req.Id)
+}
+
+// GetEffective godoc
+//
+//	@Tags			rate_limit
+//	@Summary		Получить действующие ограничения частоты запросов приложения
+//	@Description	Возвращает политики приложения и его группы. Политика приложения заменяет политику группы для того же метода. Эти же политики возвращаются в `authData.rateLimits` методов `/secure/authenticate` и `/secure/check`
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.Identity	true	"Идентификатор приложения"
+//	@Success		200		{array}		domain.RateLimit
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/rate_limit/get_effective [POST]
+func (c RateLimit) GetEffective(ctx context.Context, req domain.Identity) ([]domain.RateLimit, error) {
+	return c.service.GetEffective(ctx, req.Id)
+}
+
+// Set godoc
+//
+//	@Tags			rate_limit
+//	@Summary		Установить ограничение частоты запросов
+//	@Description	Создает или заменяет политику приложения или группы приложений для метода. Указывается ровно один из `appId` и `appGroupId`; пустой метод означает все методы, поддерживаются шаблоны методов. Должен быть задан хотя бы один из лимитов в секунду или в минуту
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.SetRateLimitRequest	true	"Политика ограничения"
+//	@Success		200		{object}	domain.RateLimit
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/rate_limit/set [POST]
+func (c RateLimit) Set(ctx context.Context, req domain.SetRateLimitRequest) (*domain.RateLimit, error) {
+	result, err := c.service.Set(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidMethodPattern):
+		return nil, invalidMethodPatternError(err)
+	case errors.Is(err, domain.ErrApplicationNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeApplicationNotFound,
+			fmt.Sprintf("application with id %d not found", req.AppId),
+			err,
+		)
+	case errors.Is(err, domain.ErrAppGroupNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeAppGroupNotFound,
+			fmt.Sprintf("application group with id %d not found", req.AppGroupId),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// DeleteList godoc
+//
+//	@Tags			rate_limit
+//	@Summary		Удалить ограничения частоты запросов
+//	@Description	Удаляет политики по списку их идентификаторов, возвращает количество удаленных политик
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.IdListRequest	true	"Список идентификаторов политик"
+//	@Success		200		{object}	domain.DeleteResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/rate_limit/delete_list [POST]
+func (c RateLimit) DeleteList(ctx context.Context, req domain.IdListRequest) (*domain.DeleteResponse, error) {
+	return c.service.DeleteList(ctx, req)
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+
+	"isp-system-service/domain"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"google.golang.org/grpc/codes"
+)
+
+type RegistryService interface {
+	Export(ctx context.Context, req domain.RegistryExportRequest) (*domain.RegistryExportResponse, error)
+	Import(ctx context.Context, req domain.RegistryImportRequest) (*domain.RegistryImportResponse, error)
+}
+
+type Registry struct {
+	service RegistryService
+}
+
+func NewRegistry(service RegistryService) Registry {
+	return Registry{
+		service: service,
+	}
+}
+
+// Export godoc
+//
+//	@Tags			registry
+//	@Summary		Выгрузка реестра
+//	@Description	Возвращает версионированный документ со всеми доменами, группами приложений, приложениями системы и их списками доступа. Домены и группы упорядочены по имени, приложения по идентификатору. При `format` = `yaml` документ возвращается строкой в поле `yaml`
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.RegistryExportRequest	true	"Формат документа"
+//	@Success		200		{object}	domain.RegistryExportResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/registry/export [POST]
+func (c Registry) Export(ctx context.Context, req domain.RegistryExportRequest) (*domain.RegistryExportResponse, error) {
+	return c.service.Export(ctx, req)
+}
+
+// Import godoc
+//
+//	@Tags			registry
+//	@Summary		Загрузка реестра
+//	@Description	Приводит реестр системы в соответствие с документом в одной транзакции: отсутствующие сущности создаются, отличающиеся изменяются, сущности, которых нет в документе, удаляются вместе с токенами. Домены и группы сопоставляются по имени, приложения по идентификатору. Повторная загрузка того же документа ничего не меняет. При `dryRun` изменения не применяются, возвращается только их список. Если документ изменяет или удаляет группы приложений или приложения, управляемые удаленной конфигурацией, загрузка отклоняется, в том числе при `dryRun`
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.RegistryImportRequest	true	"Документ в поле `document` или строкой YAML в поле `yaml`"
+//	@Success		200		{object}	domain.RegistryImportResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		409		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/registry/import [POST]
+func (c Registry) Import(ctx context.Context, req domain.RegistryImportRequest) (*domain.RegistryImportResponse, error) {
+	result, err := c.service.Import(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidRegistryDocument):
+		return nil, apierrors.NewBusinessError(
+			domain.ErrCodeInvalidRegistryDocument,
+			err.Error(),
+			err,
+		)
+	case errors.Is(err, domain.ErrInvalidMethodPattern):
+		return nil, invalidMethodPatternError(err)
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	case errors.Is(err, domain.ErrApplicationDuplicateName):
+		return nil, apierrors.New(
+			codes.AlreadyExists,
+			domain.ErrCodeApplicationDuplicateName,
+			"application name already exists in the group",
+			err,
+		)
+	case errors.Is(err, domain.ErrApplicationDuplicateId):
+		return nil, apierrors.New(
+			codes.AlreadyExists,
+			domain.ErrCodeApplicationDuplicateId,
+			"application id already exists",
+			err,
+		)
+	case err != nil:
+		return nil, err
+	default:
+		return result, nil
+	}
+}
+
+// managedByConfigError rejects changes of objects declared in the desired state of the remote config
+func managedByConfigError(err error) error {
+	return apierrors.New(
+		codes.FailedPrecondition,
+		domain.ErrCodeManagedByConfig,
+		"object is managed by the remote config, change the desired state of the config instead",
+		err,
+	)
+}
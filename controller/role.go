@@ -0,0 +1,272 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"isp-system-service/domain"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"google.golang.org/grpc/codes"
+)
+
+type RoleService interface {
+	Create(ctx context.Context, req domain.CreateRoleRequest) (*domain.Role, error)
+	Update(ctx context.Context, req domain.UpdateRoleRequest) (*domain.Role, error)
+	DeleteList(ctx context.Context, req domain.IdListRequest) (*domain.DeleteResponse, error)
+	GetByIdList(ctx context.Context, idList []int) ([]domain.Role, error)
+	GetAll(ctx context.Context) ([]domain.Role, error)
+	GetByAppId(ctx context.Context, appId int) ([]domain.Role, error)
+	GetByAppGroupId(ctx context.Context, appGroupId int) ([]domain.Role, error)
+	SetForApplication(ctx context.Context, req domain.SetApplicationRolesRequest) ([]domain.Role, error)
+	SetForAppGroup(ctx context.Context, req domain.SetAppGroupRolesRequest) ([]domain.Role, error)
+}
+
+type Role struct {
+	service RoleService
+}
+
+func NewRole(service RoleService) Role {
+	return Role{
+		service: service,
+	}
+}
+
+// Create godoc
+//
+//	@Tags			role
+//	@Summary		Создать роль
+//	@Description	Создает именованный набор разрешений на методы. Если роль с таким именем существует, возвращает ошибку
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.CreateRoleRequest	true	"Объект роли"
+//	@Success		200		{object}	domain.Role
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		409		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/role/create [POST]
+func (c Role) Create(ctx context.Context, req domain.CreateRoleRequest) (*domain.Role, error) {
+	result, err := c.service.Create(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidMethodPattern):
+		return nil, invalidMethodPatternError(err)
+	case errors.Is(err, domain.ErrRoleDuplicateName):
+		return nil, apierrors.New(
+			codes.AlreadyExists,
+			domain.ErrCodeRoleDuplicateName,
+			fmt.Sprintf("role with name %s already exists", req.Name),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// Update godoc
+//
+//	@Tags			role
+//	@Summary		Обновить роль
+//	@Description	Обновляет роль и полностью заменяет ее набор разрешений. Если роль с таким именем существует или роли с указанным id не существует, возвращает ошибку
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.UpdateRoleRequest	true	"Объект роли"
+//	@Success		200		{object}	domain.Role
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		409		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/role/update [POST]
+func (c Role) Update(ctx context.Context, req domain.UpdateRoleRequest) (*domain.Role, error) {
+	result, err := c.service.Update(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidMethodPattern):
+		return nil, invalidMethodPatternError(err)
+	case errors.Is(err, domain.ErrRoleDuplicateName):
+		return nil, apierrors.New(
+			codes.AlreadyExists,
+			domain.ErrCodeRoleDuplicateName,
+			fmt.Sprintf("role with name %s already exists", req.Name),
+			err,
+		)
+	case errors.Is(err, domain.ErrRoleNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeRoleNotFound,
+			fmt.Sprintf("role with id %d not found", req.Id),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// DeleteList godoc
+//
+//	@Tags			role
+//	@Summary		Удалить роли
+//	@Description	Удаляет роли по списку их идентификаторов вместе с их назначениями, возвращает количество удаленных ролей
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.IdListRequest	true	"список идентификаторов ролей"
+//	@Success		200		{object}	domain.DeleteResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/role/delete_list [POST]
+func (c Role) DeleteList(ctx context.Context, req domain.IdListRequest) (*domain.DeleteResponse, error) {
+	return c.service.DeleteList(ctx, req)
+}
+
+// GetByIdList godoc
+//
+//	@Tags			role
+//	@Summary		Получить роли по списку идентификаторов
+//	@Description	Возвращает роли с указанными идентификаторами
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.IdListRequest	true	"список идентификаторов ролей"
+//	@Success		200		{array}		domain.Role
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/role/get_by_id_list [POST]
+func (c Role) GetByIdList(ctx context.Context, req domain.IdListRequest) ([]domain.Role, error) {
+	return c.service.GetByIdList(ctx, req.IdList)
+}
+
+// GetAll godoc
+//
+//	@Tags			role
+//	@Summary		Получить роли
+//	@Description	Возвращает все роли
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		domain.Role
+//	@Failure		500	{object}	apierrors.Error
+//	@Router			/role/get_all [POST]
+func (c Role) GetAll(ctx context.Context) ([]domain.Role, error) {
+	return c.service.GetAll(ctx)
+}
+
+// GetByApplicationId godoc
+//
+//	@Tags			role
+//	@Summary		Получить роли приложения
+//	@Description	Возвращает роли, назначенные непосредственно приложению
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.Identity	true	"Идентификатор приложения"
+//	@Success		200		{array}		domain.Role
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/role/get_by_application_id [POST]
+func (c Role) GetByApplicationId(ctx context.Context, req domain.Identity) ([]domain.Role, error) {
+	result, err := c.service.GetByAppId(ctx, req.Id)
+	switch {
+	case errors.Is(err, domain.ErrApplicationNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeApplicationNotFound,
+			fmt.Sprintf("application with id %d not found", req.Id),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// GetByApplicationGroupId godoc
+//
+//	@Tags			role
+//	@Summary		Получить роли группы приложений
+//	@Description	Возвращает роли, назначенные группе приложений
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.Identity	true	"Идентификатор группы приложений"
+//	@Success		200		{array}		domain.Role
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/role/get_by_application_group_id [POST]
+func (c Role) GetByApplicationGroupId(ctx context.Context, req domain.Identity) ([]domain.Role, error) {
+	result, err := c.service.GetByAppGroupId(ctx, req.Id)
+	switch {
+	case errors.Is(err, domain.ErrAppGroupNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeAppGroupNotFound,
+			fmt.Sprintf("application group with id %d not found", req.Id),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// SetForApplication godoc
+//
+//	@Tags			role
+//	@Summary		Назначить роли приложению
+//	@Description	Полностью заменяет список ролей приложения, возвращает назначенные роли. Пустой список снимает все роли
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.SetApplicationRolesRequest	true	"Идентификатор приложения и список ролей"
+//	@Success		200		{array}		domain.Role
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/role/set_for_application [POST]
+func (c Role) SetForApplication(ctx context.Context, req domain.SetApplicationRolesRequest) ([]domain.Role, error) {
+	result, err := c.service.SetForApplication(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrApplicationNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeApplicationNotFound,
+			fmt.Sprintf("application with id %d not found", req.AppId),
+			err,
+		)
+	case errors.Is(err, domain.ErrRoleNotFound):
+		return nil, apierrors.New(codes.NotFound, domain.ErrCodeRoleNotFound, err.Error(), err)
+	default:
+		return result, err
+	}
+}
+
+// SetForApplicationGroup godoc
+//
+//	@Tags			role
+//	@Summary		Назначить роли группе приложений
+//	@Description	Полностью заменяет список ролей группы приложений, роли группы действуют на все приложения группы. Пустой список снимает все роли
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.SetAppGroupRolesRequest	true	"Идентификатор группы приложений и список ролей"
+//	@Success		200		{array}		domain.Role
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/role/set_for_application_group [POST]
+func (c Role) SetForApplicationGroup(ctx context.Context, req domain.SetAppGroupRolesRequest) ([]domain.Role, error) {
+	result, err := c.service.SetForAppGroup(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrAppGroupNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeAppGroupNotFound,
+			fmt.Sprintf("application group with id %d not found", req.AppGroupId),
+			err,
+		)
+	case errors.Is(err, domain.ErrRoleNotFound):
+		return nil, apierrors.New(codes.NotFound, domain.ErrCodeRoleNotFound, err.Error(), err)
+	default:
+		return result, err
+	}
+}
+
+func invalidMethodPatternError(err error) error {
+	return apierrors.NewBusinessError(
+		domain.ErrCodeInvalidMethodPattern,
+		"invalid method pattern, '**' must be a whole path segment",
+		err,
+	)
+}
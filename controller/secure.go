@@ -9,8 +9,11 @@ import (
 )
 
 type SecureService interface {
-	Authenticate(ctx context.Context, token string) (*domain.AuthData, error)
-	Authorize(ctx context.Context, req domain.AuthorizeRequest) (bool, error)
+	Authenticate(ctx context.Context, token string, clientAddress string, callerAddress string) (*domain.AuthData, error)
+	Authorize(ctx context.Context, req domain.AuthorizeRequest) (*domain.AuthorizeResponse, error)
+	AuthorizeBatch(ctx context.Context, req domain.AuthorizeBatchRequest) ([]domain.AuthorizeBatchResult, error)
+	Check(ctx context.Context, req domain.CheckRequest, clientAddress string, callerAddress string) (*domain.CheckResponse, error)
+	Jwks(ctx context.Context) (*domain.Jwks, error)
 }
 
 type Secure struct {
@@ -27,7 +30,7 @@ func NewSecure(service SecureService) Secure {
 //
 //	@Tags			secure
 //	@Summary		Метод аутентификации токена
-//	@Description	Проверяет наличие токена в системе,
+//	@Description	Проверяет наличие токена в системе. Для токена с ограниченной областью действия в `authData.scope` возвращаются доступные методы, их нужно передать в метод `/secure/authorize`. Если для токена или его приложения заданы разрешенные CIDR, адрес клиента из `clientAddress` должен входить в них, иначе `errorReason` = `source address not allowed`; без `clientAddress` такие токены отклоняются, заголовок `x-forwarded-for` и адрес вызывающего для проверки не используются. Токены приостановленных и отключенных приложений и групп приложений отклоняются с `errorReason` = `application is suspended` и `application is disabled`. В `authData.rateLimits` возвращаются ограничения частоты запросов приложения, политика приложения для метода переопределяет политику группы. Время и адрес последнего использования токена сохраняются с задержкой; если `clientAddress` не передан, адрес использования берется из заголовка `x-forwarded-for`, а при его отсутствии - из адреса вызывающего
 //	@Accept			json
 //	@Produce		json
 //	@Param			body	body		domain.AuthenticateRequest	true	"Тело запроса"
@@ -35,7 +38,7 @@ func NewSecure(service SecureService) Secure {
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/secure/authenticate [POST]
 func (c Secure) Authenticate(ctx context.Context, req domain.AuthenticateRequest) (*domain.AuthenticateResponse, error) {
-	result, err := c.service.Authenticate(ctx, req.Token)
+	result, err := c.service.Authenticate(ctx, req.Token, req.ClientAddress, clientAddress(ctx, req.ClientAddress))
 	switch {
 	case errors.Is(err, domain.ErrTokenNotFound):
 		return &domain.AuthenticateResponse{
@@ -47,6 +50,26 @@ func (c Secure) Authenticate(ctx context.Context, req domain.AuthenticateRequest
 			Authenticated: false,
 			ErrorReason:   domain.ErrTokenExpired.Error(),
 		}, nil
+	case errors.Is(err, domain.ErrTokenInvalid):
+		return &domain.AuthenticateResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrTokenInvalid.Error(),
+		}, nil
+	case errors.Is(err, domain.ErrSourceAddressNotAllowed):
+		return &domain.AuthenticateResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrSourceAddressNotAllowed.Error(),
+		}, nil
+	case errors.Is(err, domain.ErrApplicationSuspended):
+		return &domain.AuthenticateResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrApplicationSuspended.Error(),
+		}, nil
+	case errors.Is(err, domain.ErrApplicationDisabled):
+		return &domain.AuthenticateResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrApplicationDisabled.Error(),
+		}, nil
 	case err != nil:
 		return nil, errors.WithMessage(err, "authenticate")
 	default:
@@ -61,7 +84,7 @@ func (c Secure) Authenticate(ctx context.Context, req domain.AuthenticateRequest
 //
 //	@Tags			secure
 //	@Summary		Метод авторизации приложения
-//	@Description	Проверяет доступ приложения к запрашиваемому ендпоинту
+//	@Description	Проверяет доступ приложения к запрашиваемому ендпоинту. Правила проверяются по уровням: приложение и его роли, группа приложений и ее роли, домен; решение принимается на первом уровне, где нашлось подходящее правило. В пределах уровня применяется наиболее конкретное правило, при этом запрещающее правило перекрывает разрешающие, охватывающие все его методы, в ответе возвращается правило, на основании которого принято решение. Если передан `scope` из результата аутентификации, ендпоинты вне области действия токена запрещаются с `outOfScope` = true без проверки правил
 //	@Accept			json
 //	@Produce		json
 //	@Param			body	body		domain.AuthorizeRequest	true	"Тело запрос"
@@ -78,8 +101,96 @@ func (c Secure) Authorize(ctx context.Context, req domain.AuthorizeRequest) (*do
 	case err != nil:
 		return nil, errors.WithMessage(err, "authorize")
 	default:
-		return &domain.AuthorizeResponse{
-			Authorized: result,
+		return result, nil
+	}
+}
+
+// AuthorizeBatch godoc
+//
+//	@Tags			secure
+//	@Summary		Метод пакетной авторизации приложения
+//	@Description	Проверяет доступ приложения к списку ендпоинтов за один запрос к базе данных. Решения возвращаются в порядке ендпоинтов в запросе и принимаются по тем же правилам, что и в методе `/secure/authorize`
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.AuthorizeBatchRequest	true	"Тело запроса"
+//	@Success		200		{array}		domain.AuthorizeBatchResult
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/secure/authorize_batch [POST]
+func (c Secure) AuthorizeBatch(ctx context.Context, req domain.AuthorizeBatchRequest) ([]domain.AuthorizeBatchResult, error) {
+	result, err := c.service.AuthorizeBatch(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "authorize batch")
+	}
+	return result, nil
+}
+
+// Check godoc
+//
+//	@Tags			secure
+//	@Summary		Метод аутентификации и авторизации за один вызов
+//	@Description	Проверяет токен и доступ его приложения к запрашиваемому ендпоинту. Если токен не прошел проверку, `authenticated` = false, а `errorReason` заполняется так же, как в методе `/secure/authenticate`. Если доступ запрещен или ендпоинт вне области действия токена, `authorized` = false, а `errorReason` содержит причину отказа
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.CheckRequest	true	"Тело запроса"
+//	@Success		200		{object}	domain.CheckResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/secure/check [POST]
+func (c Secure) Check(ctx context.Context, req domain.CheckRequest) (*domain.CheckResponse, error) {
+	result, err := c.service.Check(ctx, req, req.ClientAddress, clientAddress(ctx, req.ClientAddress))
+	switch {
+	case errors.Is(err, domain.ErrTokenNotFound):
+		return &domain.CheckResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrTokenNotFound.Error(),
+		}, nil
+	case errors.Is(err, domain.ErrTokenExpired):
+		return &domain.CheckResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrTokenExpired.Error(),
+		}, nil
+	case errors.Is(err, domain.ErrTokenInvalid):
+		return &domain.CheckResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrTokenInvalid.Error(),
+		}, nil
+	case errors.Is(err, domain.ErrSourceAddressNotAllowed):
+		return &domain.CheckResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrSourceAddressNotAllowed.Error(),
 		}, nil
+	case errors.Is(err, domain.ErrApplicationSuspended):
+		return &domain.CheckResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrApplicationSuspended.Error(),
+		}, nil
+	case errors.Is(err, domain.ErrApplicationDisabled):
+		return &domain.CheckResponse{
+			Authenticated: false,
+			ErrorReason:   domain.ErrApplicationDisabled.Error(),
+		}, nil
+	case err != nil:
+		return nil, errors.WithMessage(err, "check")
+	default:
+		return result, nil
+	}
+}
+
+// Jwks godoc
+//
+//	@Tags			secure
+//	@Summary		Публичные ключи подписи JWT
+//	@Description	Возвращает публичные ключи в формате JWKS для проверки JWT без обращения к сервису. Отзыв токенов при такой проверке не учитывается
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	domain.Jwks
+//	@Failure		500	{object}	apierrors.Error
+//	@Router			/secure/jwks [POST]
+func (c Secure) Jwks(ctx context.Context) (*domain.Jwks, error) {
+	result, err := c.service.Jwks(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "jwks")
 	}
+	return result, nil
 }
@@ -105,6 +105,8 @@ func (c Service) GetByDomainId(ctx context.Context, req domain.Identity) ([]doma
 func (c Service) CreateUpdate(ctx context.Context, req domain.ServiceCreateUpdateRequest) (*domain.Service, error) {
 	result, err := c.service.CreateUpdate(ctx, req)
 	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
 	case errors.Is(err, domain.ErrDomainNotFound):
 		return nil, apierrors.NewBusinessError(
 			domain.ErrCodeDomainNotFound,
@@ -151,7 +153,10 @@ func (c Service) Delete(ctx context.Context, req []int) (*domain.DeleteResponse,
 	}
 
 	result, err := c.service.Delete(ctx, req)
-	if err != nil {
+	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	case err != nil:
 		return nil, err
 	}
 
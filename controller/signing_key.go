@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"isp-system-service/domain"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"google.golang.org/grpc/codes"
+)
+
+type SigningKeyService interface {
+	List(ctx context.Context) ([]domain.SigningKey, error)
+	Rotate(ctx context.Context) (*domain.SigningKey, error)
+	Retire(ctx context.Context, id string) ([]domain.SigningKey, error)
+}
+
+type SigningKey struct {
+	service SigningKeyService
+}
+
+func NewSigningKey(service SigningKeyService) SigningKey {
+	return SigningKey{
+		service: service,
+	}
+}
+
+// List godoc
+//
+//	@Tags			keys
+//	@Summary		Получить ключи подписи JWT
+//	@Description	Возвращает все ключи подписи, начиная с последнего. Активный ключ подписывает токены, выводимые из оборота ключи только проверяют ранее выпущенные токены, выведенные ключи не используются
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		domain.SigningKey
+//	@Failure		500	{object}	apierrors.Error
+//	@Router			/keys/list [POST]
+func (c SigningKey) List(ctx context.Context) ([]domain.SigningKey, error) {
+	return c.service.List(ctx)
+}
+
+// Rotate godoc
+//
+//	@Tags			keys
+//	@Summary		Сменить ключ подписи JWT
+//	@Description	Создает новый активный ключ, предыдущий активный ключ выводится из оборота и продолжает проверять выпущенные им токены до вывода
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	domain.SigningKey
+//	@Failure		500	{object}	apierrors.Error
+//	@Router			/keys/rotate [POST]
+func (c SigningKey) Rotate(ctx context.Context) (*domain.SigningKey, error) {
+	return c.service.Rotate(ctx)
+}
+
+// Retire godoc
+//
+//	@Tags			keys
+//	@Summary		Вывести ключ подписи JWT
+//	@Description	Выводит ключ из оборота, подписанные им токены становятся недействительными, закрытый ключ удаляется. Активный ключ вывести нельзя, сначала его нужно сменить
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.RetireSigningKeyRequest	true	"Идентификатор ключа"
+//	@Success		200		{array}		domain.SigningKey
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/keys/retire [POST]
+func (c SigningKey) Retire(ctx context.Context, req domain.RetireSigningKeyRequest) ([]domain.SigningKey, error) {
+	result, err := c.service.Retire(ctx, req.Id)
+	switch {
+	case errors.Is(err, domain.ErrSigningKeyNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeSigningKeyNotFound,
+			fmt.Sprintf("signing key with id %s not found", req.Id),
+			err,
+		)
+	case errors.Is(err, domain.ErrSigningKeyActive):
+		return nil, apierrors.New(codes.InvalidArgument, domain.ErrCodeSigningKeyActive, err.Error(), err)
+	default:
+		return result, err
+	}
+}
@@ -12,10 +12,12 @@ import (
 )
 
 type TokenService interface {
-	GetByAppId(ctx context.Context, appId int) ([]domain.Token, error)
-	Create(ctx context.Context, req domain.TokenCreateRequest) (*domain.ApplicationWithTokens, error)
+	GetByAppId(ctx context.Context, req domain.TokenListRequest) (*domain.TokenListResponse, error)
+	Create(ctx context.Context, req domain.TokenCreateRequest) (*domain.TokenCreateResponse, error)
 	Revoke(ctx context.Context, req domain.TokenRevokeRequest) (*domain.ApplicationWithTokens, error)
 	RevokeByAppId(ctx context.Context, appId int) (*domain.DeleteResponse, error)
+	GetUnused(ctx context.Context, req domain.GetUnusedTokensRequest) ([]domain.Token, error)
+	Rotate(ctx context.Context, req domain.TokenRotateRequest) (*domain.TokenRotateResponse, error)
 }
 
 type Token struct {
@@ -32,31 +34,47 @@ func NewToken(service TokenService) Token {
 //
 //	@Tags			token
 //	@Summary		Получить токены по идентификатору приложения
-//	@Description	Возвращает список токенов, привязанных к приложению
+//	@Description	Возвращает страницу токенов, привязанных к приложению, и общее количество отобранных токенов. Токены возвращаются только идентификатором и маскированным значением вместе со временем и адресом последнего использования. В `page` передаются фильтр по подстроке идентификатора и времени создания, сортировка по `id` или `createdAt` и курсор страницы
 //	@Accept			json
 //	@Produce		json
-//	@Param			body	body		domain.Identity	true	"Идентификатор приложения"
-//	@Success		200		{array}		entity.Token
+//	@Param			body	body		domain.TokenListRequest	true	"Идентификатор приложения и параметры страницы"
+//	@Success		200		{object}	domain.TokenListResponse
+//	@Failure		400		{object}	apierrors.Error
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/token/get_tokens_by_app_id [POST]
-func (c Token) GetByAppId(ctx context.Context, req domain.Identity) ([]domain.Token, error) {
-	return c.service.GetByAppId(ctx, req.Id)
+func (c Token) GetByAppId(ctx context.Context, req domain.TokenListRequest) (*domain.TokenListResponse, error) {
+	result, err := c.service.GetByAppId(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidListRequest):
+		return nil, invalidListRequestError(err)
+	case err != nil:
+		return nil, err
+	default:
+		return result, nil
+	}
 }
 
 // Create godoc
 //
 //	@Tags			token
 //	@Summary		Создать токен
-//	@Description	Создает токен и привязывает его к приложению
+//	@Description	Создает токен и привязывает его к приложению. Токен в формате `isp_<id>_<secret>` возвращается в поле `token` только в ответе этого метода, в базе данных хранится его хэш. Если указан `scope`, токен получает доступ только к перечисленным методам и шаблонам методов в пределах прав приложения. Если указан `allowedCidrs`, токен можно использовать только с перечисленных адресов в дополнение к ограничениям приложения
 //	@Accept			json
 //	@Produce		json
 //	@Param			body	body		domain.TokenCreateRequest	true	"Объект создания токена"
-//	@Success		200		{object}	domain.ApplicationWithTokens
+//	@Success		200		{object}	domain.TokenCreateResponse
+//	@Failure		400		{object}	apierrors.Error
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/token/create_token [POST]
-func (c Token) Create(ctx context.Context, req domain.TokenCreateRequest) (*domain.ApplicationWithTokens, error) {
+func (c Token) Create(ctx context.Context, req domain.TokenCreateRequest) (*domain.TokenCreateResponse, error) {
 	result, err := c.service.Create(ctx, req)
 	switch {
+	case errors.Is(err, domain.ErrInvalidMethodPattern):
+		return nil, apierrors.NewBusinessError(
+			domain.ErrCodeInvalidMethodPattern,
+			fmt.Sprintf("invalid method pattern in scope %v", req.Scope),
+			err,
+		)
 	case errors.Is(err, domain.ErrApplicationNotFound):
 		return nil, apierrors.New(
 			codes.NotFound,
@@ -89,7 +107,7 @@ func (c Token) Create(ctx context.Context, req domain.TokenCreateRequest) (*doma
 //
 //	@Tags			token
 //	@Summary		Отозвать токены
-//	@Description	Отвязывает токены от приложений и удаляет их
+//	@Description	Отвязывает токены от приложения и удаляет их по идентификаторам токенов
 //	@Accept			json
 //	@Produce		json
 //	@Param			body	body		domain.TokenRevokeRequest	true	"Объект отзыва токенов"
@@ -97,7 +115,13 @@ func (c Token) Create(ctx context.Context, req domain.TokenCreateRequest) (*doma
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/token/revoke_tokens [POST]
 func (c Token) Revoke(ctx context.Context, req domain.TokenRevokeRequest) (*domain.ApplicationWithTokens, error) {
-	return c.service.Revoke(ctx, req)
+	result, err := c.service.Revoke(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	default:
+		return result, err
+	}
 }
 
 // RevokeForApp godoc
@@ -112,5 +136,64 @@ func (c Token) Revoke(ctx context.Context, req domain.TokenRevokeRequest) (*doma
 //	@Failure		500		{object}	apierrors.Error
 //	@Router			/token/revoke_tokens_for_app [POST]
 func (c Token) RevokeForApp(ctx context.Context, req domain.Identity) (*domain.DeleteResponse, error) {
-	return c.service.RevokeByAppId(ctx, req.Id)
+	result, err := c.service.RevokeByAppId(ctx, req.Id)
+	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	default:
+		return result, err
+	}
+}
+
+// Rotate godoc
+//
+//	@Tags			token
+//	@Summary		Ротировать токен
+//	@Description	Создает новый токен приложения и ограничивает срок действия предыдущего токена периодом перекрытия, чтобы потребители успели перейти на новый токен. Если период не указан, используется значение из конфигурации. Новый токен получает `scope` и `allowedCidrs` предыдущего токена и возвращается в поле `token` только в ответе этого метода
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.TokenRotateRequest	true	"Объект ротации токена"
+//	@Success		200		{object}	domain.TokenRotateResponse
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		404		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/token/rotate [POST]
+func (c Token) Rotate(ctx context.Context, req domain.TokenRotateRequest) (*domain.TokenRotateResponse, error) {
+	result, err := c.service.Rotate(ctx, req)
+	switch {
+	case errors.Is(err, domain.ErrManagedByConfig):
+		return nil, managedByConfigError(err)
+	case errors.Is(err, domain.ErrTokenNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeTokenNotFound,
+			fmt.Sprintf("token with id %s not found for app_id %d", req.TokenId, req.AppId),
+			err,
+		)
+	case errors.Is(err, domain.ErrApplicationNotFound):
+		return nil, apierrors.New(
+			codes.NotFound,
+			domain.ErrCodeApplicationNotFound,
+			fmt.Sprintf("application with id %d not found", req.AppId),
+			err,
+		)
+	default:
+		return result, err
+	}
+}
+
+// GetUnused godoc
+//
+//	@Tags			token
+//	@Summary		Получить неиспользуемые токены
+//	@Description	Возвращает действующие токены, которые не использовались указанное количество дней, начиная с давно не использованных. Токены, не использованные ни разу, сравниваются по времени создания. Если идентификатор приложения не указан, возвращаются токены всех приложений
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.GetUnusedTokensRequest	true	"Параметры поиска"
+//	@Success		200		{array}		domain.Token
+//	@Failure		400		{object}	apierrors.Error
+//	@Failure		500		{object}	apierrors.Error
+//	@Router			/token/get_unused [POST]
+func (c Token) GetUnused(ctx context.Context, req domain.GetUnusedTokensRequest) ([]domain.Token, error) {
+	return c.service.GetUnused(ctx, req)
 }
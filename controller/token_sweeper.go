@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"context"
+
+	"isp-system-service/domain"
+)
+
+type TokenSweeperService interface {
+	Status(ctx context.Context) (*domain.TokenSweepStatus, error)
+}
+
+type TokenSweeper struct {
+	service TokenSweeperService
+}
+
+func NewTokenSweeper(service TokenSweeperService) TokenSweeper {
+	return TokenSweeper{
+		service: service,
+	}
+}
+
+// Status godoc
+//
+//	@Tags			token
+//	@Summary		Получить состояние очистки истекших токенов
+//	@Description	Возвращает настройки и результат последнего запуска очистки, выполненного любым экземпляром сервиса. Поле `lastRun` пустое, если очистка не выполнялась
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	domain.TokenSweepStatus
+//	@Failure		500	{object}	apierrors.Error
+//	@Router			/token/get_sweep_status [POST]
+func (c TokenSweeper) Status(ctx context.Context) (*domain.TokenSweepStatus, error) {
+	return c.service.Status(ctx)
+}
@@ -17,6 +17,41 @@ type AccessListSetListRequest struct {
 	Methods   []MethodInfo
 }
 
+type AccessListGetByIdRequest struct {
+	Id         int `validate:"required"`
+	HttpMethod string
+	Endpoint   string
+}
+
+type AccessListItem struct {
+	HttpMethod string
+	Method     string
+	Value      bool
+	Matched    bool
+	Source     string
+	SourceId   int
+}
+
+type AccessRule struct {
+	Source     string
+	SourceId   int
+	HttpMethod string
+	Method     string
+	Value      bool
+}
+
+type AccessListSetListForAppGroupRequest struct {
+	AppGroupId int `validate:"required"`
+	RemoveOld  bool
+	Methods    []MethodInfo
+}
+
+type AccessListSetListForDomainRequest struct {
+	DomainId  int `validate:"required"`
+	RemoveOld bool
+	Methods   []MethodInfo
+}
+
 type MethodInfo struct {
 	HttpMethod string
 	Method     string
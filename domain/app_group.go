@@ -8,6 +8,13 @@ type AppGroup struct {
 	Description string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	// active, suspended or disabled
+	Status          string
+	StatusReason    string
+	StatusChangedBy string
+	StatusChangedAt *time.Time
+	// declared in the remote config, changed only by reconciliation
+	ManagedByConfig bool
 }
 
 type CreateAppGroupRequest struct {
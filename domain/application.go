@@ -9,6 +9,12 @@ const (
 	ApplicationMobileType = "MOBILE"
 )
 
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusDisabled  = "disabled"
+)
+
 type Application struct {
 	Id          int
 	Name        string
@@ -17,6 +23,15 @@ type Application struct {
 	Type        string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	// empty - tokens of the application are allowed from any address
+	AllowedCidrs []string
+	// active, suspended or disabled
+	Status          string
+	StatusReason    string
+	StatusChangedBy string
+	StatusChangedAt *time.Time
+	// declared in the remote config, changed only by reconciliation
+	ManagedByConfig bool
 }
 
 type ApplicationCreateUpdateRequest struct {
@@ -55,6 +70,17 @@ type UpdateApplicationRequest struct {
 	Description string
 }
 
+type SetAllowedCidrsRequest struct {
+	AppId int `validate:"required"`
+	// empty - tokens of the application are allowed from any address
+	AllowedCidrs []string `validate:"dive,cidr"`
+}
+
+type ChangeStatusRequest struct {
+	Id     int    `validate:"required"`
+	Reason string `validate:"required,max=1024"`
+}
+
 type GetApplicationByTokenRequest struct {
 	Token string `validate:"required"`
 }
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEvent struct {
+	Id        int64
+	CreatedAt time.Time
+	// admin or application which made the change, taken from the gRPC metadata
+	Actor      string
+	RequestId  string
+	Action     string
+	EntityType string
+	EntityId   string
+	// null for created entities
+	Before json.RawMessage
+	// null for deleted entities
+	After json.RawMessage
+}
+
+type AuditSearchRequest struct {
+	EntityType string
+	EntityId   string
+	Action     string
+	Actor      string
+	RequestId  string
+	From       *time.Time
+	To         *time.Time
+	// NextCursor of the previous page, empty - from the latest event
+	Cursor int64 `validate:"min=0"`
+	// 100 by default
+	Limit int `validate:"min=0,max=1000"`
+}
+
+type AuditSearchResponse struct {
+	Items []AuditEvent
+	// cursor of the next page, 0 - there are no more events
+	NextCursor int64
+}
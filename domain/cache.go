@@ -0,0 +1,9 @@
+package domain
+
+// CacheInvalidation describes authentication and authorization cache entries which became stale
+type CacheInvalidation struct {
+	TokenHashes []string
+	AppIds      []int
+	All         bool
+	SigningKeys bool
+}
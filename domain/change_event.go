@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ChangeEventTokenCreated       = "token.created"
+	ChangeEventTokenRevoked       = "token.revoked"
+	ChangeEventAccessListChanged  = "access_list.changed"
+	ChangeEventApplicationDeleted = "application.deleted"
+	ChangeEventApplicationRenamed = "application.renamed"
+)
+
+type ChangeEvent struct {
+	// position of the event in the stream, increases without gaps in the order events are relayed
+	Offset    int64
+	CreatedAt time.Time
+	// token.created, token.revoked, access_list.changed, application.deleted, application.renamed
+	Type string
+	// TokenChange, AccessListChange, ApplicationDeleted or ApplicationRenamed depending on the type
+	Payload json.RawMessage
+}
+
+type TokenChange struct {
+	TokenId string
+	AppId   int
+}
+
+// AccessListChange contains one of the owners of the changed access list
+type AccessListChange struct {
+	AppId      int
+	AppGroupId int
+	DomainId   int
+}
+
+type ApplicationDeleted struct {
+	AppId int
+	Name  string
+}
+
+type ApplicationRenamed struct {
+	OldAppId int
+	AppId    int
+	OldName  string
+	Name     string
+}
+
+type SubscribeEventsRequest struct {
+	// NextOffset of the previous response, 0 - from the earliest retained event
+	Offset int64 `validate:"min=0"`
+	// 100 by default
+	Limit int `validate:"min=0,max=1000"`
+	// how long to wait for new events if there are none after the offset, 0 - respond immediately
+	WaitSeconds int `validate:"min=0,max=60"`
+}
+
+type SubscribeEventsResponse struct {
+	Events []ChangeEvent
+	// offset to pass in the next request
+	NextOffset int64
+}
@@ -17,6 +17,22 @@ const (
 	ErrCodeSystemNotFound      = 607
 	ErrCodeDomainNotFound      = 608
 	ErrCodeDomainDuplicateName = 609
+
+	ErrCodeInvalidMethodPattern = 610
+
+	ErrCodeRoleNotFound      = 611
+	ErrCodeRoleDuplicateName = 612
+
+	ErrCodeSigningKeyNotFound = 613
+	ErrCodeSigningKeyActive   = 614
+
+	ErrCodeTokenNotFound = 615
+
+	ErrCodeInvalidRegistryDocument = 616
+
+	ErrCodeManagedByConfig = 617
+
+	ErrCodeInvalidListRequest = 618
 )
 
 var (
@@ -34,6 +50,26 @@ var (
 
 	ErrTokenNotFound = errors.New("token not found")
 	ErrTokenExpired  = errors.New("token is expired")
+	ErrTokenInvalid  = errors.New("token is invalid")
+
+	ErrSourceAddressNotAllowed = errors.New("source address not allowed")
+
+	ErrApplicationSuspended = errors.New("application is suspended")
+	ErrApplicationDisabled  = errors.New("application is disabled")
+
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleDuplicateName = errors.New("role name already exist")
+
+	ErrAccessListNotFound   = errors.New("access_list not found")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrOutOfTokenScope      = errors.New("endpoint is out of token scope")
+	ErrInvalidMethodPattern = errors.New("invalid method pattern")
+
+	ErrSigningKeyNotFound = errors.New("signing key not found")
+	ErrSigningKeyActive   = errors.New("active signing key can't be retired")
+
+	ErrInvalidRegistryDocument = errors.New("invalid registry document")
+	ErrManagedByConfig         = errors.New("managed by config")
 
-	ErrAccessListNotFound = errors.New("access_list not found")
+	ErrInvalidListRequest = errors.New("invalid list request")
 )
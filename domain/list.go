@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	ListSortId        = "id"
+	ListSortName      = "name"
+	ListSortCreatedAt = "createdAt"
+
+	ListSortAsc  = "asc"
+	ListSortDesc = "desc"
+)
+
+// ListRequest is the common page request of listing methods
+type ListRequest struct {
+	// 100 by default
+	Limit int `validate:"min=0,max=1000"`
+	// NextCursor of the previous page, empty - the first page; the cursor is valid only for the same sort
+	Cursor string
+	// createdAt by default
+	SortField string `validate:"omitempty,oneof=id name createdAt"`
+	// desc by default
+	SortDirection string `validate:"omitempty,oneof=asc desc"`
+	// case-insensitive substring of the name or the description
+	Search string
+	// applications only
+	Types []string `validate:"dive,oneof=SYSTEM MOBILE"`
+	// applications only
+	AppGroupIdList []int
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+}
+
+type ApplicationListResponse struct {
+	Items []Application
+	// count of all items matching the filter
+	Total int
+	// cursor of the next page, empty - there are no more items
+	NextCursor string
+}
+
+type ApplicationIdListRequest struct {
+	IdList []int `validate:"required,min=1"`
+	Page   ListRequest
+}
+
+type ApplicationWithTokensListResponse struct {
+	Items []*ApplicationWithTokens
+	// count of all items matching the filter
+	Total int
+	// cursor of the next page, empty - there are no more items
+	NextCursor string
+}
+
+type AppGroupListResponse struct {
+	Items []AppGroup
+	// count of all items matching the filter
+	Total int
+	// cursor of the next page, empty - there are no more items
+	NextCursor string
+}
+
+type TokenListRequest struct {
+	// application id
+	Id int `validate:"required"`
+	// search is made by the token id, sorting by name is not supported
+	Page ListRequest
+}
+
+type TokenListResponse struct {
+	Items []Token
+	// count of all items matching the filter
+	Total int
+	// cursor of the next page, empty - there are no more items
+	NextCursor string
+}
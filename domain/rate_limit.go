@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+type RateLimit struct {
+	Id int
+	// 0 for the policy of the application group
+	AppId int
+	// 0 for the policy of the application
+	AppGroupId int
+	// method or method pattern, empty - all methods
+	Method string
+	// 0 - the limit is not set
+	RequestsPerSecond int
+	RequestsPerMinute int
+	Burst             int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// RateLimitPolicy is the effective rate limit returned to gateways with auth data
+type RateLimitPolicy struct {
+	// application or application_group
+	Source string
+	// method or method pattern, empty - all methods
+	Method            string
+	RequestsPerSecond int
+	RequestsPerMinute int
+	Burst             int
+}
+
+type SetRateLimitRequest struct {
+	AppId             int    `validate:"required_without=AppGroupId,excluded_with=AppGroupId"`
+	AppGroupId        int    `validate:"required_without=AppId"`
+	Method            string `validate:"max=255"`
+	RequestsPerSecond int    `validate:"min=0,required_without=RequestsPerMinute"`
+	RequestsPerMinute int    `validate:"min=0"`
+	Burst             int    `validate:"min=0"`
+}
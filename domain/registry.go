@@ -0,0 +1,117 @@
+package domain
+
+const (
+	RegistryDocumentVersion = 1
+
+	RegistryFormatJson = "json"
+	RegistryFormatYaml = "yaml"
+
+	RegistryChangeCreate = "create"
+	RegistryChangeUpdate = "update"
+	RegistryChangeDelete = "delete"
+)
+
+// RegistryDocument describes domains, application groups, applications and their access lists of the system,
+// domains and groups are identified by names, applications by ids
+type RegistryDocument struct {
+	Version int              `validate:"required" yaml:"version"`
+	Domains []RegistryDomain `validate:"dive" yaml:"domains"`
+}
+
+type RegistryDomain struct {
+	Name        string               `validate:"required" yaml:"name"`
+	Description string               `yaml:"description,omitempty"`
+	AccessList  []RegistryAccessRule `validate:"dive" yaml:"accessList,omitempty"`
+	AppGroups   []RegistryAppGroup   `validate:"dive" yaml:"appGroups,omitempty"`
+}
+
+type RegistryAppGroup struct {
+	Name         string                `validate:"required" yaml:"name"`
+	Description  string                `yaml:"description,omitempty"`
+	AccessList   []RegistryAccessRule  `validate:"dive" yaml:"accessList,omitempty"`
+	Applications []RegistryApplication `validate:"dive" yaml:"applications,omitempty"`
+}
+
+type RegistryApplication struct {
+	Id          int                  `validate:"required" yaml:"id"`
+	Name        string               `validate:"required" yaml:"name"`
+	Description string               `yaml:"description,omitempty"`
+	Type        string               `validate:"required,oneof=SYSTEM MOBILE" yaml:"type"`
+	AccessList  []RegistryAccessRule `validate:"dive" yaml:"accessList,omitempty"`
+}
+
+type RegistryAccessRule struct {
+	HttpMethod string `yaml:"httpMethod,omitempty"`
+	Method     string `validate:"required" yaml:"method"`
+	Value      bool   `yaml:"value"`
+}
+
+type RegistryExportRequest struct {
+	// json by default
+	Format string `validate:"omitempty,oneof=json yaml"`
+}
+
+type RegistryExportResponse struct {
+	// filled for json format
+	Document *RegistryDocument
+	// filled for yaml format
+	Yaml string
+}
+
+type RegistryImportRequest struct {
+	// one of Document and Yaml is required
+	Document *RegistryDocument
+	Yaml     string
+	// return the changes without applying them
+	DryRun bool
+}
+
+type RegistryImportResponse struct {
+	DryRun  bool
+	Changes []RegistryChange
+}
+
+type RegistryChange struct {
+	// create, update or delete
+	Action string
+	// domain, application_group, application or access_list
+	EntityType string
+	// name of the domain, <domain>/<group> for the group, id of the application,
+	// <owner type>:<owner key> for the access list
+	Key string
+	// changed fields for update
+	Fields []string
+}
+
+// DesiredState is the part of the registry declared in the remote config,
+// declared groups, applications and tokens are marked as managed by config
+type DesiredState struct {
+	AppGroups []DesiredAppGroup `validate:"dive"`
+}
+
+type DesiredAppGroup struct {
+	// name of the domain of the system, the domain is created if it doesn't exist
+	Domain       string `validate:"required"`
+	Name         string `validate:"required"`
+	Description  string
+	AccessList   []RegistryAccessRule `validate:"dive"`
+	Applications []DesiredApplication `validate:"dive"`
+}
+
+type DesiredApplication struct {
+	Id          int    `validate:"required"`
+	Name        string `validate:"required"`
+	Description string
+	Type        string               `validate:"required,oneof=SYSTEM MOBILE"`
+	AccessList  []RegistryAccessRule `validate:"dive"`
+	// tokens are issued by the API, the config only protects them from revocation
+	TokenIdList []string
+}
+
+type ReconcileResult struct {
+	Changes []RegistryChange
+	// changes of objects already managed by config, they were made bypassing the config
+	Drift []RegistryChange
+	// declared tokens which are not issued for their applications
+	MissingTokenIdList []string
+}
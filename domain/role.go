@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+type Role struct {
+	Id          int
+	Name        string
+	Description string
+	Methods     []MethodInfo
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type CreateRoleRequest struct {
+	Name        string `validate:"required"`
+	Description string
+	Methods     []MethodInfo
+}
+
+type UpdateRoleRequest struct {
+	Id          int    `validate:"required"`
+	Name        string `validate:"required"`
+	Description string
+	Methods     []MethodInfo
+}
+
+type SetApplicationRolesRequest struct {
+	AppId      int `validate:"required"`
+	RoleIdList []int
+}
+
+type SetAppGroupRolesRequest struct {
+	AppGroupId int `validate:"required"`
+	RoleIdList []int
+}
@@ -2,6 +2,9 @@ package domain
 
 type AuthenticateRequest struct {
 	Token string `validate:"required"`
+	// address of the client presenting the token, checked against allowed CIDR of the token and its application.
+	// If empty, the first x-forwarded-for address or the caller address is used
+	ClientAddress string
 }
 
 type AuthenticateResponse struct {
@@ -16,14 +19,76 @@ type AuthData struct {
 	DomainId      int
 	ServiceId     int
 	ApplicationId int
+	// methods and method patterns the token is restricted to, empty - the token is not restricted
+	Scope []string
+	// policies the gateway throttles the application with, empty - the application is not limited
+	RateLimits []RateLimitPolicy
 }
 
 type AuthorizeRequest struct {
 	ApplicationId int `validate:"required"`
 	HttpMethod    string
 	Endpoint      string `validate:"required"`
+	// scope of the authenticated token, empty - the token is not restricted
+	Scope []string
 }
 
 type AuthorizeResponse struct {
 	Authorized bool
+	// the endpoint is out of the token scope, access list is not checked
+	OutOfScope bool
+	Rule       *AccessRule
+}
+
+type AuthorizeBatchRequest struct {
+	ApplicationId int                 `validate:"required"`
+	Endpoints     []AuthorizeEndpoint `validate:"required,min=1,max=500,dive"`
+	// scope of the authenticated token, empty - the token is not restricted
+	Scope []string
+}
+
+type AuthorizeEndpoint struct {
+	HttpMethod string
+	Endpoint   string `validate:"required"`
+}
+
+type AuthorizeBatchResult struct {
+	HttpMethod string
+	Endpoint   string
+	Authorized bool
+	OutOfScope bool
+	Rule       *AccessRule
+}
+
+type CheckRequest struct {
+	Token      string `validate:"required"`
+	HttpMethod string
+	Endpoint   string `validate:"required"`
+	// the same as AuthenticateRequest.ClientAddress
+	ClientAddress string
+}
+
+type CheckResponse struct {
+	Authenticated bool
+	Authorized    bool
+	ErrorReason   string
+	AuthData      *AuthData
+	Rule          *AccessRule
+}
+
+type Jwks struct {
+	Keys []Jwk
+}
+
+type Jwk struct {
+	Kty string
+	Kid string
+	Alg string
+	Use string
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
 }
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+)
+
+type SigningKey struct {
+	Id         string
+	Algorithm  string
+	Status     string
+	CreatedAt  time.Time
+	RetiringAt *time.Time
+	RetiredAt  *time.Time
+	// empty for retired keys, their private keys are erased
+	PublicKey *Jwk
+}
+
+type RetireSigningKeyRequest struct {
+	Id string `validate:"required"`
+}
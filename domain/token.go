@@ -5,18 +5,83 @@ import (
 )
 
 type Token struct {
-	Token      string
+	Id          string
+	MaskedToken string
+	Kind        string
+	AppId       int
+	ExpireTime  int
+	// null if the token doesn't expire
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+	// null if the token has not been used since tracking was introduced
+	LastUsedAt      *time.Time
+	LastUsedAddress string
+	// empty if the token is not restricted
+	Scope []string
+	// empty if the token is allowed from any address
+	AllowedCidrs []string
+	// declared in the remote config, can not be revoked or rotated
+	ManagedByConfig bool
+}
+
+type GetUnusedTokensRequest struct {
+	// 0 - tokens of all applications
 	AppId      int
-	ExpireTime int
-	CreatedAt  time.Time
+	UnusedDays int `validate:"required,min=1"`
 }
 
 type TokenRevokeRequest struct {
-	AppId  int `validate:"required"`
-	Tokens []string
+	AppId       int `validate:"required"`
+	TokenIdList []string
 }
 
 type TokenCreateRequest struct {
 	AppId        int `validate:"required"`
 	ExpireTimeMs int `validate:"required"`
+	// methods and method patterns the token is restricted to within the access list of the application,
+	// empty - the token is not restricted
+	Scope []string `validate:"dive,required"`
+	// CIDR the source address of the token must belong to in addition to the application ones,
+	// empty - the token is not restricted
+	AllowedCidrs []string `validate:"dive,cidr"`
+}
+
+type TokenCreateResponse struct {
+	// returned only once, only the hash of the token is stored
+	Token  string
+	App    Application
+	Tokens []Token
+}
+
+type TokenRotateRequest struct {
+	AppId   int    `validate:"required"`
+	TokenId string `validate:"required"`
+	// lifetime of the new token, -1 - the token doesn't expire
+	ExpireTimeMs int `validate:"required"`
+	// 0 - the period from the config
+	OverlapHours int `validate:"min=0"`
+}
+
+type TokenRotateResponse struct {
+	// returned only once, only the hash of the token is stored
+	Token    string
+	NewToken Token
+	// expires after the overlap period
+	PreviousToken Token
+}
+
+type TokenSweepStatus struct {
+	Enabled          bool
+	GracePeriodHours int
+	// null if expired tokens have never been swept
+	LastRun *TokenSweepRun
+}
+
+type TokenSweepRun struct {
+	StartedAt            time.Time
+	FinishedAt           time.Time
+	DeletedTokens        int
+	DeletedRevokedTokens int
+	// empty if the run succeeded
+	Error string
 }
@@ -11,3 +11,20 @@ type Method struct {
 	HttpMethod string
 	Method     string
 }
+
+const (
+	AccessRuleSourceApplication     = "application"
+	AccessRuleSourceApplicationRole = "application_role"
+	AccessRuleSourceAppGroup        = "application_group"
+	AccessRuleSourceAppGroupRole    = "application_group_role"
+	AccessRuleSourceDomain          = "domain"
+)
+
+// AccessRule is an access rule effective for an application together with the entity it was inherited from
+type AccessRule struct {
+	Source     string
+	SourceId   int
+	HttpMethod string
+	Method     string
+	Value      bool
+}
@@ -6,10 +6,15 @@ import (
 )
 
 type AppGroup struct {
-	Id          int
-	Name        string
-	Description sql.NullString
-	DomainId    int
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	Id              int
+	Name            string
+	Description     sql.NullString
+	DomainId        int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Status          string
+	StatusReason    sql.NullString
+	StatusChangedBy sql.NullString
+	StatusChangedAt sql.NullTime
+	ManagedByConfig bool
 }
@@ -13,4 +13,10 @@ type Application struct {
 	Type               string
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
+	AllowedCidrs       AddressList
+	Status             string
+	StatusReason       sql.NullString
+	StatusChangedBy    sql.NullString
+	StatusChangedAt    sql.NullTime
+	ManagedByConfig    bool
 }
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	AuditEntityDomain      = "domain"
+	AuditEntityAppGroup    = "application_group"
+	AuditEntityApplication = "application"
+	AuditEntityAccessList  = "access_list"
+	AuditEntityToken       = "token"
+	AuditEntityRegistry    = "registry"
+	AuditEntityRole        = "role"
+	AuditEntityRateLimit   = "rate_limit"
+)
+
+type AuditEvent struct {
+	Id         int64
+	CreatedAt  time.Time
+	Actor      sql.NullString
+	RequestId  sql.NullString
+	Action     string
+	EntityType string
+	EntityId   string
+	Before     AuditState
+	After      AuditState
+}
+
+// AuditState is a JSON snapshot of the entity, empty state is stored as NULL
+type AuditState json.RawMessage
+
+func (s AuditState) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	return string(s), nil
+}
+
+func (s *AuditState) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*s = nil
+	case string:
+		*s = AuditState(src)
+	case []byte:
+		*s = append(AuditState(nil), src...)
+	default:
+		return errors.Errorf("unexpected audit state type %T", src)
+	}
+	return nil
+}
+
+type AuditEventFilter struct {
+	EntityType string
+	EntityId   string
+	Action     string
+	Actor      string
+	RequestId  string
+	From       sql.NullTime
+	To         sql.NullTime
+	// only events with id less than the cursor are returned, 0 - from the latest event
+	Cursor int64
+	Limit  int
+}
@@ -0,0 +1,15 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+type ChangeEvent struct {
+	Id int64
+	// null until the event is relayed
+	StreamOffset sql.NullInt64
+	CreatedAt    time.Time
+	Type         string
+	Payload      []byte
+}
@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// AddressList is a list of CIDR the source address must belong to,
+// nil and empty lists are stored as NULL and mean any address is allowed
+type AddressList []string
+
+func (l AddressList) Value() (driver.Value, error) {
+	return jsonListValue(l)
+}
+
+func (l *AddressList) Scan(src any) error {
+	return scanJsonList(src, (*[]string)(l))
+}
+
+// jsonListValue stores the list as JSON, empty list is stored as NULL
+func jsonListValue(list []string) (driver.Value, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	value, err := json.Marshal(list)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal json list")
+	}
+	return string(value), nil
+}
+
+func scanJsonList(src any, list *[]string) error {
+	var value []byte
+	switch src := src.(type) {
+	case nil:
+		*list = nil
+		return nil
+	case string:
+		value = []byte(src)
+	case []byte:
+		value = src
+	default:
+		return errors.Errorf("unexpected json list type %T", src)
+	}
+
+	err := json.Unmarshal(value, list)
+	if err != nil {
+		return errors.WithMessage(err, "unmarshal json list")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"database/sql"
+)
+
+// ListFilter is the page query of a table with keyset pagination by the sort field and the id
+type ListFilter struct {
+	Search         string
+	IdList         []int
+	Types          []string
+	AppGroupIdList []int
+	CreatedFrom    sql.NullTime
+	CreatedTo      sql.NullTime
+	// one of domain.ListSort*
+	SortField  string
+	Descending bool
+	// value of the sort field and the id of the last item of the previous page,
+	// only the id if the page is sorted by id, empty - the first page
+	After []any
+	// one more row than the limit is selected to detect the next page
+	Limit int
+}
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RateLimit struct {
+	Id int
+	// exactly one of AppId and AppGroupId is set
+	AppId      sql.NullInt64
+	AppGroupId sql.NullInt64
+	// empty for all methods
+	Method            string
+	RequestsPerSecond int
+	RequestsPerMinute int
+	Burst             int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
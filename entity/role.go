@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Role struct {
+	Id          int
+	Name        string
+	Description sql.NullString
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type RoleAccessList struct {
+	RoleId     int
+	HttpMethod string
+	Method     string
+	Value      bool
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	SigningKeyStatusActive   = "active"
+	SigningKeyStatusRetiring = "retiring"
+	SigningKeyStatusRetired  = "retired"
+)
+
+type SigningKey struct {
+	Id         string
+	Algorithm  string
+	PrivateKey string
+	Status     string
+	CreatedAt  time.Time
+	RetiringAt sql.NullTime
+	RetiredAt  sql.NullTime
+}
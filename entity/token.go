@@ -1,22 +1,81 @@
 package entity
 
 import (
+	"database/sql"
+	"database/sql/driver"
 	"time"
 )
 
+const (
+	TokenKindOpaque = "opaque"
+	TokenKindJwt    = "jwt"
+)
+
 type Token struct {
-	Token      string
-	AppId      int
-	ExpireTime int
-	CreatedAt  time.Time
+	Id          string
+	TokenHash   string
+	MaskedToken string
+	Kind        string
+	AppId       int
+	ExpireTime  int
+	// null if the token doesn't expire
+	ExpiresAt       sql.NullTime
+	CreatedAt       time.Time
+	LastUsedAt      sql.NullTime
+	LastUsedAddress sql.NullString
+	Scope           TokenScope
+	AllowedCidrs    AddressList
+	ManagedByConfig bool
+}
+
+// TokenScope is a list of methods and method patterns the token is restricted to,
+// nil and empty scopes are stored as NULL and mean the token is not restricted
+type TokenScope []string
+
+func (s TokenScope) Value() (driver.Value, error) {
+	return jsonListValue(s)
+}
+
+func (s *TokenScope) Scan(src any) error {
+	return scanJsonList(src, (*[]string)(s))
+}
+
+// RevokedToken is an entry of the JWT deny list
+type RevokedToken struct {
+	Id string
+	// null if the token doesn't expire
+	ExpiresAt sql.NullTime
+	RevokedAt time.Time
+	// the token is valid until RevokedAt after rotation
+	Rotated bool
+}
+
+type TokenUsage struct {
+	TokenId string
+	UsedAt  time.Time
+	Address string
 }
 
 type AuthData struct {
+	TokenId            string
 	AppName            string
 	SystemId           int
 	DomainId           int
 	ApplicationGroupId int
 	AppId              int
-	ExpireTime         int
+	ExpiresAt          sql.NullTime
 	CreatedAt          time.Time
+	Scope              TokenScope
+	AllowedCidrs       AddressList
+	AppAllowedCidrs    AddressList
+	AppStatus          string
+	AppGroupStatus     string
+}
+
+type TokenSweepStatus struct {
+	StartedAt            time.Time
+	FinishedAt           time.Time
+	DeletedTokens        int
+	DeletedRevokedTokens int
+	Error                sql.NullString
 }
@@ -0,0 +1,53 @@
+-- +goose Up
+CREATE TABLE role
+(
+    id          SERIAL4      NOT NULL PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at  TIMESTAMP    NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    updated_at  TIMESTAMP    NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    CONSTRAINT uq_role_name UNIQUE (name)
+);
+
+CREATE TRIGGER modify_role
+    BEFORE UPDATE OR INSERT
+    ON role
+    FOR EACH ROW EXECUTE PROCEDURE update_created_modified_column_date();
+
+CREATE TABLE role_access_list
+(
+    role_id     INT          NOT NULL,
+    http_method VARCHAR(255) NOT NULL DEFAULT '',
+    method      VARCHAR(255) NOT NULL,
+    value       BOOLEAN      NOT NULL,
+    PRIMARY KEY (role_id, http_method, method),
+    CONSTRAINT fk_role_id__role_id FOREIGN KEY (role_id) REFERENCES role (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE application_role
+(
+    app_id  INT NOT NULL,
+    role_id INT NOT NULL,
+    PRIMARY KEY (app_id, role_id),
+    CONSTRAINT fk_app_id__application_id FOREIGN KEY (app_id) REFERENCES application (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_role_id__role_id FOREIGN KEY (role_id) REFERENCES role (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_application_role__role_id ON application_role (role_id);
+
+CREATE TABLE application_group_role
+(
+    app_group_id INT NOT NULL,
+    role_id      INT NOT NULL,
+    PRIMARY KEY (app_group_id, role_id),
+    CONSTRAINT fk_app_group_id__application_group_id FOREIGN KEY (app_group_id) REFERENCES application_group (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_role_id__role_id FOREIGN KEY (role_id) REFERENCES role (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_application_group_role__role_id ON application_group_role (role_id);
+
+-- +goose Down
+DROP TABLE application_group_role;
+DROP TABLE application_role;
+DROP TABLE role_access_list;
+DROP TABLE role;
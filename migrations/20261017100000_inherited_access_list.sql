@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE app_group_access_list
+(
+    app_group_id INT          NOT NULL,
+    http_method  VARCHAR(255) NOT NULL DEFAULT '',
+    method       VARCHAR(255) NOT NULL,
+    value        BOOLEAN      NOT NULL,
+    PRIMARY KEY (app_group_id, http_method, method),
+    CONSTRAINT fk_app_group_id__application_group_id FOREIGN KEY (app_group_id) REFERENCES application_group (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE domain_access_list
+(
+    domain_id   INT          NOT NULL,
+    http_method VARCHAR(255) NOT NULL DEFAULT '',
+    method      VARCHAR(255) NOT NULL,
+    value       BOOLEAN      NOT NULL,
+    PRIMARY KEY (domain_id, http_method, method),
+    CONSTRAINT fk_domain_id__domain_id FOREIGN KEY (domain_id) REFERENCES domain (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- +goose Down
+DROP TABLE domain_access_list;
+DROP TABLE app_group_access_list;
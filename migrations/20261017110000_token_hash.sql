@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE token RENAME COLUMN token TO token_hash;
+-- existing rows keep raw tokens until they are rehashed on startup
+ALTER TABLE token ADD COLUMN hashed bool NOT NULL DEFAULT false;
+ALTER TABLE token ALTER COLUMN hashed SET DEFAULT true;
+
+-- +goose Down
+ALTER TABLE token DROP COLUMN hashed;
+ALTER TABLE token RENAME COLUMN token_hash TO token;
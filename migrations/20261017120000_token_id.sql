@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE token ADD COLUMN id TEXT;
+ALTER TABLE token ADD COLUMN masked_token TEXT NOT NULL DEFAULT '****';
+UPDATE token SET id = substr(md5(random()::text || clock_timestamp()::text || token_hash), 1, 16);
+ALTER TABLE token ALTER COLUMN id SET NOT NULL;
+ALTER TABLE token ALTER COLUMN masked_token DROP DEFAULT;
+CREATE UNIQUE INDEX uq_token_id ON token (id);
+
+-- +goose Down
+DROP INDEX uq_token_id;
+ALTER TABLE token DROP COLUMN masked_token;
+ALTER TABLE token DROP COLUMN id;
@@ -0,0 +1,39 @@
+-- +goose Up
+ALTER TABLE token ADD COLUMN kind TEXT NOT NULL DEFAULT 'opaque';
+
+-- deny list of signed tokens which are validated without token table
+CREATE TABLE revoked_token
+(
+    id         TEXT      NOT NULL PRIMARY KEY,
+    expires_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION revoke_jwt_token()
+    RETURNS TRIGGER AS
+$body$
+BEGIN
+    INSERT INTO revoked_token (id, expires_at)
+    VALUES (OLD.id,
+            CASE WHEN OLD.expire_time < 0 THEN NULL ELSE OLD.created_at + OLD.expire_time * INTERVAL '1 millisecond' END)
+    ON CONFLICT (id) DO NOTHING;
+    RETURN NULL;
+END;
+$body$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- claims of the token contain application id, so the token is revoked when it is deleted or moved to other application
+CREATE TRIGGER revoke_jwt_token
+    AFTER DELETE OR UPDATE OF app_id
+    ON token
+    FOR EACH ROW
+    WHEN (OLD.kind = 'jwt')
+EXECUTE PROCEDURE revoke_jwt_token();
+
+-- +goose Down
+DROP TRIGGER revoke_jwt_token ON token;
+DROP FUNCTION revoke_jwt_token();
+DROP TABLE revoked_token;
+ALTER TABLE token DROP COLUMN kind;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- private key is encrypted and is erased when the key is retired
+CREATE TABLE signing_key
+(
+    id          TEXT      NOT NULL PRIMARY KEY,
+    algorithm   TEXT      NOT NULL,
+    private_key TEXT      NOT NULL,
+    status      TEXT      NOT NULL CHECK (status IN ('active', 'retiring', 'retired')),
+    created_at  TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    retiring_at TIMESTAMP NULL,
+    retired_at  TIMESTAMP NULL
+);
+
+CREATE UNIQUE INDEX uq_signing_key_active ON signing_key (status) WHERE status = 'active';
+
+-- +goose Down
+DROP TABLE signing_key;
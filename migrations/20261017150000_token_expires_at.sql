@@ -0,0 +1,41 @@
+-- +goose Up
+ALTER TABLE token ADD COLUMN expires_at TIMESTAMP NULL;
+
+UPDATE token
+SET expires_at = created_at + expire_time * INTERVAL '1 millisecond'
+WHERE expire_time <> -1;
+
+CREATE INDEX ix_token_expires_at ON token (expires_at) WHERE expires_at IS NOT NULL;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION revoke_jwt_token()
+    RETURNS TRIGGER AS
+$body$
+BEGIN
+    INSERT INTO revoked_token (id, expires_at)
+    VALUES (OLD.id, OLD.expires_at)
+    ON CONFLICT (id) DO NOTHING;
+    RETURN NULL;
+END;
+$body$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION revoke_jwt_token()
+    RETURNS TRIGGER AS
+$body$
+BEGIN
+    INSERT INTO revoked_token (id, expires_at)
+    VALUES (OLD.id,
+            CASE WHEN OLD.expire_time < 0 THEN NULL ELSE OLD.created_at + OLD.expire_time * INTERVAL '1 millisecond' END)
+    ON CONFLICT (id) DO NOTHING;
+    RETURN NULL;
+END;
+$body$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP INDEX ix_token_expires_at;
+ALTER TABLE token DROP COLUMN expires_at;
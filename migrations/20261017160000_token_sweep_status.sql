@@ -0,0 +1,14 @@
+-- +goose Up
+-- the single row contains the status of the last token sweep made by any instance
+CREATE TABLE token_sweep_status
+(
+    id                     INT       NOT NULL PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    started_at             TIMESTAMP NOT NULL,
+    finished_at            TIMESTAMP NOT NULL,
+    deleted_tokens         INT       NOT NULL,
+    deleted_revoked_tokens INT       NOT NULL,
+    error                  TEXT      NULL
+);
+
+-- +goose Down
+DROP TABLE token_sweep_status;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE token ADD COLUMN last_used_at TIMESTAMP NULL;
+ALTER TABLE token ADD COLUMN last_used_address TEXT NULL;
+
+-- +goose Down
+ALTER TABLE token DROP COLUMN last_used_address;
+ALTER TABLE token DROP COLUMN last_used_at;
@@ -0,0 +1,35 @@
+-- +goose Up
+-- rotated JWT stays valid until revoked_at and is rejected as expired after it
+ALTER TABLE revoked_token ADD COLUMN rotated BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION revoke_jwt_token()
+    RETURNS TRIGGER AS
+$body$
+BEGIN
+    INSERT INTO revoked_token (id, expires_at)
+    VALUES (OLD.id, OLD.expires_at)
+    ON CONFLICT (id) DO UPDATE SET revoked_at = LEAST(revoked_token.revoked_at, EXCLUDED.revoked_at),
+                                   rotated    = false;
+    RETURN NULL;
+END;
+$body$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION revoke_jwt_token()
+    RETURNS TRIGGER AS
+$body$
+BEGIN
+    INSERT INTO revoked_token (id, expires_at)
+    VALUES (OLD.id, OLD.expires_at)
+    ON CONFLICT (id) DO NOTHING;
+    RETURN NULL;
+END;
+$body$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+ALTER TABLE revoked_token DROP COLUMN rotated;
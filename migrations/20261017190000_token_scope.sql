@@ -0,0 +1,7 @@
+-- +goose Up
+-- methods and method patterns the token is restricted to within the access list of its application,
+-- null - the token is not restricted
+ALTER TABLE token ADD COLUMN scope JSONB NULL;
+
+-- +goose Down
+ALTER TABLE token DROP COLUMN scope;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- CIDR the source address of the token must belong to, null - any address is allowed
+ALTER TABLE application ADD COLUMN allowed_cidrs JSONB NULL;
+ALTER TABLE token ADD COLUMN allowed_cidrs JSONB NULL;
+
+-- +goose Down
+ALTER TABLE token DROP COLUMN allowed_cidrs;
+ALTER TABLE application DROP COLUMN allowed_cidrs;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- rate limit policy of an application or an application group served to gateways with auth data,
+-- empty method applies to all methods, zero limit means the limit is not set
+CREATE TABLE rate_limit
+(
+    id                  SERIAL4      NOT NULL PRIMARY KEY,
+    app_id              INT          NULL,
+    app_group_id        INT          NULL,
+    method              VARCHAR(255) NOT NULL DEFAULT '',
+    requests_per_second INT          NOT NULL DEFAULT 0,
+    requests_per_minute INT          NOT NULL DEFAULT 0,
+    burst               INT          NOT NULL DEFAULT 0,
+    created_at          TIMESTAMP    NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    updated_at          TIMESTAMP    NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    CONSTRAINT ck_rate_limit_target CHECK ((app_id IS NULL) <> (app_group_id IS NULL)),
+    CONSTRAINT ck_rate_limit_limit CHECK (requests_per_second > 0 OR requests_per_minute > 0),
+    CONSTRAINT fk_app_id__application_id FOREIGN KEY (app_id) REFERENCES application (id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_app_group_id__application_group_id FOREIGN KEY (app_group_id) REFERENCES application_group (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE UNIQUE INDEX uq_rate_limit_app_id ON rate_limit (app_id, method) WHERE app_id IS NOT NULL;
+CREATE UNIQUE INDEX uq_rate_limit_app_group_id ON rate_limit (app_group_id, method) WHERE app_group_id IS NOT NULL;
+
+CREATE TRIGGER modify_rate_limit
+    BEFORE UPDATE OR INSERT
+    ON rate_limit
+    FOR EACH ROW EXECUTE PROCEDURE update_created_modified_column_date();
+
+-- +goose Down
+DROP TABLE rate_limit;
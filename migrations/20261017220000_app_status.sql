@@ -0,0 +1,26 @@
+-- +goose Up
+-- active, suspended or disabled, tokens of not active applications are not authenticated
+ALTER TABLE application
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+        CONSTRAINT ck_application_status CHECK (status IN ('active', 'suspended', 'disabled')),
+    ADD COLUMN status_reason TEXT NULL,
+    ADD COLUMN status_changed_by TEXT NULL,
+    ADD COLUMN status_changed_at TIMESTAMP NULL;
+ALTER TABLE application_group
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+        CONSTRAINT ck_application_group_status CHECK (status IN ('active', 'suspended', 'disabled')),
+    ADD COLUMN status_reason TEXT NULL,
+    ADD COLUMN status_changed_by TEXT NULL,
+    ADD COLUMN status_changed_at TIMESTAMP NULL;
+
+-- +goose Down
+ALTER TABLE application_group
+    DROP COLUMN status_changed_at,
+    DROP COLUMN status_changed_by,
+    DROP COLUMN status_reason,
+    DROP COLUMN status;
+ALTER TABLE application
+    DROP COLUMN status_changed_at,
+    DROP COLUMN status_changed_by,
+    DROP COLUMN status_reason,
+    DROP COLUMN status;
@@ -0,0 +1,38 @@
+-- +goose Up
+-- append-only log of registry mutations written in the transaction of the mutation
+CREATE TABLE audit_event
+(
+    id          BIGSERIAL    NOT NULL PRIMARY KEY,
+    created_at  TIMESTAMP    NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    actor       TEXT         NULL,
+    request_id  TEXT         NULL,
+    action      VARCHAR(255) NOT NULL,
+    entity_type VARCHAR(255) NOT NULL,
+    entity_id   TEXT         NOT NULL,
+    before      JSONB        NULL,
+    after       JSONB        NULL
+);
+
+CREATE INDEX ix_audit_event_entity ON audit_event (entity_type, entity_id);
+CREATE INDEX ix_audit_event_created_at ON audit_event (created_at);
+CREATE INDEX ix_audit_event_actor ON audit_event (actor);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION reject_audit_event_change()
+    RETURNS TRIGGER AS
+$body$
+BEGIN
+    RAISE EXCEPTION 'audit_event is append-only';
+END;
+$body$
+    LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER append_only_audit_event
+    BEFORE UPDATE OR DELETE
+    ON audit_event
+    FOR EACH ROW EXECUTE PROCEDURE reject_audit_event_change();
+
+-- +goose Down
+DROP TABLE audit_event;
+DROP FUNCTION reject_audit_event_change();
@@ -0,0 +1,18 @@
+-- +goose Up
+-- transactional outbox of registry changes, written in the transaction of the change;
+-- stream_offset is assigned by the relay in the order events become visible, so it never skips uncommitted events
+CREATE TABLE change_event
+(
+    id            BIGSERIAL    NOT NULL PRIMARY KEY,
+    stream_offset BIGINT       NULL,
+    created_at    TIMESTAMP    NOT NULL DEFAULT (now() AT TIME ZONE 'utc'),
+    type          VARCHAR(255) NOT NULL,
+    payload       JSONB        NOT NULL
+);
+
+CREATE UNIQUE INDEX ux_change_event_stream_offset ON change_event (stream_offset);
+CREATE INDEX ix_change_event_pending ON change_event (id) WHERE stream_offset IS NULL;
+CREATE INDEX ix_change_event_created_at ON change_event (created_at);
+
+-- +goose Down
+DROP TABLE change_event;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- objects declared in the desired state of the remote config, they are changed only by reconciliation
+ALTER TABLE application_group
+    ADD COLUMN managed_by_config BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE application
+    ADD COLUMN managed_by_config BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE token
+    ADD COLUMN managed_by_config BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE token
+    DROP COLUMN managed_by_config;
+ALTER TABLE application
+    DROP COLUMN managed_by_config;
+ALTER TABLE application_group
+    DROP COLUMN managed_by_config;
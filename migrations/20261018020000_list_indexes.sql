@@ -0,0 +1,14 @@
+-- +goose Up
+-- keyset pagination of listing methods goes by the sort column and the id
+CREATE INDEX ix_application_created_at ON application (created_at, id);
+CREATE INDEX ix_application_name ON application (name, id);
+CREATE INDEX ix_application_group_created_at ON application_group (created_at, id);
+CREATE INDEX ix_application_group_name ON application_group (name, id);
+CREATE INDEX ix_token_app_id_created_at ON token (app_id, created_at, id);
+
+-- +goose Down
+DROP INDEX ix_token_app_id_created_at;
+DROP INDEX ix_application_group_name;
+DROP INDEX ix_application_group_created_at;
+DROP INDEX ix_application_name;
+DROP INDEX ix_application_created_at;
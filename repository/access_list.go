@@ -2,9 +2,8 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
 
-	"isp-system-service/domain"
 	"isp-system-service/entity"
 
 	"github.com/Masterminds/squirrel"
@@ -24,28 +23,93 @@ func NewAccessList(db db.DB) AccessList {
 	}
 }
 
-func (r AccessList) GetAccessListByAppIdAndMethod(ctx context.Context, appId int, httpMethod string, method string) (*entity.AccessList, error) {
-	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.GetAccessListByAppIdAndMethod")
+// accessRulesCte selects rules of the application, its roles, its group, group roles and domain.
+// appId is an sql expression evaluating to the application id
+func accessRulesCte(appId string) string {
+	return fmt.Sprintf(`
+	rules AS (
+		SELECT 'application' AS source, al.app_id AS source_id, al.http_method, al.method, al.value
+		FROM access_list al
+		WHERE al.app_id = %[1]s
+		UNION ALL
+		SELECT 'application_role', ar.role_id, ral.http_method, ral.method, ral.value
+		FROM role_access_list ral
+		JOIN application_role ar ON ar.role_id = ral.role_id
+		WHERE ar.app_id = %[1]s
+		UNION ALL
+		SELECT 'application_group', agal.app_group_id, agal.http_method, agal.method, agal.value
+		FROM app_group_access_list agal
+		JOIN application a ON a.application_group_id = agal.app_group_id
+		WHERE a.id = %[1]s
+		UNION ALL
+		SELECT 'application_group_role', agr.role_id, ral.http_method, ral.method, ral.value
+		FROM role_access_list ral
+		JOIN application_group_role agr ON agr.role_id = ral.role_id
+		JOIN application a ON a.application_group_id = agr.app_group_id
+		WHERE a.id = %[1]s
+		UNION ALL
+		SELECT 'domain', dal.domain_id, dal.http_method, dal.method, dal.value
+		FROM domain_access_list dal
+		JOIN application_group ag ON ag.domain_id = dal.domain_id
+		JOIN application a ON a.application_group_id = ag.id
+		WHERE a.id = %[1]s
+	)`, appId)
+}
 
-	q := `
-		SELECT app_id, http_method, method, value
-		FROM access_list
-		WHERE app_id = $1
-		AND method = $2
-		AND http_method IN ($3, '')
-		ORDER BY (http_method = $3) DESC
-		LIMIT 1;
+var effectiveAccessRulesQuery = `
+	WITH ` + accessRulesCte("$1") + `
+	SELECT source, source_id, http_method, method, value
+	FROM rules
 	`
-	result := entity.AccessList{}
-	err := r.db.SelectRow(ctx, &result, q, appId, method, httpMethod)
-	switch {
-	case errors.Is(err, sql.ErrNoRows):
-		return nil, domain.ErrAccessListNotFound
-	case err != nil:
+
+func (r AccessList) GetApplicableAccessRules(ctx context.Context, appId int, httpMethod string, method string) ([]entity.AccessRule, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.GetApplicableAccessRules")
+
+	q := effectiveAccessRulesQuery + `
+	WHERE (method = $2 OR strpos(method, '*') > 0)
+	AND http_method IN ($3, '')
+	`
+	result := make([]entity.AccessRule, 0)
+	err := r.db.Select(ctx, &result, q, appId, method, httpMethod)
+	if err != nil {
 		return nil, errors.WithMessagef(err, "exec query %s", q)
-	default:
-		return &result, nil
 	}
+
+	return result, nil
+}
+
+func (r AccessList) GetApplicableAccessRulesBatch(
+	ctx context.Context,
+	appId int,
+	httpMethods []string,
+	methods []string,
+) ([]entity.AccessRule, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.GetApplicableAccessRulesBatch")
+
+	q := effectiveAccessRulesQuery + `
+	WHERE (method = ANY($2) OR strpos(method, '*') > 0)
+	AND (http_method = '' OR http_method = ANY($3))
+	`
+	result := make([]entity.AccessRule, 0)
+	err := r.db.Select(ctx, &result, q, appId, methods, httpMethods)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r AccessList) GetEffectiveAccessRules(ctx context.Context, appId int) ([]entity.AccessRule, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.GetEffectiveAccessRules")
+
+	q := effectiveAccessRulesQuery
+	result := make([]entity.AccessRule, 0)
+	err := r.db.Select(ctx, &result, q, appId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
 }
 
 func (r AccessList) GetAccessListByAppId(ctx context.Context, appId int) ([]entity.AccessList, error) {
@@ -180,3 +244,115 @@ func (r AccessList) DeleteAccessListByAppId(ctx context.Context, appId int) ([]e
 
 	return result, nil
 }
+
+func (r AccessList) GetAppGroupAccessList(ctx context.Context, appGroupId int) ([]entity.AccessRule, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.GetAppGroupAccessList")
+
+	q := `
+	SELECT 'application_group' AS source, app_group_id AS source_id, http_method, method, value
+	FROM app_group_access_list
+	WHERE app_group_id = $1
+	ORDER BY method, http_method
+	`
+	result := make([]entity.AccessRule, 0)
+	err := r.db.Select(ctx, &result, q, appGroupId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r AccessList) UpsertAppGroupAccessList(ctx context.Context, appGroupId int, methods []entity.AccessRule) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.UpsertAppGroupAccessList")
+
+	qBuilder := query.New().
+		Insert("app_group_access_list").
+		Columns("app_group_id", "http_method", "method", "value")
+	for _, m := range methods {
+		qBuilder = qBuilder.Values(appGroupId, m.HttpMethod, m.Method, m.Value)
+	}
+	qBuilder = qBuilder.Suffix("ON CONFLICT (app_group_id, http_method, method) DO UPDATE SET value = EXCLUDED.value")
+	q, args, err := qBuilder.ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+func (r AccessList) DeleteAppGroupAccessList(ctx context.Context, appGroupId int) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.DeleteAppGroupAccessList")
+
+	q := `
+	DELETE FROM app_group_access_list
+	WHERE app_group_id = $1
+	`
+	_, err := r.db.Exec(ctx, q, appGroupId)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+func (r AccessList) GetDomainAccessList(ctx context.Context, domainId int) ([]entity.AccessRule, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.GetDomainAccessList")
+
+	q := `
+	SELECT 'domain' AS source, domain_id AS source_id, http_method, method, value
+	FROM domain_access_list
+	WHERE domain_id = $1
+	ORDER BY method, http_method
+	`
+	result := make([]entity.AccessRule, 0)
+	err := r.db.Select(ctx, &result, q, domainId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r AccessList) UpsertDomainAccessList(ctx context.Context, domainId int, methods []entity.AccessRule) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.UpsertDomainAccessList")
+
+	qBuilder := query.New().
+		Insert("domain_access_list").
+		Columns("domain_id", "http_method", "method", "value")
+	for _, m := range methods {
+		qBuilder = qBuilder.Values(domainId, m.HttpMethod, m.Method, m.Value)
+	}
+	qBuilder = qBuilder.Suffix("ON CONFLICT (domain_id, http_method, method) DO UPDATE SET value = EXCLUDED.value")
+	q, args, err := qBuilder.ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+func (r AccessList) DeleteDomainAccessList(ctx context.Context, domainId int) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AccessList.DeleteDomainAccessList")
+
+	q := `
+	DELETE FROM domain_access_list
+	WHERE domain_id = $1
+	`
+	_, err := r.db.Exec(ctx, q, domainId)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
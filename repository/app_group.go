@@ -29,7 +29,8 @@ func (r AppGroup) GetAppGroupById(ctx context.Context, id int) (*entity.AppGroup
 	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.GetAppGroupById")
 
 	q := `
-	SELECT id, name, description, domain_id, created_at, updated_at
+	SELECT id, name, description, domain_id, created_at, updated_at,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
 	FROM application_group
 	WHERE id = $1
 	`
@@ -49,7 +50,8 @@ func (r AppGroup) GetAppGroupByIdList(ctx context.Context, idList []int) ([]enti
 	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.GetAppGroupByIdList")
 
 	q, arg, err := query.New().
-		Select("id", "name", "description", "domain_id", "created_at", "updated_at").
+		Select("id", "name", "description", "domain_id", "created_at", "updated_at",
+			"status", "status_reason", "status_changed_by", "status_changed_at", "managed_by_config").
 		From("application_group").
 		Where(squirrel.Eq{"id": idList}).
 		OrderBy("created_at DESC").
@@ -71,7 +73,8 @@ func (r AppGroup) GetAppGroupByDomainId(ctx context.Context, domainIdList []int)
 	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.GetAppGroupByDomainId")
 
 	q, arg, err := query.New().
-		Select("id", "name", "description", "domain_id", "created_at", "updated_at").
+		Select("id", "name", "description", "domain_id", "created_at", "updated_at",
+			"status", "status_reason", "status_changed_by", "status_changed_at", "managed_by_config").
 		From("application_group").
 		Where(squirrel.Eq{"domain_id": domainIdList}).
 		OrderBy("created_at DESC").
@@ -93,7 +96,8 @@ func (r AppGroup) GetAppGroupByNameAndDomainId(ctx context.Context, name string,
 	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.GetAppGroupByNameAndDomainId")
 
 	q := `
-	SELECT id, name, description, domain_id, created_at, updated_at
+	SELECT id, name, description, domain_id, created_at, updated_at,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
 	FROM application_group
 	WHERE name = $1 AND domain_id = $2
 	`
@@ -117,7 +121,8 @@ func (r AppGroup) CreateAppGroup(ctx context.Context, name string, desc string,
 	(name, description, domain_id)
 	VALUES ($1, $2, $3)
 	ON CONFLICT (name, domain_id) DO NOTHING
-	RETURNING id, name, description, domain_id, created_at, updated_at
+	RETURNING id, name, description, domain_id, created_at, updated_at,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
 	`
 	result := entity.AppGroup{}
 	err := r.db.SelectRow(ctx, &result, q, name, desc, domainId)
@@ -138,7 +143,8 @@ func (r AppGroup) UpdateAppGroup(ctx context.Context, id int, name string, descr
 	UPDATE application_group 
 	SET name = $1, description = $2
 	WHERE id = $3
-	RETURNING id, name, description, domain_id, created_at, updated_at
+	RETURNING id, name, description, domain_id, created_at, updated_at,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
 	`
 	result := entity.AppGroup{}
 	err := r.db.SelectRow(ctx, &result, q, name, description, id)
@@ -155,6 +161,37 @@ func (r AppGroup) UpdateAppGroup(ctx context.Context, id int, name string, descr
 	}
 }
 
+func (r AppGroup) SetAppGroupStatus(
+	ctx context.Context,
+	id int,
+	status string,
+	reason string,
+	changedBy string,
+) (*entity.AppGroup, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.SetAppGroupStatus")
+
+	q := `
+	UPDATE application_group
+	SET status = $2,
+		status_reason = NULLIF($3, ''),
+		status_changed_by = NULLIF($4, ''),
+		status_changed_at = (now() AT TIME ZONE 'utc')
+	WHERE id = $1
+	RETURNING id, name, description, domain_id, created_at, updated_at,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
+	`
+	result := entity.AppGroup{}
+	err := r.db.SelectRow(ctx, &result, q, id, status, reason, changedBy)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrAppGroupNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	default:
+		return &result, nil
+	}
+}
+
 func (r AppGroup) DeleteAppGroup(ctx context.Context, idList []int) (int, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.DeleteAppGroup")
 
@@ -179,23 +216,48 @@ func (r AppGroup) DeleteAppGroup(ctx context.Context, idList []int) (int, error)
 	return int(rowsAffected), nil
 }
 
-func (r AppGroup) GetAllAppGroups(ctx context.Context) ([]entity.AppGroup, error) {
-	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.GetAllAppGroups")
+// ListAppGroups returns the page of application groups matching the filter
+func (r AppGroup) ListAppGroups(ctx context.Context, filter entity.ListFilter) ([]entity.AppGroup, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.ListAppGroups")
 
-	q, arg, err := query.New().
-		Select("id", "name", "description", "domain_id", "created_at", "updated_at").
+	builder := query.New().
+		Select("id", "name", "description", "domain_id", "created_at", "updated_at",
+			"status", "status_reason", "status_changed_by", "status_changed_at", "managed_by_config").
 		From("application_group").
-		OrderBy("created_at DESC").
-		ToSql()
+		Where(listWhere(filter, "name", "description"))
+	q, args, err := listPage(builder, filter).ToSql()
 	if err != nil {
 		return nil, errors.WithMessage(err, "build query")
 	}
 
 	result := make([]entity.AppGroup, 0)
-	err = r.db.Select(ctx, &result, q, arg...)
+	err = r.db.Select(ctx, &result, q, args...)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "exec query %s", q)
 	}
 
 	return result, nil
 }
+
+// CountAppGroups returns the count of application groups matching the filter regardless of the page
+func (r AppGroup) CountAppGroups(ctx context.Context, filter entity.ListFilter) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.CountAppGroups")
+
+	return listCount(ctx, r.db, "application_group", listWhere(filter, "name", "description"))
+}
+
+// MarkManagedAppGroups marks groups of the list as managed by config and unmarks the rest
+func (r AppGroup) MarkManagedAppGroups(ctx context.Context, idList []int) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "AppGroup.MarkManagedAppGroups")
+
+	q := `
+	UPDATE application_group
+	SET managed_by_config = COALESCE(id = ANY($1), false)
+	WHERE managed_by_config <> COALESCE(id = ANY($1), false)
+	`
+	_, err := r.db.Exec(ctx, q, idList)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+	return nil
+}
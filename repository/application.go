@@ -29,7 +29,8 @@ func (r Application) GetApplicationById(ctx context.Context, id int) (*entity.Ap
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.GetApplicationById")
 
 	q := `
-	SELECT id, name, description, application_group_id, type, created_at, updated_at
+	SELECT id, name, description, application_group_id, type, created_at, updated_at, allowed_cidrs,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
 	FROM application
 	WHERE id = $1
 	`
@@ -49,7 +50,8 @@ func (r Application) GetApplicationByIdList(ctx context.Context, idList []int) (
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.GetApplicationByIdList")
 
 	q, args, err := query.New().
-		Select("id", "name", "description", "application_group_id", "type", "created_at", "updated_at").
+		Select("id", "name", "description", "application_group_id", "type", "created_at", "updated_at", "allowed_cidrs",
+			"status", "status_reason", "status_changed_by", "status_changed_at", "managed_by_config").
 		From("application").
 		Where(squirrel.Eq{"id": idList}).
 		OrderBy("created_at DESC").
@@ -71,7 +73,8 @@ func (r Application) GetApplicationByAppGroupIdList(ctx context.Context, appGrou
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.GetApplicationByAppGroupIdList")
 
 	q, args, err := query.New().
-		Select("id", "name", "description", "application_group_id", "type", "created_at", "updated_at").
+		Select("id", "name", "description", "application_group_id", "type", "created_at", "updated_at", "allowed_cidrs",
+			"status", "status_reason", "status_changed_by", "status_changed_at", "managed_by_config").
 		From("application").
 		Where(squirrel.Eq{"application_group_id": appGroupIdList}).
 		OrderBy("created_at DESC").
@@ -93,7 +96,8 @@ func (r Application) GetApplicationByNameAndAppGroupId(ctx context.Context, name
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.GetApplicationByNameAndAppGroupId")
 
 	q := `
-	SELECT id, name, description, application_group_id, type, created_at, updated_at
+	SELECT id, name, description, application_group_id, type, created_at, updated_at, allowed_cidrs,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
 	FROM application 
 	WHERE name = $1 AND application_group_id = $2
 	`
@@ -123,7 +127,8 @@ func (r Application) CreateApplication(
 	INSERT INTO application 
 	(id, name, description, application_group_id, type)
 	VALUES ($1, $2, $3, $4, $5)
-	RETURNING id, name, description, application_group_id, type, created_at, updated_at
+	RETURNING id, name, description, application_group_id, type, created_at, updated_at, allowed_cidrs,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
 	`
 	result := entity.Application{}
 	err := r.db.SelectRow(ctx, &result, q, id, name, desc, appGroupId, appType)
@@ -146,7 +151,8 @@ func (r Application) UpdateApplication(
 	SET name = $2,
 		description = $3
 	WHERE id = $1
-	RETURNING id, name, description, application_group_id, type, created_at, updated_at
+	RETURNING id, name, description, application_group_id, type, created_at, updated_at, allowed_cidrs,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
 	`
 	result := entity.Application{}
 	err := r.db.SelectRow(ctx, &result, q, id, name, description)
@@ -156,6 +162,35 @@ func (r Application) UpdateApplication(
 	return &result, nil
 }
 
+// UpdateApplicationDefinition updates all declared fields of the application including its group
+func (r Application) UpdateApplicationDefinition(
+	ctx context.Context,
+	id int,
+	name string,
+	description string,
+	appGroupId int,
+	appType string,
+) (*entity.Application, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.UpdateApplicationDefinition")
+
+	q := `
+	UPDATE application
+	SET name = $2,
+		description = $3,
+		application_group_id = $4,
+		type = $5
+	WHERE id = $1
+	RETURNING id, name, description, application_group_id, type, created_at, updated_at, allowed_cidrs,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
+	`
+	result := entity.Application{}
+	err := r.db.SelectRow(ctx, &result, q, id, name, description, appGroupId, appType)
+	if err != nil {
+		return nil, r.handleUpdateError(err, q)
+	}
+	return &result, nil
+}
+
 func (r Application) UpdateApplicationWithNewId(
 	ctx context.Context,
 	oldId int,
@@ -171,7 +206,8 @@ func (r Application) UpdateApplicationWithNewId(
 		name = $3,
 		description = $4
 	WHERE id = $1
-	RETURNING id, name, description, application_group_id, type, created_at, updated_at
+	RETURNING id, name, description, application_group_id, type, created_at, updated_at, allowed_cidrs,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
 	`
 	result := entity.Application{}
 	err := r.db.SelectRow(ctx, &result, q, oldId, newId, name, description)
@@ -181,6 +217,59 @@ func (r Application) UpdateApplicationWithNewId(
 	return &result, nil
 }
 
+func (r Application) SetApplicationAllowedCidrs(ctx context.Context, id int, allowedCidrs entity.AddressList) (*entity.Application, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.SetApplicationAllowedCidrs")
+
+	q := `
+	UPDATE application
+	SET allowed_cidrs = $2
+	WHERE id = $1
+	RETURNING id, name, description, application_group_id, type, created_at, updated_at, allowed_cidrs,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
+	`
+	result := entity.Application{}
+	err := r.db.SelectRow(ctx, &result, q, id, allowedCidrs)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrApplicationNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func (r Application) SetApplicationStatus(
+	ctx context.Context,
+	id int,
+	status string,
+	reason string,
+	changedBy string,
+) (*entity.Application, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.SetApplicationStatus")
+
+	q := `
+	UPDATE application
+	SET status = $2,
+		status_reason = NULLIF($3, ''),
+		status_changed_by = NULLIF($4, ''),
+		status_changed_at = (now() AT TIME ZONE 'utc')
+	WHERE id = $1
+	RETURNING id, name, description, application_group_id, type, created_at, updated_at, allowed_cidrs,
+		status, status_reason, status_changed_by, status_changed_at, managed_by_config
+	`
+	result := entity.Application{}
+	err := r.db.SelectRow(ctx, &result, q, id, status, reason, changedBy)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrApplicationNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	default:
+		return &result, nil
+	}
+}
+
 func (r Application) DeleteApplicationByIdList(ctx context.Context, idList []int) (int, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.DeleteApplicationByIdList")
 
@@ -217,16 +306,18 @@ func (r Application) NextApplicationId(ctx context.Context) (int, error) {
 	return nextAppId, nil
 }
 
-func (r Application) GetAllApplications(ctx context.Context) ([]entity.Application, error) {
-	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.GetAllApplications")
+// ListApplications returns the page of applications matching the filter
+func (r Application) ListApplications(ctx context.Context, filter entity.ListFilter) ([]entity.Application, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.ListApplications")
 
-	q, args, err := query.New().
-		Select("id", "name", "description", "application_group_id", "type", "created_at", "updated_at").
+	builder := query.New().
+		Select("id", "name", "description", "application_group_id", "type", "created_at", "updated_at", "allowed_cidrs",
+			"status", "status_reason", "status_changed_by", "status_changed_at", "managed_by_config").
 		From("application").
-		OrderBy("created_at DESC").
-		ToSql()
+		Where(applicationListWhere(filter))
+	q, args, err := listPage(builder, filter).ToSql()
 	if err != nil {
-		return nil, errors.WithMessagef(err, "exec query %s", q)
+		return nil, errors.WithMessage(err, "build query")
 	}
 
 	result := make([]entity.Application, 0)
@@ -238,6 +329,13 @@ func (r Application) GetAllApplications(ctx context.Context) ([]entity.Applicati
 	return result, nil
 }
 
+// CountApplications returns the count of applications matching the filter regardless of the page
+func (r Application) CountApplications(ctx context.Context, filter entity.ListFilter) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.CountApplications")
+
+	return listCount(ctx, r.db, "application", applicationListWhere(filter))
+}
+
 func (r Application) handleCreateError(err error, q string) error {
 	var pgErr *pgconn.PgError
 	if !errors.As(err, &pgErr) {
@@ -273,3 +371,33 @@ func (r Application) handleUpdateError(err error, q string) error {
 		return errors.WithMessagef(err, "exec query %s", q)
 	}
 }
+
+// MarkManagedApplications marks applications of the list as managed by config and unmarks the rest
+func (r Application) MarkManagedApplications(ctx context.Context, idList []int) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Application.MarkManagedApplications")
+
+	q := `
+	UPDATE application
+	SET managed_by_config = COALESCE(id = ANY($1), false)
+	WHERE managed_by_config <> COALESCE(id = ANY($1), false)
+	`
+	_, err := r.db.Exec(ctx, q, idList)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+	return nil
+}
+
+func applicationListWhere(filter entity.ListFilter) squirrel.And {
+	where := listWhere(filter, "name", "description")
+	if len(filter.IdList) > 0 {
+		where = append(where, squirrel.Eq{"id": filter.IdList})
+	}
+	if len(filter.Types) > 0 {
+		where = append(where, squirrel.Eq{"type": filter.Types})
+	}
+	if len(filter.AppGroupIdList) > 0 {
+		where = append(where, squirrel.Eq{"application_group_id": filter.AppGroupIdList})
+	}
+	return where
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+
+	"isp-system-service/entity"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+)
+
+type Audit struct {
+	db db.DB
+}
+
+func NewAudit(db db.DB) Audit {
+	return Audit{
+		db: db,
+	}
+}
+
+func (r Audit) InsertAuditEvent(ctx context.Context, event entity.AuditEvent) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Audit.InsertAuditEvent")
+
+	q := `
+	INSERT INTO audit_event
+	(actor, request_id, action, entity_type, entity_id, before, after)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.Exec(ctx, q,
+		event.Actor, event.RequestId, event.Action, event.EntityType, event.EntityId, event.Before, event.After,
+	)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+	return nil
+}
+
+// SearchAuditEvents returns events matching the filter from the latest to the earliest,
+// one extra event is selected to detect the next page
+func (r Audit) SearchAuditEvents(ctx context.Context, filter entity.AuditEventFilter) ([]entity.AuditEvent, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Audit.SearchAuditEvents")
+
+	where := squirrel.And{}
+	if filter.EntityType != "" {
+		where = append(where, squirrel.Eq{"entity_type": filter.EntityType})
+	}
+	if filter.EntityId != "" {
+		where = append(where, squirrel.Eq{"entity_id": filter.EntityId})
+	}
+	if filter.Action != "" {
+		where = append(where, squirrel.Eq{"action": filter.Action})
+	}
+	if filter.Actor != "" {
+		where = append(where, squirrel.Eq{"actor": filter.Actor})
+	}
+	if filter.RequestId != "" {
+		where = append(where, squirrel.Eq{"request_id": filter.RequestId})
+	}
+	if filter.From.Valid {
+		where = append(where, squirrel.GtOrEq{"created_at": filter.From.Time})
+	}
+	if filter.To.Valid {
+		where = append(where, squirrel.Lt{"created_at": filter.To.Time})
+	}
+	if filter.Cursor > 0 {
+		where = append(where, squirrel.Lt{"id": filter.Cursor})
+	}
+
+	q, args, err := query.New().
+		Select("id", "created_at", "actor", "request_id", "action", "entity_type", "entity_id", "before", "after").
+		From("audit_event").
+		Where(where).
+		OrderBy("id DESC").
+		Limit(uint64(filter.Limit) + 1).
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.AuditEvent, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
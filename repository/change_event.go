@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"isp-system-service/entity"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+)
+
+type ChangeEvent struct {
+	db db.DB
+}
+
+func NewChangeEvent(db db.DB) ChangeEvent {
+	return ChangeEvent{
+		db: db,
+	}
+}
+
+func (r ChangeEvent) InsertChangeEvent(ctx context.Context, event entity.ChangeEvent) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "ChangeEvent.InsertChangeEvent")
+
+	q := `
+	INSERT INTO change_event (type, payload)
+	VALUES ($1, $2)
+	`
+	_, err := r.db.Exec(ctx, q, event.Type, string(event.Payload))
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+	return nil
+}
+
+// AssignChangeEventOffsets numbers committed events without offset in the order of insertion
+// following the last assigned offset, it must be called under the relay lock
+func (r ChangeEvent) AssignChangeEventOffsets(ctx context.Context, limit int) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "ChangeEvent.AssignChangeEventOffsets")
+
+	q := `
+	WITH pending AS (
+		SELECT id, row_number() OVER (ORDER BY id) AS position
+		FROM change_event
+		WHERE stream_offset IS NULL
+		ORDER BY id
+		LIMIT $1
+	), last AS (
+		SELECT COALESCE(MAX(stream_offset), 0) AS stream_offset
+		FROM change_event
+	)
+	UPDATE change_event
+	SET stream_offset = last.stream_offset + pending.position
+	FROM pending, last
+	WHERE change_event.id = pending.id
+	`
+	result, err := r.db.Exec(ctx, q, limit)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.WithMessage(err, "get rows affected")
+	}
+
+	return int(rowsAffected), nil
+}
+
+// GetChangeEvents returns relayed events with offset greater than the given one in the order of offsets
+func (r ChangeEvent) GetChangeEvents(ctx context.Context, afterOffset int64, limit int) ([]entity.ChangeEvent, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "ChangeEvent.GetChangeEvents")
+
+	q := `
+	SELECT id, stream_offset, created_at, type, payload
+	FROM change_event
+	WHERE stream_offset > $1
+	ORDER BY stream_offset
+	LIMIT $2
+	`
+	result := make([]entity.ChangeEvent, 0)
+	err := r.db.Select(ctx, &result, q, afterOffset, limit)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r ChangeEvent) GetLastChangeEventOffset(ctx context.Context) (int64, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "ChangeEvent.GetLastChangeEventOffset")
+
+	q := `
+	SELECT COALESCE(MAX(stream_offset), 0)
+	FROM change_event
+	`
+	var result int64
+	err := r.db.SelectRow(ctx, &result, q)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+// DeleteExpiredChangeEvents deletes relayed events older than the retention period,
+// the last relayed event is kept as offsets continue from it
+func (r ChangeEvent) DeleteExpiredChangeEvents(ctx context.Context, retention time.Duration) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "ChangeEvent.DeleteExpiredChangeEvents")
+
+	q := `
+	DELETE FROM change_event
+	WHERE created_at < (now() AT TIME ZONE 'utc') - $1 * INTERVAL '1 second'
+		AND stream_offset < (SELECT MAX(stream_offset) FROM change_event)
+	`
+	result, err := r.db.Exec(ctx, q, int64(retention.Seconds()))
+	if err != nil {
+		return 0, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.WithMessage(err, "get rows affected")
+	}
+
+	return int(rowsAffected), nil
+}
@@ -6,4 +6,8 @@ const (
 	applicationFkAppGroupConstraintName = "fk_application_group_id"
 
 	applicationGroupUniqueNameConstraint = "uq_name_domain_name"
+
+	roleUniqueNameConstraint = "uq_role_name"
+
+	tokenExpiredExpr = "COALESCE(token.expires_at <= (now() AT TIME ZONE 'utc'), false)"
 )
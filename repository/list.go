@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+)
+
+var (
+	listSortColumns = map[string]string{
+		domain.ListSortId:        "id",
+		domain.ListSortName:      "name",
+		domain.ListSortCreatedAt: "created_at",
+	}
+
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// listWhere returns the conditions of the filter common for listed tables,
+// the search substring is matched against any of searchColumns
+func listWhere(filter entity.ListFilter, searchColumns ...string) squirrel.And {
+	where := squirrel.And{}
+	if filter.Search != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		search := squirrel.Or{}
+		for _, column := range searchColumns {
+			search = append(search, squirrel.ILike{column: pattern})
+		}
+		where = append(where, search)
+	}
+	if filter.CreatedFrom.Valid {
+		where = append(where, squirrel.GtOrEq{"created_at": filter.CreatedFrom.Time})
+	}
+	if filter.CreatedTo.Valid {
+		where = append(where, squirrel.Lt{"created_at": filter.CreatedTo.Time})
+	}
+	return where
+}
+
+// listPage continues the query after the last item of the previous page in the sort order,
+// the id breaks ties of equal sort values
+func listPage(builder squirrel.SelectBuilder, filter entity.ListFilter) squirrel.SelectBuilder {
+	column := listSortColumns[filter.SortField]
+	direction, operator := "ASC", ">"
+	if filter.Descending {
+		direction, operator = "DESC", "<"
+	}
+	builder = builder.Limit(uint64(filter.Limit) + 1)
+
+	if column == "id" {
+		if len(filter.After) > 0 {
+			builder = builder.Where(squirrel.Expr("id "+operator+" ?", filter.After[0]))
+		}
+		return builder.OrderBy("id " + direction)
+	}
+	if len(filter.After) > 0 {
+		builder = builder.Where(squirrel.Expr(fmt.Sprintf("(%s, id) %s (?, ?)", column, operator), filter.After...))
+	}
+	return builder.OrderBy(column+" "+direction, "id "+direction)
+}
+
+func listCount(ctx context.Context, db db.DB, table string, where squirrel.And) (int, error) {
+	q, args, err := query.New().
+		Select("COUNT(*)").
+		From(table).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return 0, errors.WithMessage(err, "build query")
+	}
+
+	total := 0
+	err = db.SelectRow(ctx, &total, q, args...)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "exec query %s", q)
+	}
+	return total, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+)
+
+type Notification struct {
+	db db.DB
+}
+
+func NewNotification(db db.DB) Notification {
+	return Notification{
+		db: db,
+	}
+}
+
+func (r Notification) Notify(ctx context.Context, channel string, payload string) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Notification.Notify")
+
+	q := `SELECT pg_notify($1, $2)`
+	_, err := r.db.Exec(ctx, q, channel, payload)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+const (
+	unlistenTimeout = 5 * time.Second
+)
+
+type DbProvider interface {
+	DB() (*dbx.Client, error)
+}
+
+type NotificationListener struct {
+	db DbProvider
+}
+
+func NewNotificationListener(db DbProvider) NotificationListener {
+	return NotificationListener{
+		db: db,
+	}
+}
+
+// Listen holds a dedicated connection listening to the channel and calls handler for every notification.
+// onListen is called once the connection is subscribed.
+// Blocks until ctx is done or the connection fails
+func (r NotificationListener) Listen(
+	ctx context.Context,
+	channel string,
+	onListen func(),
+	handler func(payload string),
+) error {
+	cli, err := r.db.DB()
+	if err != nil {
+		return errors.WithMessage(err, "get db client")
+	}
+
+	conn, err := cli.Conn(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "acquire connection")
+	}
+	defer conn.Close() // nolint:errcheck
+
+	return conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.Errorf("unexpected driver connection %T", driverConn)
+		}
+		pgConn := stdlibConn.Conn()
+
+		_, err := pgConn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+		if err != nil {
+			return errors.WithMessage(err, "listen")
+		}
+		defer func() {
+			// connection returns to the pool, it must not stay subscribed
+			unlistenCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unlistenTimeout)
+			defer cancel()
+			_, _ = pgConn.Exec(unlistenCtx, "UNLISTEN *")
+		}()
+		onListen()
+
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return errors.WithMessage(err, "wait for notification")
+			}
+			handler(notification.Payload)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+
+	"isp-system-service/entity"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+)
+
+var (
+	rateLimitColumns = []string{
+		"id", "app_id", "app_group_id", "method", "requests_per_second", "requests_per_minute", "burst",
+		"created_at", "updated_at",
+	}
+)
+
+type RateLimit struct {
+	db db.DB
+}
+
+func NewRateLimit(db db.DB) RateLimit {
+	return RateLimit{
+		db: db,
+	}
+}
+
+func (r RateLimit) GetRateLimitsByIdList(ctx context.Context, idList []int) ([]entity.RateLimit, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "RateLimit.GetRateLimitsByIdList")
+
+	return r.selectRateLimits(ctx, squirrel.Eq{"id": idList})
+}
+
+func (r RateLimit) GetRateLimitsByAppId(ctx context.Context, appId int) ([]entity.RateLimit, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "RateLimit.GetRateLimitsByAppId")
+
+	return r.selectRateLimits(ctx, squirrel.Eq{"app_id": appId})
+}
+
+func (r RateLimit) GetRateLimitsByAppGroupId(ctx context.Context, appGroupId int) ([]entity.RateLimit, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "RateLimit.GetRateLimitsByAppGroupId")
+
+	return r.selectRateLimits(ctx, squirrel.Eq{"app_group_id": appGroupId})
+}
+
+// GetEffectiveRateLimits returns policies of the application and of its group,
+// the policy of the application overrides the policy of the group for the same method
+func (r RateLimit) GetEffectiveRateLimits(ctx context.Context, appId int) ([]entity.RateLimit, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "RateLimit.GetEffectiveRateLimits")
+
+	q := `
+	SELECT DISTINCT ON (method) id, app_id, app_group_id, method, requests_per_second, requests_per_minute, burst,
+		created_at, updated_at
+	FROM rate_limit
+	WHERE app_id = $1
+		OR app_group_id = (SELECT application_group_id FROM application WHERE id = $1)
+	ORDER BY method, app_id IS NULL
+	`
+	result := make([]entity.RateLimit, 0)
+	err := r.db.Select(ctx, &result, q, appId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+// UpsertRateLimit replaces the policy of the application or the application group for the method
+func (r RateLimit) UpsertRateLimit(ctx context.Context, rateLimit entity.RateLimit) (*entity.RateLimit, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "RateLimit.UpsertRateLimit")
+
+	conflict := "(app_id, method) WHERE app_id IS NOT NULL"
+	if rateLimit.AppGroupId.Valid {
+		conflict = "(app_group_id, method) WHERE app_group_id IS NOT NULL"
+	}
+	q := `
+	INSERT INTO rate_limit
+	(app_id, app_group_id, method, requests_per_second, requests_per_minute, burst)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT ` + conflict + ` DO UPDATE SET
+		requests_per_second = EXCLUDED.requests_per_second,
+		requests_per_minute = EXCLUDED.requests_per_minute,
+		burst = EXCLUDED.burst
+	RETURNING id, app_id, app_group_id, method, requests_per_second, requests_per_minute, burst, created_at, updated_at
+	`
+	result := entity.RateLimit{}
+	err := r.db.SelectRow(ctx, &result, q,
+		rateLimit.AppId, rateLimit.AppGroupId, rateLimit.Method,
+		rateLimit.RequestsPerSecond, rateLimit.RequestsPerMinute, rateLimit.Burst,
+	)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return &result, nil
+}
+
+func (r RateLimit) DeleteRateLimits(ctx context.Context, idList []int) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "RateLimit.DeleteRateLimits")
+
+	q, args, err := query.New().
+		Delete("rate_limit").
+		Where(squirrel.Eq{"id": idList}).
+		ToSql()
+	if err != nil {
+		return 0, errors.WithMessage(err, "build query")
+	}
+
+	result, err := r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.WithMessage(err, "get rows affected")
+	}
+
+	return int(rowsAffected), nil
+}
+
+func (r RateLimit) selectRateLimits(ctx context.Context, where squirrel.Sqlizer) ([]entity.RateLimit, error) {
+	q, args, err := query.New().
+		Select(rateLimitColumns...).
+		From("rate_limit").
+		Where(where).
+		OrderBy("method").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RateLimit, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/db/query"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+)
+
+type Role struct {
+	db db.DB
+}
+
+func NewRole(db db.DB) Role {
+	return Role{
+		db: db,
+	}
+}
+
+func (r Role) GetRoleByIdList(ctx context.Context, idList []int) ([]entity.Role, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetRoleByIdList")
+
+	q, args, err := query.New().
+		Select("id", "name", "description", "created_at", "updated_at").
+		From("role").
+		Where(squirrel.Eq{"id": idList}).
+		OrderBy("name").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.Role, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Role) GetAllRoles(ctx context.Context) ([]entity.Role, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetAllRoles")
+
+	q := `
+	SELECT id, name, description, created_at, updated_at
+	FROM role
+	ORDER BY name
+	`
+	result := make([]entity.Role, 0)
+	err := r.db.Select(ctx, &result, q)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Role) GetRolesByAppId(ctx context.Context, appId int) ([]entity.Role, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetRolesByAppId")
+
+	q := `
+	SELECT r.id, r.name, r.description, r.created_at, r.updated_at
+	FROM role r
+	JOIN application_role ar ON ar.role_id = r.id
+	WHERE ar.app_id = $1
+	ORDER BY r.name
+	`
+	result := make([]entity.Role, 0)
+	err := r.db.Select(ctx, &result, q, appId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Role) GetRolesByAppGroupId(ctx context.Context, appGroupId int) ([]entity.Role, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetRolesByAppGroupId")
+
+	q := `
+	SELECT r.id, r.name, r.description, r.created_at, r.updated_at
+	FROM role r
+	JOIN application_group_role agr ON agr.role_id = r.id
+	WHERE agr.app_group_id = $1
+	ORDER BY r.name
+	`
+	result := make([]entity.Role, 0)
+	err := r.db.Select(ctx, &result, q, appGroupId)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Role) CreateRole(ctx context.Context, name string, description string) (*entity.Role, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.CreateRole")
+
+	q := `
+	INSERT INTO role
+	(name, description)
+	VALUES ($1, $2)
+	ON CONFLICT (name) DO NOTHING
+	RETURNING id, name, description, created_at, updated_at
+	`
+	result := entity.Role{}
+	err := r.db.SelectRow(ctx, &result, q, name, description)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrRoleDuplicateName
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func (r Role) UpdateRole(ctx context.Context, id int, name string, description string) (*entity.Role, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.UpdateRole")
+
+	q := `
+	UPDATE role
+	SET name = $1, description = $2
+	WHERE id = $3
+	RETURNING id, name, description, created_at, updated_at
+	`
+	result := entity.Role{}
+	err := r.db.SelectRow(ctx, &result, q, name, description, id)
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr) && pgErr.ConstraintName == roleUniqueNameConstraint:
+		return nil, domain.ErrRoleDuplicateName
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, domain.ErrRoleNotFound
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+func (r Role) DeleteRoles(ctx context.Context, idList []int) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.DeleteRoles")
+
+	q, args, err := query.New().
+		Delete("role").
+		Where(squirrel.Eq{"id": idList}).
+		ToSql()
+	if err != nil {
+		return 0, errors.WithMessage(err, "build query")
+	}
+
+	result, err := r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.WithMessage(err, "get rows affected")
+	}
+
+	return int(rowsAffected), nil
+}
+
+// GetAppIdListByRoleIdList returns applications which are assigned any of the roles
+func (r Role) GetAppIdListByRoleIdList(ctx context.Context, roleIdList []int) ([]int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetAppIdListByRoleIdList")
+
+	q, args, err := query.New().
+		Select("app_id").
+		Distinct().
+		From("application_role").
+		Where(squirrel.Eq{"role_id": roleIdList}).
+		OrderBy("app_id").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]int, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+// GetAppGroupIdListByRoleIdList returns application groups which are assigned any of the roles
+func (r Role) GetAppGroupIdListByRoleIdList(ctx context.Context, roleIdList []int) ([]int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetAppGroupIdListByRoleIdList")
+
+	q, args, err := query.New().
+		Select("app_group_id").
+		Distinct().
+		From("application_group_role").
+		Where(squirrel.Eq{"role_id": roleIdList}).
+		OrderBy("app_group_id").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]int, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Role) GetRoleAccessListByRoleIdList(ctx context.Context, roleIdList []int) ([]entity.RoleAccessList, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.GetRoleAccessListByRoleIdList")
+
+	q, args, err := query.New().
+		Select("role_id", "http_method", "method", "value").
+		From("role_access_list").
+		Where(squirrel.Eq{"role_id": roleIdList}).
+		OrderBy("method", "http_method").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.RoleAccessList, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Role) InsertRoleAccessList(ctx context.Context, accessList []entity.RoleAccessList) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.InsertRoleAccessList")
+
+	qBuilder := query.New().
+		Insert("role_access_list").
+		Columns("role_id", "http_method", "method", "value")
+	for _, e := range accessList {
+		qBuilder = qBuilder.Values(e.RoleId, e.HttpMethod, e.Method, e.Value)
+	}
+	qBuilder = qBuilder.Suffix("ON CONFLICT (role_id, http_method, method) DO UPDATE SET value = EXCLUDED.value")
+	q, args, err := qBuilder.ToSql()
+	if err != nil {
+		return errors.WithMessage(err, "build query")
+	}
+
+	_, err = r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+func (r Role) DeleteRoleAccessListByRoleId(ctx context.Context, roleId int) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.DeleteRoleAccessListByRoleId")
+
+	q := `
+	DELETE FROM role_access_list
+	WHERE role_id = $1
+	`
+	_, err := r.db.Exec(ctx, q, roleId)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+func (r Role) SetApplicationRoles(ctx context.Context, appId int, roleIdList []int) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.SetApplicationRoles")
+
+	q := `
+	DELETE FROM application_role
+	WHERE app_id = $1
+	`
+	_, err := r.db.Exec(ctx, q, appId)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+	if len(roleIdList) == 0 {
+		return nil
+	}
+
+	q = `
+	INSERT INTO application_role
+	(app_id, role_id)
+	SELECT $1, unnest($2::int[])
+	ON CONFLICT DO NOTHING
+	`
+	_, err = r.db.Exec(ctx, q, appId, roleIdList)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+func (r Role) SetAppGroupRoles(ctx context.Context, appGroupId int, roleIdList []int) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Role.SetAppGroupRoles")
+
+	q := `
+	DELETE FROM application_group_role
+	WHERE app_group_id = $1
+	`
+	_, err := r.db.Exec(ctx, q, appGroupId)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+	if len(roleIdList) == 0 {
+		return nil
+	}
+
+	q = `
+	INSERT INTO application_group_role
+	(app_group_id, role_id)
+	SELECT $1, unnest($2::int[])
+	ON CONFLICT DO NOTHING
+	`
+	_, err = r.db.Exec(ctx, q, appGroupId, roleIdList)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"isp-system-service/entity"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/db"
+	"github.com/txix-open/isp-kit/metrics/sql_metrics"
+)
+
+type SigningKey struct {
+	db db.DB
+}
+
+func NewSigningKey(db db.DB) SigningKey {
+	return SigningKey{
+		db: db,
+	}
+}
+
+func (r SigningKey) GetSigningKeys(ctx context.Context) ([]entity.SigningKey, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "SigningKey.GetSigningKeys")
+
+	q := `
+	SELECT id, algorithm, private_key, status, created_at, retiring_at, retired_at
+	FROM signing_key
+	ORDER BY created_at DESC
+	`
+	result := make([]entity.SigningKey, 0)
+	err := r.db.Select(ctx, &result, q)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r SigningKey) GetNotRetiredSigningKeys(ctx context.Context) ([]entity.SigningKey, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "SigningKey.GetNotRetiredSigningKeys")
+
+	q := `
+	SELECT id, algorithm, private_key, status, created_at, retiring_at, retired_at
+	FROM signing_key
+	WHERE status <> $1
+	ORDER BY created_at DESC
+	`
+	result := make([]entity.SigningKey, 0)
+	err := r.db.Select(ctx, &result, q, entity.SigningKeyStatusRetired)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r SigningKey) GetSigningKeyById(ctx context.Context, id string) (*entity.SigningKey, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "SigningKey.GetSigningKeyById")
+
+	q := `
+	SELECT id, algorithm, private_key, status, created_at, retiring_at, retired_at
+	FROM signing_key
+	WHERE id = $1
+	`
+	result := entity.SigningKey{}
+	err := r.db.SelectRow(ctx, &result, q, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil //nolint:nilnil
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+// LockSigningKeys serializes key changes made by several instances until the end of the transaction,
+// reads are not blocked
+func (r SigningKey) LockSigningKeys(ctx context.Context) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "SigningKey.LockSigningKeys")
+
+	q := `LOCK TABLE signing_key IN SHARE ROW EXCLUSIVE MODE`
+	_, err := r.db.Exec(ctx, q)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+func (r SigningKey) InsertSigningKey(ctx context.Context, key entity.SigningKey) (*entity.SigningKey, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "SigningKey.InsertSigningKey")
+
+	q := `
+	INSERT INTO signing_key
+	(id, algorithm, private_key, status)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, algorithm, private_key, status, created_at, retiring_at, retired_at
+	`
+	result := entity.SigningKey{}
+	err := r.db.SelectRow(ctx, &result, q, key.Id, key.Algorithm, key.PrivateKey, key.Status)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return &result, nil
+}
+
+// MarkActiveSigningKeyRetiring moves the active key to retiring state, it verifies tokens until it is retired
+func (r SigningKey) MarkActiveSigningKeyRetiring(ctx context.Context) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "SigningKey.MarkActiveSigningKeyRetiring")
+
+	q := `
+	UPDATE signing_key
+	SET status = $1, retiring_at = (now() AT TIME ZONE 'utc')
+	WHERE status = $2
+	`
+	_, err := r.db.Exec(ctx, q, entity.SigningKeyStatusRetiring, entity.SigningKeyStatusActive)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+// RetireSigningKeys retires retiring keys and erases their private keys
+func (r SigningKey) RetireSigningKeys(ctx context.Context, idList []string) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "SigningKey.RetireSigningKeys")
+
+	q := `
+	UPDATE signing_key
+	SET status = $1, retired_at = (now() AT TIME ZONE 'utc'), private_key = ''
+	WHERE id = ANY($2) AND status = $3
+	`
+	_, err := r.db.Exec(ctx, q, entity.SigningKeyStatusRetired, idList, entity.SigningKeyStatusRetiring)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
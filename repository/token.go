@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"isp-system-service/domain"
 	"isp-system-service/entity"
@@ -14,6 +15,13 @@ import (
 	"github.com/txix-open/isp-kit/metrics/sql_metrics"
 )
 
+var (
+	tokenColumns = []string{
+		"id", "token_hash", "masked_token", "kind", "app_id", "expire_time", "expires_at", "created_at",
+		"last_used_at", "last_used_address", "scope", "allowed_cidrs", "managed_by_config",
+	}
+)
+
 type Token struct {
 	db db.DB
 }
@@ -24,17 +32,19 @@ func NewToken(db db.DB) Token {
 	}
 }
 
-func (r Token) SaveToken(ctx context.Context, token string, appId int, expireTime int) (*entity.Token, error) {
+func (r Token) SaveToken(ctx context.Context, token entity.Token) (*entity.Token, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.SaveToken")
 
 	q := `
 	INSERT INTO token
-	(token, app_id, expire_time)
-	VALUES ($1, $2, $3)
-	RETURNING token, app_id, expire_time, created_at
+	(id, token_hash, masked_token, kind, app_id, expire_time, expires_at, scope, allowed_cidrs)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, token_hash, masked_token, kind, app_id, expire_time, expires_at, created_at, last_used_at, last_used_address, scope, allowed_cidrs, managed_by_config
 	`
 	result := entity.Token{}
-	err := r.db.SelectRow(ctx, &result, q, token, appId, expireTime)
+	err := r.db.SelectRow(ctx, &result, q,
+		token.Id, token.TokenHash, token.MaskedToken, token.Kind, token.AppId, token.ExpireTime, token.ExpiresAt, token.Scope, token.AllowedCidrs,
+	)
 	if err != nil {
 		return nil, errors.WithMessage(err, "select row db")
 	}
@@ -42,16 +52,38 @@ func (r Token) SaveToken(ctx context.Context, token string, appId int, expireTim
 	return &result, nil
 }
 
-func (r Token) GetTokenById(ctx context.Context, token string) (*entity.Token, error) {
+func (r Token) GetTokenById(ctx context.Context, tokenHash string) (*entity.Token, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.GetTokenById")
 
 	q := `
-	SELECT token, app_id, expire_time, created_at
+	SELECT id, token_hash, masked_token, kind, app_id, expire_time, expires_at, created_at, last_used_at, last_used_address, scope, allowed_cidrs, managed_by_config
+	FROM token
+	WHERE token_hash = $1
+	`
+	result := entity.Token{}
+	err := r.db.SelectRow(ctx, &result, q, tokenHash)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil //nolint:nilnil
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+// GetTokenByAppIdAndId locks the token of the application until the end of the transaction
+func (r Token) GetTokenByAppIdAndId(ctx context.Context, appId int, id string) (*entity.Token, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.GetTokenByAppIdAndId")
+
+	q := `
+	SELECT id, token_hash, masked_token, kind, app_id, expire_time, expires_at, created_at, last_used_at, last_used_address, scope, allowed_cidrs, managed_by_config
 	FROM token
-	WHERE token = $1
+	WHERE app_id = $1 AND id = $2
+	FOR UPDATE
 	`
 	result := entity.Token{}
-	err := r.db.SelectRow(ctx, &result, q, token)
+	err := r.db.SelectRow(ctx, &result, q, appId, id)
 	switch {
 	case errors.Is(err, sql.ErrNoRows):
 		return nil, nil //nolint:nilnil
@@ -66,7 +98,7 @@ func (r Token) GetTokenByAppIdList(ctx context.Context, appIdList []int) ([]enti
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.GetTokenByAppIdList")
 
 	q, args, err := query.New().
-		Select("token", "app_id", "expire_time", "created_at").
+		Select(tokenColumns...).
 		From("token").
 		Where(squirrel.Eq{"app_id": appIdList}).
 		OrderBy("created_at DESC").
@@ -84,12 +116,41 @@ func (r Token) GetTokenByAppIdList(ctx context.Context, appIdList []int) ([]enti
 	return result, nil
 }
 
-func (r Token) DeleteToken(ctx context.Context, tokens []string) (int, error) {
+// ListTokens returns the page of tokens of the application matching the filter, the search is made by the token id
+func (r Token) ListTokens(ctx context.Context, appId int, filter entity.ListFilter) ([]entity.Token, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.ListTokens")
+
+	builder := query.New().
+		Select(tokenColumns...).
+		From("token").
+		Where(tokenListWhere(appId, filter))
+	q, args, err := listPage(builder, filter).ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.Token, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+// CountTokens returns the count of tokens of the application matching the filter regardless of the page
+func (r Token) CountTokens(ctx context.Context, appId int, filter entity.ListFilter) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.CountTokens")
+
+	return listCount(ctx, r.db, "token", tokenListWhere(appId, filter))
+}
+
+func (r Token) DeleteToken(ctx context.Context, tokenHashes []string) (int, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.DeleteToken")
 
 	q, args, err := query.New().
 		Delete("token").
-		Where(squirrel.Eq{"token": tokens}).
+		Where(squirrel.Eq{"token_hash": tokenHashes}).
 		ToSql()
 	if err != nil {
 		return 0, errors.WithMessagef(err, "build query")
@@ -108,11 +169,103 @@ func (r Token) DeleteToken(ctx context.Context, tokens []string) (int, error) {
 	return int(rowsAffected), nil
 }
 
-func (r Token) AuthDataByToken(ctx context.Context, token string) (*entity.AuthData, error) {
+// DeleteTokensByIdList deletes tokens of the application and returns hashes of deleted tokens
+func (r Token) DeleteTokensByIdList(ctx context.Context, appId int, idList []string) ([]string, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.DeleteTokensByIdList")
+
+	q := `
+	DELETE FROM token
+	WHERE app_id = $1 AND id = ANY($2)
+	RETURNING token_hash
+	`
+	result := make([]string, 0)
+	err := r.db.Select(ctx, &result, q, appId, idList)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+// GetRevokedToken returns nil if the token is not in the deny list
+func (r Token) GetRevokedToken(ctx context.Context, id string) (*entity.RevokedToken, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.GetRevokedToken")
+
+	q := `
+	SELECT id, expires_at, revoked_at, rotated
+	FROM revoked_token
+	WHERE id = $1
+	`
+	result := entity.RevokedToken{}
+	err := r.db.SelectRow(ctx, &result, q, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil //nolint:nilnil
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+// ScheduleJwtRevocation adds the JWT of the application to the deny list effective from revokeAt,
+// the entry keeps the original expiration of the token, so it is not swept before the token expires.
+// Opaque tokens are skipped
+func (r Token) ScheduleJwtRevocation(ctx context.Context, appId int, id string, revokeAt time.Time) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.ScheduleJwtRevocation")
+
+	q := `
+	INSERT INTO revoked_token (id, expires_at, revoked_at, rotated)
+	SELECT id, expires_at, $3, true
+	FROM token
+	WHERE app_id = $1 AND id = $2 AND kind = $4
+	ON CONFLICT (id) DO UPDATE SET revoked_at = LEAST(revoked_token.revoked_at, EXCLUDED.revoked_at)
+	`
+	_, err := r.db.Exec(ctx, q, appId, id, revokeAt, entity.TokenKindJwt)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+// ShortenTokenExpiry sets expiration of the token of the application to expiresAt if it expires later,
+// returns nil if the token is not found
+func (r Token) ShortenTokenExpiry(ctx context.Context, appId int, id string, expiresAt time.Time) (*entity.Token, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.ShortenTokenExpiry")
+
+	q := `
+	UPDATE token
+	SET expires_at = LEAST(COALESCE(expires_at, $3), $3)
+	WHERE app_id = $1 AND id = $2
+	RETURNING id, token_hash, masked_token, kind, app_id, expire_time, expires_at, created_at, last_used_at, last_used_address, scope, allowed_cidrs, managed_by_config
+	`
+	result := entity.Token{}
+	err := r.db.SelectRow(ctx, &result, q, appId, id, expiresAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil //nolint:nilnil
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	default:
+		return &result, nil
+	}
+}
+
+type authDataRow struct {
+	entity.AuthData
+	Expired bool
+}
+
+// AuthDataByToken returns ErrTokenExpired instead of auth data of the token expired by the database clock
+func (r Token) AuthDataByToken(ctx context.Context, tokenHash string) (*entity.AuthData, error) {
 	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.AuthDataByToken")
 
 	q := `
-SELECT system_id, domain_id, application_group_id, app_id, application.name AS app_name , token.expire_time, token.created_at
+SELECT token.id AS token_id, system_id, domain_id, application_group_id, app_id, application.name AS app_name , token.expires_at, token.created_at, token.scope,
+		token.allowed_cidrs, application.allowed_cidrs AS app_allowed_cidrs,
+		application.status AS app_status, application_group.status AS app_group_status,
+       ` + tokenExpiredExpr + ` AS expired
 FROM token
          LEFT JOIN application
                    ON token.app_id = application.id
@@ -120,16 +273,281 @@ FROM token
                    ON application.application_group_id = application_group.id
          LEFT JOIN domain
                    ON application_group.domain_id = domain.id
-WHERE token = $1
+WHERE token_hash = $1
 `
-	result := entity.AuthData{}
-	err := r.db.SelectRow(ctx, &result, q, token)
+	result := authDataRow{}
+	err := r.db.SelectRow(ctx, &result, q, tokenHash)
 	switch {
 	case errors.Is(err, sql.ErrNoRows):
 		return nil, domain.ErrTokenNotFound
 	case err != nil:
 		return nil, errors.WithMessagef(err, "exec query %s", q)
+	case result.Expired:
+		return nil, domain.ErrTokenExpired
+	default:
+		return &result.AuthData, nil
+	}
+}
+
+type authDataWithAccessRule struct {
+	authDataRow
+	Source     sql.NullString
+	SourceId   sql.NullInt64
+	HttpMethod sql.NullString
+	Method     sql.NullString
+	Value      sql.NullBool
+}
+
+// AuthDataWithAccessRules returns auth data of the token together with the access rules of its application
+// applicable to the method in a single query
+func (r Token) AuthDataWithAccessRules(
+	ctx context.Context,
+	tokenHash string,
+	httpMethod string,
+	method string,
+) (*entity.AuthData, []entity.AccessRule, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.AuthDataWithAccessRules")
+
+	q := `
+	WITH auth AS (
+		SELECT token.id AS token_id, system_id, domain_id, application_group_id, app_id, application.name AS app_name, token.expires_at, token.created_at, token.scope,
+		token.allowed_cidrs, application.allowed_cidrs AS app_allowed_cidrs,
+		application.status AS app_status, application_group.status AS app_group_status,
+			` + tokenExpiredExpr + ` AS expired
+		FROM token
+		LEFT JOIN application ON token.app_id = application.id
+		LEFT JOIN application_group ON application.application_group_id = application_group.id
+		LEFT JOIN domain ON application_group.domain_id = domain.id
+		WHERE token_hash = $1
+	),
+	` + accessRulesCte("(SELECT app_id FROM auth)") + `
+	SELECT auth.token_id, auth.system_id, auth.domain_id, auth.application_group_id, auth.app_id, auth.app_name, auth.expires_at, auth.created_at,
+		auth.scope, auth.allowed_cidrs, auth.app_allowed_cidrs,
+		auth.app_status, auth.app_group_status, auth.expired, rules.source, rules.source_id, rules.http_method, rules.method, rules.value
+	FROM auth
+	LEFT JOIN rules ON (rules.method = $3 OR strpos(rules.method, '*') > 0) AND rules.http_method IN ($2, '')
+	`
+	rows := make([]authDataWithAccessRule, 0)
+	err := r.db.Select(ctx, &rows, q, tokenHash, httpMethod, method)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+	if len(rows) == 0 {
+		return nil, nil, domain.ErrTokenNotFound
+	}
+	if rows[0].Expired {
+		return nil, nil, domain.ErrTokenExpired
+	}
+
+	rules := make([]entity.AccessRule, 0, len(rows))
+	for _, row := range rows {
+		if !row.Source.Valid {
+			continue
+		}
+		rules = append(rules, entity.AccessRule{
+			Source:     row.Source.String,
+			SourceId:   int(row.SourceId.Int64),
+			HttpMethod: row.HttpMethod.String,
+			Method:     row.Method.String,
+			Value:      row.Value.Bool,
+		})
+	}
+	return &rows[0].AuthData, rules, nil
+}
+
+// GetNotHashedTokens returns tokens stored before hashing was introduced, rows are locked until the end of transaction
+func (r Token) GetNotHashedTokens(ctx context.Context) ([]string, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.GetNotHashedTokens")
+
+	q := `
+	SELECT token_hash
+	FROM token
+	WHERE NOT hashed
+	FOR UPDATE
+	`
+	result := make([]string, 0)
+	err := r.db.Select(ctx, &result, q)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+func (r Token) ReplaceWithHashes(ctx context.Context, tokens []string, tokenHashes []string, maskedTokens []string) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.ReplaceWithHashes")
+
+	q := `
+	UPDATE token
+	SET token_hash = v.token_hash, masked_token = v.masked_token, hashed = true
+	FROM unnest($1::text[], $2::text[], $3::text[]) AS v(token, token_hash, masked_token)
+	WHERE token.token_hash = v.token AND NOT token.hashed
+	`
+	_, err := r.db.Exec(ctx, q, tokens, tokenHashes, maskedTokens)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+// DeleteExpiredTokens deletes tokens expired longer than gracePeriod ago by the database clock
+func (r Token) DeleteExpiredTokens(ctx context.Context, gracePeriod time.Duration) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.DeleteExpiredTokens")
+
+	q := `
+	DELETE FROM token
+	WHERE expires_at < (now() AT TIME ZONE 'utc') - $1 * INTERVAL '1 second'
+	`
+	result, err := r.db.Exec(ctx, q, int64(gracePeriod.Seconds()))
+	if err != nil {
+		return 0, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.WithMessage(err, "get rows affected")
+	}
+
+	return int(rowsAffected), nil
+}
+
+// DeleteExpiredRevokedTokens deletes entries of the deny list which are rejected by expiration anyway
+func (r Token) DeleteExpiredRevokedTokens(ctx context.Context, gracePeriod time.Duration) (int, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.DeleteExpiredRevokedTokens")
+
+	q := `
+	DELETE FROM revoked_token
+	WHERE expires_at < (now() AT TIME ZONE 'utc') - $1 * INTERVAL '1 second'
+	`
+	result, err := r.db.Exec(ctx, q, int64(gracePeriod.Seconds()))
+	if err != nil {
+		return 0, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.WithMessage(err, "get rows affected")
+	}
+
+	return int(rowsAffected), nil
+}
+
+func (r Token) SaveTokenSweepStatus(ctx context.Context, status entity.TokenSweepStatus) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.SaveTokenSweepStatus")
+
+	q := `
+	INSERT INTO token_sweep_status
+	(started_at, finished_at, deleted_tokens, deleted_revoked_tokens, error)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (id) DO UPDATE SET
+		started_at = excluded.started_at,
+		finished_at = excluded.finished_at,
+		deleted_tokens = excluded.deleted_tokens,
+		deleted_revoked_tokens = excluded.deleted_revoked_tokens,
+		error = excluded.error
+	`
+	_, err := r.db.Exec(ctx, q, status.StartedAt, status.FinishedAt, status.DeletedTokens, status.DeletedRevokedTokens, status.Error)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+func (r Token) GetTokenSweepStatus(ctx context.Context) (*entity.TokenSweepStatus, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.GetTokenSweepStatus")
+
+	q := `
+	SELECT started_at, finished_at, deleted_tokens, deleted_revoked_tokens, error
+	FROM token_sweep_status
+	`
+	result := entity.TokenSweepStatus{}
+	err := r.db.SelectRow(ctx, &result, q)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil //nolint:nilnil
+	case err != nil:
+		return nil, errors.WithMessagef(err, "exec query %s", q)
 	default:
 		return &result, nil
 	}
 }
+
+// UpdateTokenUsage writes coalesced usages by a single query, older usages don't overwrite newer ones
+func (r Token) UpdateTokenUsage(ctx context.Context, usages []entity.TokenUsage) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.UpdateTokenUsage")
+
+	idList := make([]string, len(usages))
+	usedAtList := make([]time.Time, len(usages))
+	addressList := make([]string, len(usages))
+	for i, usage := range usages {
+		idList[i] = usage.TokenId
+		usedAtList[i] = usage.UsedAt
+		addressList[i] = usage.Address
+	}
+
+	q := `
+	UPDATE token
+	SET last_used_at = usage.used_at, last_used_address = NULLIF(usage.address, '')
+	FROM unnest($1::text[], $2::timestamp[], $3::text[]) AS usage(id, used_at, address)
+	WHERE token.id = usage.id AND (token.last_used_at IS NULL OR token.last_used_at < usage.used_at)
+	`
+	_, err := r.db.Exec(ctx, q, idList, usedAtList, addressList)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return nil
+}
+
+// GetUnusedTokens returns not expired tokens which have not been used since unusedSince ago by the database clock,
+// never used tokens are compared by creation time
+func (r Token) GetUnusedTokens(ctx context.Context, appId int, unusedSince time.Duration) ([]entity.Token, error) {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.GetUnusedTokens")
+
+	where := squirrel.And{
+		squirrel.Expr("COALESCE(last_used_at, created_at) < (now() AT TIME ZONE 'utc') - ? * INTERVAL '1 second'", int64(unusedSince.Seconds())),
+		squirrel.Expr("NOT " + tokenExpiredExpr),
+	}
+	if appId != 0 {
+		where = append(where, squirrel.Eq{"app_id": appId})
+	}
+	q, args, err := query.New().
+		Select(tokenColumns...).
+		From("token").
+		Where(where).
+		OrderBy("COALESCE(last_used_at, created_at)").
+		ToSql()
+	if err != nil {
+		return nil, errors.WithMessage(err, "build query")
+	}
+
+	result := make([]entity.Token, 0)
+	err = r.db.Select(ctx, &result, q, args...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "exec query %s", q)
+	}
+
+	return result, nil
+}
+
+// MarkManagedTokens marks tokens of the list as managed by config and unmarks the rest
+func (r Token) MarkManagedTokens(ctx context.Context, idList []string) error {
+	ctx = sql_metrics.OperationLabelToContext(ctx, "Token.MarkManagedTokens")
+
+	q := `
+	UPDATE token
+	SET managed_by_config = COALESCE(id = ANY($1), false)
+	WHERE managed_by_config <> COALESCE(id = ANY($1), false)
+	`
+	_, err := r.db.Exec(ctx, q, idList)
+	if err != nil {
+		return errors.WithMessagef(err, "exec query %s", q)
+	}
+	return nil
+}
+
+func tokenListWhere(appId int, filter entity.ListFilter) squirrel.And {
+	return append(listWhere(filter, "id"), squirrel.Eq{"app_id": appId})
+}
@@ -11,13 +11,20 @@ import (
 )
 
 type Controllers struct {
-	AccessList  controller.AccessList
-	Domain      controller.Domain
-	Service     controller.Service
-	Application controller.Application
-	AppGroup    controller.AppGroup
-	Token       controller.Token
-	Secure      controller.Secure
+	AccessList   controller.AccessList
+	Domain       controller.Domain
+	Service      controller.Service
+	Application  controller.Application
+	AppGroup     controller.AppGroup
+	Token        controller.Token
+	Secure       controller.Secure
+	Role         controller.Role
+	SigningKey   controller.SigningKey
+	TokenSweeper controller.TokenSweeper
+	RateLimit    controller.RateLimit
+	Audit        controller.Audit
+	ChangeEvent  controller.ChangeEvent
+	Registry     controller.Registry
 }
 
 func EndpointDescriptors() []cluster.EndpointDescriptor {
@@ -41,6 +48,12 @@ func endpointDescriptors(c Controllers) []cluster.EndpointDescriptor {
 		applicationCluster(c),
 		tokenCluster(c),
 		applicationGroupCluster(c),
+		roleCluster(c),
+		signingKeyCluster(c),
+		rateLimitCluster(c),
+		auditCluster(c),
+		changeEventCluster(c),
+		registryCluster(c),
 		commonEndpoints(),
 	)
 }
@@ -57,6 +70,21 @@ func secureCluster(c Controllers) []cluster.EndpointDescriptor {
 			Inner:   true,
 			Handler: c.Secure.Authorize,
 		},
+		{
+			Path:    "system/secure/authorize_batch",
+			Inner:   true,
+			Handler: c.Secure.AuthorizeBatch,
+		},
+		{
+			Path:    "system/secure/check",
+			Inner:   true,
+			Handler: c.Secure.Check,
+		},
+		{
+			Path:    "system/secure/jwks",
+			Inner:   true,
+			Handler: c.Secure.Jwks,
+		},
 	}
 }
 
@@ -87,6 +115,26 @@ func accessListCluster(c Controllers) []cluster.EndpointDescriptor {
 			Inner:   true,
 			Handler: c.AccessList.DeleteListWithMethods,
 		},
+		{
+			Path:    "system/access_list/get_by_application_group_id",
+			Inner:   true,
+			Handler: c.AccessList.GetByApplicationGroupId,
+		},
+		{
+			Path:    "system/access_list/set_list_for_application_group",
+			Inner:   true,
+			Handler: c.AccessList.SetListForApplicationGroup,
+		},
+		{
+			Path:    "system/access_list/get_by_domain_id",
+			Inner:   true,
+			Handler: c.AccessList.GetByDomainId,
+		},
+		{
+			Path:    "system/access_list/set_list_for_domain",
+			Inner:   true,
+			Handler: c.AccessList.SetListForDomain,
+		},
 	}
 }
 
@@ -202,6 +250,26 @@ func applicationCluster(c Controllers) []cluster.EndpointDescriptor {
 			Inner:   true,
 			Handler: c.Application.Update,
 		},
+		{
+			Path:    "system/application/set_allowed_cidrs",
+			Inner:   true,
+			Handler: c.Application.SetAllowedCidrs,
+		},
+		{
+			Path:    "system/application/suspend",
+			Inner:   true,
+			Handler: c.Application.Suspend,
+		},
+		{
+			Path:    "system/application/disable",
+			Inner:   true,
+			Handler: c.Application.Disable,
+		},
+		{
+			Path:    "system/application/resume",
+			Inner:   true,
+			Handler: c.Application.Resume,
+		},
 	}
 }
 
@@ -227,6 +295,21 @@ func tokenCluster(c Controllers) []cluster.EndpointDescriptor {
 			Inner:   true,
 			Handler: c.Token.GetByAppId,
 		},
+		{
+			Path:    "system/token/rotate",
+			Inner:   true,
+			Handler: c.Token.Rotate,
+		},
+		{
+			Path:    "system/token/get_unused",
+			Inner:   true,
+			Handler: c.Token.GetUnused,
+		},
+		{
+			Path:    "system/token/get_sweep_status",
+			Inner:   true,
+			Handler: c.TokenSweeper.Status,
+		},
 	}
 }
 
@@ -252,6 +335,139 @@ func applicationGroupCluster(c Controllers) []cluster.EndpointDescriptor {
 			Path:    "system/application_group/get_all",
 			Inner:   true,
 			Handler: c.AppGroup.GetAll,
+		}, {
+			Path:    "system/application_group/suspend",
+			Inner:   true,
+			Handler: c.AppGroup.Suspend,
+		}, {
+			Path:    "system/application_group/disable",
+			Inner:   true,
+			Handler: c.AppGroup.Disable,
+		}, {
+			Path:    "system/application_group/resume",
+			Inner:   true,
+			Handler: c.AppGroup.Resume,
+		},
+	}
+}
+
+func roleCluster(c Controllers) []cluster.EndpointDescriptor {
+	return []cluster.EndpointDescriptor{
+		{
+			Path:    "system/role/create",
+			Inner:   true,
+			Handler: c.Role.Create,
+		}, {
+			Path:    "system/role/update",
+			Inner:   true,
+			Handler: c.Role.Update,
+		}, {
+			Path:    "system/role/delete_list",
+			Inner:   true,
+			Handler: c.Role.DeleteList,
+		}, {
+			Path:    "system/role/get_by_id_list",
+			Inner:   true,
+			Handler: c.Role.GetByIdList,
+		}, {
+			Path:    "system/role/get_all",
+			Inner:   true,
+			Handler: c.Role.GetAll,
+		}, {
+			Path:    "system/role/get_by_application_id",
+			Inner:   true,
+			Handler: c.Role.GetByApplicationId,
+		}, {
+			Path:    "system/role/get_by_application_group_id",
+			Inner:   true,
+			Handler: c.Role.GetByApplicationGroupId,
+		}, {
+			Path:    "system/role/set_for_application",
+			Inner:   true,
+			Handler: c.Role.SetForApplication,
+		}, {
+			Path:    "system/role/set_for_application_group",
+			Inner:   true,
+			Handler: c.Role.SetForApplicationGroup,
+		},
+	}
+}
+
+func signingKeyCluster(c Controllers) []cluster.EndpointDescriptor {
+	return []cluster.EndpointDescriptor{
+		{
+			Path:    "system/keys/list",
+			Inner:   true,
+			Handler: c.SigningKey.List,
+		}, {
+			Path:    "system/keys/rotate",
+			Inner:   true,
+			Handler: c.SigningKey.Rotate,
+		}, {
+			Path:    "system/keys/retire",
+			Inner:   true,
+			Handler: c.SigningKey.Retire,
+		},
+	}
+}
+
+func rateLimitCluster(c Controllers) []cluster.EndpointDescriptor {
+	return []cluster.EndpointDescriptor{
+		{
+			Path:    "system/rate_limit/get_by_application_id",
+			Inner:   true,
+			Handler: c.RateLimit.GetByApplicationId,
+		}, {
+			Path:    "system/rate_limit/get_by_application_group_id",
+			Inner:   true,
+			Handler: c.RateLimit.GetByApplicationGroupId,
+		}, {
+			Path:    "system/rate_limit/get_effective",
+			Inner:   true,
+			Handler: c.RateLimit.GetEffective,
+		}, {
+			Path:    "system/rate_limit/set",
+			Inner:   true,
+			Handler: c.RateLimit.Set,
+		}, {
+			Path:    "system/rate_limit/delete_list",
+			Inner:   true,
+			Handler: c.RateLimit.DeleteList,
+		},
+	}
+}
+
+func auditCluster(c Controllers) []cluster.EndpointDescriptor {
+	return []cluster.EndpointDescriptor{
+		{
+			Path:    "system/audit/search",
+			Inner:   true,
+			Handler: c.Audit.Search,
+		},
+	}
+}
+
+func changeEventCluster(c Controllers) []cluster.EndpointDescriptor {
+	return []cluster.EndpointDescriptor{
+		{
+			Path:    "system/events/subscribe",
+			Inner:   true,
+			Handler: c.ChangeEvent.Subscribe,
+		},
+	}
+}
+
+func registryCluster(c Controllers) []cluster.EndpointDescriptor {
+	return []cluster.EndpointDescriptor{
+		{
+			Path:    "system/registry/export",
+			Inner:   true,
+			Handler: c.Registry.Export,
+		},
+		{
+			Path:    "system/registry/import",
+			Inner:   true,
+			Handler: c.Registry.Import,
 		},
 	}
 }
@@ -2,83 +2,139 @@ package service
 
 import (
 	"context"
+	"fmt"
 
 	"isp-system-service/domain"
 	"isp-system-service/entity"
+	"isp-system-service/service/acl"
 
 	"github.com/pkg/errors"
 )
 
 type AccessListRepo interface {
 	GetAccessListByAppId(ctx context.Context, appId int) ([]entity.AccessList, error)
-	DeleteAccessList(ctx context.Context, appId int, methods []entity.Method) error
+	GetEffectiveAccessRules(ctx context.Context, appId int) ([]entity.AccessRule, error)
+	GetAppGroupAccessList(ctx context.Context, appGroupId int) ([]entity.AccessRule, error)
+	GetDomainAccessList(ctx context.Context, domainId int) ([]entity.AccessRule, error)
 }
 
 type AccessListSetOneTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetAccessListByAppId(ctx context.Context, appId int) ([]entity.AccessList, error)
 	UpsertAccessList(ctx context.Context, e entity.AccessList) (int, error)
 }
 
 type AccessListSetListTx interface {
+	AuditWriter
+	ChangeEventWriter
 	GetAccessListByAppId(ctx context.Context, appId int) ([]entity.AccessList, error)
 	InsertArrayAccessList(ctx context.Context, entity []entity.AccessList) error
 	DeleteAccessList(ctx context.Context, appId int, methods []entity.Method) error
 	DeleteAccessListByAppId(ctx context.Context, appId int) ([]entity.AccessList, error)
 }
 
+type AccessListSetForAppGroupTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetAppGroupAccessList(ctx context.Context, appGroupId int) ([]entity.AccessRule, error)
+	UpsertAppGroupAccessList(ctx context.Context, appGroupId int, methods []entity.AccessRule) error
+	DeleteAppGroupAccessList(ctx context.Context, appGroupId int) error
+}
+
+type AccessListSetForDomainTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetDomainAccessList(ctx context.Context, domainId int) ([]entity.AccessRule, error)
+	UpsertDomainAccessList(ctx context.Context, domainId int, methods []entity.AccessRule) error
+	DeleteDomainAccessList(ctx context.Context, domainId int) error
+}
+
 type AccessListTxRunner interface {
 	AccessListSetOneTx(ctx context.Context, tx func(ctx context.Context, tx AccessListSetOneTx) error) error
 	AccessListSetListTx(ctx context.Context, tx func(ctx context.Context, tx AccessListSetListTx) error) error
+	AccessListSetForAppGroupTx(ctx context.Context, tx func(ctx context.Context, tx AccessListSetForAppGroupTx) error) error
+	AccessListSetForDomainTx(ctx context.Context, tx func(ctx context.Context, tx AccessListSetForDomainTx) error) error
 }
 
 type AccessList struct {
 	tx             AccessListTxRunner
 	accessListRepo AccessListRepo
 	appRepo        ApplicationRepo
+	appGroupRepo   AppGroupRepo
+	domainRepo     DomainRepo
+	invalidator    CacheInvalidator
 }
 
 func NewAccessList(
 	tx AccessListTxRunner,
 	accessListRepo AccessListRepo,
 	appRepo ApplicationRepo,
+	appGroupRepo AppGroupRepo,
+	domainRepo DomainRepo,
+	invalidator CacheInvalidator,
 ) AccessList {
 	return AccessList{
 		tx:             tx,
 		accessListRepo: accessListRepo,
 		appRepo:        appRepo,
+		appGroupRepo:   appGroupRepo,
+		domainRepo:     domainRepo,
+		invalidator:    invalidator,
 	}
 }
 
-func (s AccessList) GetById(ctx context.Context, appId int) ([]domain.MethodInfo, error) {
-	_, err := s.appRepo.GetApplicationById(ctx, appId)
+func (s AccessList) GetById(ctx context.Context, req domain.AccessListGetByIdRequest) ([]domain.AccessListItem, error) {
+	_, err := s.appRepo.GetApplicationById(ctx, req.Id)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get application by id")
 	}
 
-	accessList, err := s.accessListRepo.GetAccessListByAppId(ctx, appId)
+	rules, err := s.accessListRepo.GetEffectiveAccessRules(ctx, req.Id)
 	if err != nil {
-		return nil, errors.WithMessage(err, "get access list by app_id")
+		return nil, errors.WithMessage(err, "get effective access rules")
 	}
 
-	methodInfos := make([]domain.MethodInfo, len(accessList))
-	for i, access := range accessList {
-		methodInfos[i] = domain.MethodInfo{
-			HttpMethod: access.HttpMethod,
-			Method:     access.Method,
-			Value:      access.Value,
+	var matched *entity.AccessRule
+	if req.Endpoint != "" {
+		matched = acl.ResolveInherited(rules, req.HttpMethod, req.Endpoint)
+	}
+
+	items := make([]domain.AccessListItem, len(rules))
+	for i, rule := range rules {
+		items[i] = domain.AccessListItem{
+			HttpMethod: rule.HttpMethod,
+			Method:     rule.Method,
+			Value:      rule.Value,
+			Matched:    matched == &rules[i],
+			Source:     rule.Source,
+			SourceId:   rule.SourceId,
 		}
 	}
 
-	return methodInfos, nil
+	return items, nil
 }
 
 func (s AccessList) SetOne(ctx context.Context, request domain.AccessListSetOneRequest) (*domain.AccessListSetOneResponse, error) {
-	_, err := s.appRepo.GetApplicationById(ctx, request.AppId)
+	if !acl.IsValidPattern(request.Method) {
+		return nil, domain.ErrInvalidMethodPattern
+	}
+
+	app, err := s.appRepo.GetApplicationById(ctx, request.AppId)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get application by id")
 	}
+	if app.ManagedByConfig {
+		return nil, errors.WithMessagef(domain.ErrManagedByConfig, "application %d", request.AppId)
+	}
 
 	var resp int
 	err = s.tx.AccessListSetOneTx(ctx, func(ctx context.Context, tx AccessListSetOneTx) error {
+		before, err := tx.GetAccessListByAppId(ctx, request.AppId)
+		if err != nil {
+			return errors.WithMessage(err, "get access list by app_id")
+		}
+
 		resp, err = tx.UpsertAccessList(ctx, entity.AccessList{
 			AppId:      request.AppId,
 			HttpMethod: request.HttpMethod,
@@ -89,11 +145,12 @@ func (s AccessList) SetOne(ctx context.Context, request domain.AccessListSetOneR
 			return errors.WithMessage(err, "upsert access list")
 		}
 
-		return nil
+		return writeAppAccessListChange(ctx, tx, "set_one", request.AppId, before)
 	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "transaction access list set one")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{AppIds: []int{request.AppId}})
 
 	return &domain.AccessListSetOneResponse{
 		Count: resp,
@@ -101,12 +158,25 @@ func (s AccessList) SetOne(ctx context.Context, request domain.AccessListSetOneR
 }
 
 func (s AccessList) SetList(ctx context.Context, req domain.AccessListSetListRequest) ([]domain.MethodInfo, error) {
-	_, err := s.appRepo.GetApplicationById(ctx, req.AppId)
+	err := validateMethods(req.Methods)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := s.appRepo.GetApplicationById(ctx, req.AppId)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get application by id")
 	}
+	if app.ManagedByConfig {
+		return nil, errors.WithMessagef(domain.ErrManagedByConfig, "application %d", req.AppId)
+	}
 
 	err = s.tx.AccessListSetListTx(ctx, func(ctx context.Context, tx AccessListSetListTx) error {
+		before, err := tx.GetAccessListByAppId(ctx, req.AppId)
+		if err != nil {
+			return errors.WithMessage(err, "get access list by app_id")
+		}
+
 		if req.RemoveOld {
 			_, err = tx.DeleteAccessListByAppId(ctx, req.AppId)
 			if err != nil {
@@ -141,27 +211,152 @@ func (s AccessList) SetList(ctx context.Context, req domain.AccessListSetListReq
 			}
 		}
 
-		return nil
+		return writeAppAccessListChange(ctx, tx, "set_list", req.AppId, before)
 	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "transaction access list set list")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{AppIds: []int{req.AppId}})
 
 	accessList, err := s.accessListRepo.GetAccessListByAppId(ctx, req.AppId)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get access list by app_id")
 	}
 
-	methodInfos := make([]domain.MethodInfo, len(accessList))
-	for i, access := range accessList {
-		methodInfos[i] = domain.MethodInfo{
-			HttpMethod: access.HttpMethod,
-			Method:     access.Method,
-			Value:      access.Value,
+	return appMethodInfos(accessList), nil
+}
+
+func (s AccessList) GetByAppGroupId(ctx context.Context, appGroupId int) ([]domain.MethodInfo, error) {
+	_, err := s.appGroupRepo.GetAppGroupById(ctx, appGroupId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get application group by id")
+	}
+
+	rules, err := s.accessListRepo.GetAppGroupAccessList(ctx, appGroupId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get access list by app_group_id")
+	}
+
+	return methodInfos(rules), nil
+}
+
+func (s AccessList) SetListForAppGroup(ctx context.Context, req domain.AccessListSetListForAppGroupRequest) ([]domain.MethodInfo, error) {
+	err := validateMethods(req.Methods)
+	if err != nil {
+		return nil, err
+	}
+
+	appGroup, err := s.appGroupRepo.GetAppGroupById(ctx, req.AppGroupId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get application group by id")
+	}
+	if appGroup.ManagedByConfig {
+		return nil, errors.WithMessagef(domain.ErrManagedByConfig, "application group %d", req.AppGroupId)
+	}
+
+	err = s.tx.AccessListSetForAppGroupTx(ctx, func(ctx context.Context, tx AccessListSetForAppGroupTx) error {
+		before, err := tx.GetAppGroupAccessList(ctx, req.AppGroupId)
+		if err != nil {
+			return errors.WithMessage(err, "get access list by app_group_id")
+		}
+
+		if req.RemoveOld {
+			err = tx.DeleteAppGroupAccessList(ctx, req.AppGroupId)
+			if err != nil {
+				return errors.WithMessage(err, "delete access list by app_group_id")
+			}
+		}
+		if len(req.Methods) > 0 {
+			err = tx.UpsertAppGroupAccessList(ctx, req.AppGroupId, accessRules(req.Methods))
+			if err != nil {
+				return errors.WithMessage(err, "upsert app group access list")
+			}
+		}
+
+		after, err := tx.GetAppGroupAccessList(ctx, req.AppGroupId)
+		if err != nil {
+			return errors.WithMessage(err, "get access list by app_group_id")
+		}
+		err = WriteAuditEvent(
+			ctx, tx, entity.AuditEntityAccessList, "set_for_app_group",
+			accessListOwner(entity.AuditEntityAppGroup, req.AppGroupId), methodInfos(before), methodInfos(after),
+		)
+		if err != nil {
+			return err
+		}
+		return WriteChangeEvent(ctx, tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{AppGroupId: req.AppGroupId})
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction access list set list for app group")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
+
+	return s.GetByAppGroupId(ctx, req.AppGroupId)
+}
+
+func (s AccessList) GetByDomainId(ctx context.Context, domainId int) ([]domain.MethodInfo, error) {
+	_, err := s.domainRepo.GetDomainById(ctx, domainId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get domain by id")
+	}
+
+	rules, err := s.accessListRepo.GetDomainAccessList(ctx, domainId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get access list by domain_id")
+	}
+
+	return methodInfos(rules), nil
+}
+
+func (s AccessList) SetListForDomain(ctx context.Context, req domain.AccessListSetListForDomainRequest) ([]domain.MethodInfo, error) {
+	err := validateMethods(req.Methods)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.domainRepo.GetDomainById(ctx, req.DomainId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get domain by id")
+	}
+
+	err = s.tx.AccessListSetForDomainTx(ctx, func(ctx context.Context, tx AccessListSetForDomainTx) error {
+		before, err := tx.GetDomainAccessList(ctx, req.DomainId)
+		if err != nil {
+			return errors.WithMessage(err, "get access list by domain_id")
+		}
+
+		if req.RemoveOld {
+			err = tx.DeleteDomainAccessList(ctx, req.DomainId)
+			if err != nil {
+				return errors.WithMessage(err, "delete access list by domain_id")
+			}
 		}
+		if len(req.Methods) > 0 {
+			err = tx.UpsertDomainAccessList(ctx, req.DomainId, accessRules(req.Methods))
+			if err != nil {
+				return errors.WithMessage(err, "upsert domain access list")
+			}
+		}
+
+		after, err := tx.GetDomainAccessList(ctx, req.DomainId)
+		if err != nil {
+			return errors.WithMessage(err, "get access list by domain_id")
+		}
+		err = WriteAuditEvent(
+			ctx, tx, entity.AuditEntityAccessList, "set_for_domain",
+			accessListOwner(entity.AuditEntityDomain, req.DomainId), methodInfos(before), methodInfos(after),
+		)
+		if err != nil {
+			return err
+		}
+		return WriteChangeEvent(ctx, tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{DomainId: req.DomainId})
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction access list set list for domain")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
 
-	return methodInfos, nil
+	return s.GetByDomainId(ctx, req.DomainId)
 }
 
 func (s AccessList) DeleteList(ctx context.Context, req domain.AccessListDeleteListRequest) error {
@@ -176,18 +371,104 @@ func (s AccessList) DeleteList(ctx context.Context, req domain.AccessListDeleteL
 }
 
 func (s AccessList) DeleteListWithMethods(ctx context.Context, req domain.AccessListDeleteV2ListRequest) error {
-	_, err := s.appRepo.GetApplicationById(ctx, req.AppId)
+	app, err := s.appRepo.GetApplicationById(ctx, req.AppId)
 	if err != nil {
 		return errors.WithMessage(err, "get application by id")
 	}
+	if app.ManagedByConfig {
+		return errors.WithMessagef(domain.ErrManagedByConfig, "application %d", req.AppId)
+	}
 
 	methods := make([]entity.Method, 0, len(req.Methods))
 	for _, method := range req.Methods {
 		methods = append(methods, entity.Method{Method: method.Method, HttpMethod: method.HttpMethod})
 	}
-	err = s.accessListRepo.DeleteAccessList(ctx, req.AppId, methods)
+	err = s.tx.AccessListSetListTx(ctx, func(ctx context.Context, tx AccessListSetListTx) error {
+		before, err := tx.GetAccessListByAppId(ctx, req.AppId)
+		if err != nil {
+			return errors.WithMessage(err, "get access list by app_id")
+		}
+
+		err = tx.DeleteAccessList(ctx, req.AppId, methods)
+		if err != nil {
+			return errors.WithMessage(err, "delete access_list")
+		}
+
+		return writeAppAccessListChange(ctx, tx, "delete", req.AppId, before)
+	})
 	if err != nil {
-		return errors.WithMessage(err, "delete access_list")
+		return errors.WithMessage(err, "transaction access list delete")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{AppIds: []int{req.AppId}})
 	return nil
 }
+
+type appAccessListChangeTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetAccessListByAppId(ctx context.Context, appId int) ([]entity.AccessList, error)
+}
+
+// writeAppAccessListChange records the access list of the application before and after the change
+// and publishes the change to subscribers
+func writeAppAccessListChange(
+	ctx context.Context,
+	tx appAccessListChangeTx,
+	action string,
+	appId int,
+	before []entity.AccessList,
+) error {
+	after, err := tx.GetAccessListByAppId(ctx, appId)
+	if err != nil {
+		return errors.WithMessage(err, "get access list by app_id")
+	}
+	err = WriteAuditEvent(
+		ctx, tx, entity.AuditEntityAccessList, action,
+		accessListOwner(entity.AuditEntityApplication, appId), appMethodInfos(before), appMethodInfos(after),
+	)
+	if err != nil {
+		return err
+	}
+	return WriteChangeEvent(ctx, tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{AppId: appId})
+}
+
+// accessListOwner identifies the access list in the audit log by the type and the id of its owner
+func accessListOwner(entityType string, id int) string {
+	return fmt.Sprintf("%s:%d", entityType, id)
+}
+
+func appMethodInfos(accessList []entity.AccessList) []domain.MethodInfo {
+	result := make([]domain.MethodInfo, len(accessList))
+	for i, access := range accessList {
+		result[i] = domain.MethodInfo{
+			HttpMethod: access.HttpMethod,
+			Method:     access.Method,
+			Value:      access.Value,
+		}
+	}
+	return result
+}
+
+func accessRules(methods []domain.MethodInfo) []entity.AccessRule {
+	result := make([]entity.AccessRule, len(methods))
+	for i, m := range methods {
+		result[i] = entity.AccessRule{
+			HttpMethod: m.HttpMethod,
+			Method:     m.Method,
+			Value:      m.Value,
+		}
+	}
+	return result
+}
+
+func methodInfos(rules []entity.AccessRule) []domain.MethodInfo {
+	result := make([]domain.MethodInfo, len(rules))
+	for i, rule := range rules {
+		result[i] = domain.MethodInfo{
+			HttpMethod: rule.HttpMethod,
+			Method:     rule.Method,
+			Value:      rule.Value,
+		}
+	}
+	return result
+}
@@ -0,0 +1,233 @@
+package acl
+
+import (
+	"strings"
+
+	"isp-system-service/entity"
+)
+
+const (
+	wildcard      = "*"
+	deepWildcard  = "**"
+	pathSeparator = "/"
+)
+
+const (
+	levelApplication = iota
+	levelAppGroup
+	levelDomain
+	levelCount
+)
+
+const (
+	kindExact = iota
+	kindPrefix
+	kindWildcard
+)
+
+// IsPattern reports whether method is a glob/prefix pattern rather than an exact method
+func IsPattern(method string) bool {
+	return strings.Contains(method, wildcard)
+}
+
+// IsValidPattern reports whether "**" is used only as a whole path segment
+func IsValidPattern(method string) bool {
+	for _, segment := range strings.Split(method, pathSeparator) {
+		if strings.Contains(segment, deepWildcard) && segment != deepWildcard {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve returns the rule which decides access to the endpoint or nil if there is no applicable rule.
+// Precedence: exact method > longest prefix pattern (admin/*) > wildcard pattern (admin/**/get_*),
+// a rule with the same http method wins over a rule for any http method within the same rank.
+// A deny rule overrides every allow rule covering all endpoints the deny rule matches, whatever their ranks are,
+// so a more specific allow rule still makes an exception from a broader deny rule
+func Resolve(rules []entity.AccessRule, httpMethod string, endpoint string) *entity.AccessRule {
+	return resolve(rules, httpMethod, endpoint, func(*entity.AccessRule) bool {
+		return true
+	})
+}
+
+// ResolveInherited resolves rules level by level: application (own rules and roles),
+// then application group (group rules and roles), then domain.
+// The first level having an applicable rule decides, rules of the lower levels are not taken into account
+func ResolveInherited(rules []entity.AccessRule, httpMethod string, endpoint string) *entity.AccessRule {
+	for level := range levelCount {
+		rule := resolve(rules, httpMethod, endpoint, func(rule *entity.AccessRule) bool {
+			return sourceLevel(rule.Source) == level
+		})
+		if rule != nil {
+			return rule
+		}
+	}
+	return nil
+}
+
+// InScope reports whether the endpoint matches any method or method pattern of the token scope,
+// empty scope doesn't restrict the token
+func InScope(scope []string, endpoint string) bool {
+	if len(scope) == 0 {
+		return true
+	}
+	for _, pattern := range scope {
+		_, ok := match(pattern, endpoint)
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func resolve(
+	rules []entity.AccessRule,
+	httpMethod string,
+	endpoint string,
+	include func(rule *entity.AccessRule) bool,
+) *entity.AccessRule {
+	matched := make([]*entity.AccessRule, 0)
+	ranks := make([]rank, 0)
+	for i := range rules {
+		rule := &rules[i]
+		if !include(rule) {
+			continue
+		}
+		if rule.HttpMethod != "" && rule.HttpMethod != httpMethod {
+			continue
+		}
+		r, ok := match(rule.Method, endpoint)
+		if !ok {
+			continue
+		}
+		r.exactHttpMethod = rule.HttpMethod != ""
+		matched = append(matched, rule)
+		ranks = append(ranks, r)
+	}
+
+	var (
+		best     *entity.AccessRule
+		bestRank rank
+	)
+	for i, rule := range matched {
+		if rule.Value && overridden(rule, matched) {
+			continue
+		}
+		r := ranks[i]
+		switch {
+		case best == nil || r.moreSpecificThan(bestRank):
+			best, bestRank = rule, r
+		case !rule.Value && best.Value && !bestRank.moreSpecificThan(r):
+			best = rule
+		}
+	}
+	return best
+}
+
+// overridden reports whether any of the matched deny rules is covered by the allow rule
+func overridden(allow *entity.AccessRule, matched []*entity.AccessRule) bool {
+	for _, rule := range matched {
+		if !rule.Value && covers(allow, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether the allow rule matches every endpoint and http method the deny rule matches.
+// A prefix pattern covers rules starting with its prefix, an exact method or a wildcard pattern covers only itself
+func covers(allow *entity.AccessRule, deny *entity.AccessRule) bool {
+	if allow.HttpMethod != "" && allow.HttpMethod != deny.HttpMethod {
+		return false
+	}
+	if allow.Method == deny.Method {
+		return true
+	}
+	prefix := strings.TrimRight(allow.Method, wildcard)
+	if prefix == allow.Method || IsPattern(prefix) {
+		return false
+	}
+	denyPrefix, _, _ := strings.Cut(deny.Method, wildcard)
+	return strings.HasPrefix(denyPrefix, prefix)
+}
+
+func sourceLevel(source string) int {
+	switch source {
+	case entity.AccessRuleSourceAppGroup, entity.AccessRuleSourceAppGroupRole:
+		return levelAppGroup
+	case entity.AccessRuleSourceDomain:
+		return levelDomain
+	default:
+		return levelApplication
+	}
+}
+
+type rank struct {
+	kind            int
+	weight          int
+	exactHttpMethod bool
+}
+
+func (r rank) moreSpecificThan(other rank) bool {
+	if r.kind != other.kind {
+		return r.kind < other.kind
+	}
+	if r.weight != other.weight {
+		return r.weight > other.weight
+	}
+	return r.exactHttpMethod && !other.exactHttpMethod
+}
+
+func match(pattern string, endpoint string) (rank, bool) {
+	if !IsPattern(pattern) {
+		return rank{kind: kindExact}, pattern == endpoint
+	}
+
+	prefix := strings.TrimRight(pattern, wildcard)
+	if !IsPattern(prefix) {
+		return rank{kind: kindPrefix, weight: len(prefix)}, strings.HasPrefix(endpoint, prefix)
+	}
+
+	literals := len(pattern) - strings.Count(pattern, wildcard)
+	matched := matchSegments(
+		strings.Split(pattern, pathSeparator),
+		strings.Split(endpoint, pathSeparator),
+	)
+	return rank{kind: kindWildcard, weight: literals}, matched
+}
+
+func matchSegments(pattern []string, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == deepWildcard {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 || !matchSegment(pattern[0], parts[0]) {
+		return false
+	}
+	return matchSegments(pattern[1:], parts[1:])
+}
+
+func matchSegment(pattern string, part string) bool {
+	before, after, found := strings.Cut(pattern, wildcard)
+	if !found {
+		return pattern == part
+	}
+	if !strings.HasPrefix(part, before) {
+		return false
+	}
+	part = part[len(before):]
+	for i := 0; i <= len(part); i++ {
+		if matchSegment(after, part[i:]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package acl_test
+
+import (
+	"net/http"
+	"testing"
+
+	"isp-system-service/entity"
+	"isp-system-service/service/acl"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	rules := []entity.AccessRule{
+		{Method: "admin/*", Value: true},
+		{Method: "admin/user/*", Value: false},
+		{Method: "admin/user/get", Value: true},
+		{Method: "admin/**/get_*", Value: true},
+		{Method: "report/*/export", Value: true},
+		{HttpMethod: http.MethodPost, Method: "report/*", Value: false},
+	}
+
+	tests := []struct {
+		httpMethod string
+		endpoint   string
+		expected   string
+	}{
+		{endpoint: "admin/user/get", expected: "admin/user/get"},
+		{endpoint: "admin/user/delete", expected: "admin/user/*"},
+		{endpoint: "admin/role/get_all", expected: "admin/*"},
+		{endpoint: "report/daily/export", expected: "report/*/export"},
+		{httpMethod: http.MethodPost, endpoint: "report/daily/export", expected: "report/*"},
+		{endpoint: "report/daily/import", expected: ""},
+		{endpoint: "other", expected: ""},
+	}
+	for _, test := range tests {
+		rule := acl.Resolve(rules, test.httpMethod, test.endpoint)
+		if test.expected == "" {
+			require.Nil(t, rule, test.endpoint)
+			continue
+		}
+		require.NotNil(t, rule, test.endpoint)
+		require.Equal(t, test.expected, rule.Method, test.endpoint)
+	}
+}
+
+func TestResolve_Wildcard(t *testing.T) {
+	t.Parallel()
+
+	rules := []entity.AccessRule{
+		{Method: "admin/**/get_*", Value: true},
+	}
+
+	require.NotNil(t, acl.Resolve(rules, "", "admin/get_all"))
+	require.NotNil(t, acl.Resolve(rules, "", "admin/user/role/get_by_id"))
+	require.Nil(t, acl.Resolve(rules, "", "admin/user/delete"))
+	require.Nil(t, acl.Resolve(rules, "", "public/user/get_all"))
+}
+
+func TestResolve_HttpMethod(t *testing.T) {
+	t.Parallel()
+
+	rules := []entity.AccessRule{
+		{Method: "admin/user/get", Value: false},
+		{HttpMethod: http.MethodGet, Method: "admin/user/get", Value: true},
+	}
+
+	rule := acl.Resolve(rules, http.MethodGet, "admin/user/get")
+	require.NotNil(t, rule)
+	require.True(t, rule.Value)
+
+	rule = acl.Resolve(rules, http.MethodPost, "admin/user/get")
+	require.NotNil(t, rule)
+	require.False(t, rule.Value)
+}
+
+func TestResolve_DenyOverrides(t *testing.T) {
+	t.Parallel()
+
+	rules := []entity.AccessRule{
+		{Method: "admin/user/delete", Value: true},
+		{Method: "admin/**/delete", Value: false},
+		{Method: "admin/user/*", Value: false},
+		{Method: "admin/*", Value: true},
+		{Source: entity.AccessRuleSourceApplicationRole, Method: "report/*", Value: true},
+		{Method: "report/*", Value: false},
+		{Source: entity.AccessRuleSourceApplicationRole, Method: "report/*", Value: true},
+		{HttpMethod: http.MethodGet, Method: "orders/*", Value: true},
+		{Method: "orders/**/secret", Value: false},
+	}
+
+	rule := acl.Resolve(rules, "", "admin/user/delete")
+	require.NotNil(t, rule)
+	require.True(t, rule.Value)
+	require.Equal(t, "admin/user/delete", rule.Method)
+
+	rule = acl.Resolve(rules, "", "admin/user/get")
+	require.NotNil(t, rule)
+	require.False(t, rule.Value)
+	require.Equal(t, "admin/user/*", rule.Method)
+
+	rule = acl.Resolve(rules, "", "admin/role/delete")
+	require.NotNil(t, rule)
+	require.False(t, rule.Value)
+	require.Equal(t, "admin/**/delete", rule.Method)
+
+	rule = acl.Resolve(rules, "", "admin/role/get")
+	require.NotNil(t, rule)
+	require.True(t, rule.Value)
+	require.Equal(t, "admin/*", rule.Method)
+
+	rule = acl.Resolve(rules, "", "report/daily")
+	require.NotNil(t, rule)
+	require.False(t, rule.Value)
+
+	rule = acl.Resolve(rules, http.MethodGet, "orders/daily/secret")
+	require.NotNil(t, rule)
+	require.True(t, rule.Value)
+
+	rule = acl.Resolve(rules, http.MethodPost, "orders/daily/secret")
+	require.NotNil(t, rule)
+	require.False(t, rule.Value)
+}
+
+func TestIsValidPattern(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, acl.IsValidPattern("admin/user/get"))
+	require.True(t, acl.IsValidPattern("admin/**/get_*"))
+	require.True(t, acl.IsValidPattern("admin/*"))
+	require.False(t, acl.IsValidPattern("admin/**get"))
+	require.False(t, acl.IsValidPattern("admin/***"))
+}
+
+func TestInScope(t *testing.T) {
+	t.Parallel()
+
+	scope := []string{"admin/deploy/*", "report/**/get_*", "health"}
+
+	require.True(t, acl.InScope(nil, "admin/user/delete"))
+	require.True(t, acl.InScope(scope, "admin/deploy/start"))
+	require.True(t, acl.InScope(scope, "report/daily/get_all"))
+	require.True(t, acl.InScope(scope, "health"))
+	require.False(t, acl.InScope(scope, "admin/user/delete"))
+	require.False(t, acl.InScope(scope, "health/check"))
+}
+
+func TestResolveInherited(t *testing.T) {
+	t.Parallel()
+
+	rules := []entity.AccessRule{
+		{Source: entity.AccessRuleSourceDomain, Method: "admin/*", Value: true},
+		{Source: entity.AccessRuleSourceDomain, Method: "report/*", Value: false},
+		{Source: entity.AccessRuleSourceAppGroupRole, Method: "admin/user/*", Value: false},
+		{Source: entity.AccessRuleSourceAppGroup, Method: "report/daily/*", Value: true},
+		{Source: entity.AccessRuleSourceApplicationRole, Method: "admin/user/get", Value: true},
+		{Source: entity.AccessRuleSourceApplication, Method: "report/daily/import", Value: false},
+	}
+
+	tests := []struct {
+		endpoint string
+		source   string
+		value    bool
+	}{
+		{endpoint: "admin/user/get", source: entity.AccessRuleSourceApplicationRole, value: true},
+		{endpoint: "admin/user/delete", source: entity.AccessRuleSourceAppGroupRole, value: false},
+		{endpoint: "admin/role/get", source: entity.AccessRuleSourceDomain, value: true},
+		{endpoint: "report/daily/export", source: entity.AccessRuleSourceAppGroup, value: true},
+		{endpoint: "report/daily/import", source: entity.AccessRuleSourceApplication, value: false},
+		{endpoint: "report/weekly/export", source: entity.AccessRuleSourceDomain, value: false},
+	}
+	for _, test := range tests {
+		rule := acl.ResolveInherited(rules, "", test.endpoint)
+		require.NotNil(t, rule, test.endpoint)
+		require.Equal(t, test.source, rule.Source, test.endpoint)
+		require.Equal(t, test.value, rule.Value, test.endpoint)
+	}
+	require.Nil(t, acl.ResolveInherited(rules, "", "other"))
+}
@@ -8,18 +8,44 @@ import (
 	"github.com/pkg/errors"
 )
 
+type AppGroupSaveTx interface {
+	AuditWriter
+	GetAppGroupById(ctx context.Context, id int) (*entity.AppGroup, error)
+	CreateAppGroup(ctx context.Context, name string, desc string, domainId int) (*entity.AppGroup, error)
+	UpdateAppGroup(ctx context.Context, id int, name string, description string) (*entity.AppGroup, error)
+	SetAppGroupStatus(ctx context.Context, id int, status string, reason string, changedBy string) (*entity.AppGroup, error)
+}
+
+type AppGroupDeleteTx interface {
+	AuditWriter
+	CascadeDeleteTx
+	GetAppGroupByIdList(ctx context.Context, idList []int) ([]entity.AppGroup, error)
+	DeleteAppGroup(ctx context.Context, idList []int) (int, error)
+}
+
+type AppGroupTxRunner interface {
+	AppGroupSaveTx(ctx context.Context, tx func(ctx context.Context, tx AppGroupSaveTx) error) error
+	AppGroupDeleteTx(ctx context.Context, tx func(ctx context.Context, tx AppGroupDeleteTx) error) error
+}
+
 type AppGroup struct {
-	repo AppGroupRepo
+	txRunner    AppGroupTxRunner
+	repo        AppGroupRepo
+	invalidator CacheInvalidator
 }
 
-func NewAppGroup(repo AppGroupRepo) AppGroup {
+func NewAppGroup(txRunner AppGroupTxRunner, repo AppGroupRepo, invalidator CacheInvalidator) AppGroup {
 	return AppGroup{
-		repo: repo,
+		txRunner:    txRunner,
+		repo:        repo,
+		invalidator: invalidator,
 	}
 }
 
 func (s AppGroup) Create(ctx context.Context, req domain.CreateAppGroupRequest) (*domain.AppGroup, error) {
-	appGroup, err := s.repo.CreateAppGroup(ctx, req.Name, req.Description, 1)
+	appGroup, err := s.save(ctx, "create", 0, func(ctx context.Context, tx AppGroupSaveTx) (*entity.AppGroup, error) {
+		return tx.CreateAppGroup(ctx, req.Name, req.Description, 1)
+	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "create appGroup")
 	}
@@ -28,7 +54,9 @@ func (s AppGroup) Create(ctx context.Context, req domain.CreateAppGroupRequest)
 }
 
 func (s AppGroup) Update(ctx context.Context, req domain.UpdateAppGroupRequest) (*domain.AppGroup, error) {
-	appGroup, err := s.repo.UpdateAppGroup(ctx, req.Id, req.Name, req.Description)
+	appGroup, err := s.save(ctx, "update", req.Id, func(ctx context.Context, tx AppGroupSaveTx) (*entity.AppGroup, error) {
+		return tx.UpdateAppGroup(ctx, req.Id, req.Name, req.Description)
+	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "update appGroup")
 	}
@@ -37,10 +65,40 @@ func (s AppGroup) Update(ctx context.Context, req domain.UpdateAppGroupRequest)
 }
 
 func (s AppGroup) DeleteList(ctx context.Context, req domain.IdListRequest) (*domain.DeleteResponse, error) {
-	deleted, err := s.repo.DeleteAppGroup(ctx, req.IdList)
+	deleted := 0
+	err := s.txRunner.AppGroupDeleteTx(ctx, func(ctx context.Context, tx AppGroupDeleteTx) error {
+		appGroups, err := tx.GetAppGroupByIdList(ctx, req.IdList)
+		if err != nil {
+			return errors.WithMessage(err, "get appGroups by id list")
+		}
+		for _, appGroup := range appGroups {
+			if appGroup.ManagedByConfig {
+				return errors.WithMessagef(domain.ErrManagedByConfig, "application group %d", appGroup.Id)
+			}
+		}
+
+		err = writeCascadeDeleteEvents(ctx, tx, req.IdList)
+		if err != nil {
+			return err
+		}
+
+		deleted, err = tx.DeleteAppGroup(ctx, req.IdList)
+		if err != nil {
+			return errors.WithMessage(err, "delete appGroup")
+		}
+
+		for _, appGroup := range appGroups {
+			err = WriteAuditEvent(ctx, tx, entity.AuditEntityAppGroup, "delete", appGroup.Id, s.convertAppGroup(appGroup), nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "delete appGroup")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
 	return &domain.DeleteResponse{
 		Deleted: deleted,
 	}, nil
@@ -58,24 +116,108 @@ func (s AppGroup) GetByIdList(ctx context.Context, idList []int) ([]domain.AppGr
 	return result, nil
 }
 
-func (s AppGroup) GetAll(ctx context.Context) ([]domain.AppGroup, error) {
-	appGroups, err := s.repo.GetAllAppGroups(ctx)
+// GetAll returns the page of application groups matching the request with the total count
+func (s AppGroup) GetAll(ctx context.Context, req domain.ListRequest) (*domain.AppGroupListResponse, error) {
+	filter, err := listFilter[int](req, listSortFields)
 	if err != nil {
-		return nil, errors.WithMessage(err, "get all appGroups")
+		return nil, err
 	}
-	result := make([]domain.AppGroup, 0, len(appGroups))
+
+	appGroups, err := s.repo.ListAppGroups(ctx, filter)
+	if err != nil {
+		return nil, errors.WithMessage(err, "list appGroups")
+	}
+	total, err := s.repo.CountAppGroups(ctx, filter)
+	if err != nil {
+		return nil, errors.WithMessage(err, "count appGroups")
+	}
+
+	result := &domain.AppGroupListResponse{
+		Total: total,
+	}
+	if len(appGroups) > filter.Limit {
+		appGroups = appGroups[:filter.Limit]
+		last := appGroups[len(appGroups)-1]
+		result.NextCursor, err = listNextCursor(req, last.Id, last.Name, last.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.Items = make([]domain.AppGroup, 0, len(appGroups))
 	for _, appGroup := range appGroups {
-		result = append(result, s.convertAppGroup(appGroup))
+		result.Items = append(result.Items, s.convertAppGroup(appGroup))
 	}
 	return result, nil
 }
 
+// SetStatus changes the lifecycle status of the group, it applies to all applications of the group
+func (s AppGroup) SetStatus(
+	ctx context.Context,
+	id int,
+	status string,
+	reason string,
+) (*domain.AppGroup, error) {
+	appGroup, err := s.save(ctx, "set_status", id, func(ctx context.Context, tx AppGroupSaveTx) (*entity.AppGroup, error) {
+		return tx.SetAppGroupStatus(ctx, id, status, reason, callerActor(ctx))
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "set appGroup status")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
+	converted := s.convertAppGroup(*appGroup)
+	return &converted, nil
+}
+
+// save runs the change of the group in a transaction together with its audit event,
+// id is 0 for a new group
+func (s AppGroup) save(
+	ctx context.Context,
+	action string,
+	id int,
+	change func(ctx context.Context, tx AppGroupSaveTx) (*entity.AppGroup, error),
+) (*entity.AppGroup, error) {
+	var appGroup *entity.AppGroup
+	err := s.txRunner.AppGroupSaveTx(ctx, func(ctx context.Context, tx AppGroupSaveTx) error {
+		var before any
+		if id != 0 {
+			existing, err := tx.GetAppGroupById(ctx, id)
+			if err != nil {
+				return errors.WithMessage(err, "get appGroup by id")
+			}
+			if action == "update" && existing.ManagedByConfig {
+				return errors.WithMessagef(domain.ErrManagedByConfig, "application group %d", id)
+			}
+			before = s.convertAppGroup(*existing)
+		}
+
+		var err error
+		appGroup, err = change(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		return WriteAuditEvent(ctx, tx, entity.AuditEntityAppGroup, action, appGroup.Id, before, s.convertAppGroup(*appGroup))
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "transaction appGroup %s", action)
+	}
+	return appGroup, nil
+}
+
 func (s AppGroup) convertAppGroup(appGroup entity.AppGroup) domain.AppGroup {
-	return domain.AppGroup{
-		Id:          appGroup.Id,
-		Name:        appGroup.Name,
-		Description: appGroup.Description.String,
-		CreatedAt:   appGroup.CreatedAt,
-		UpdatedAt:   appGroup.UpdatedAt,
+	result := domain.AppGroup{
+		Id:              appGroup.Id,
+		Name:            appGroup.Name,
+		Description:     appGroup.Description.String,
+		CreatedAt:       appGroup.CreatedAt,
+		UpdatedAt:       appGroup.UpdatedAt,
+		Status:          appGroup.Status,
+		StatusReason:    appGroup.StatusReason.String,
+		StatusChangedBy: appGroup.StatusChangedBy.String,
+		ManagedByConfig: appGroup.ManagedByConfig,
+	}
+	if appGroup.StatusChangedAt.Valid {
+		result.StatusChangedAt = &appGroup.StatusChangedAt.Time
 	}
+	return result
 }
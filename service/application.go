@@ -5,15 +5,32 @@ import (
 
 	"isp-system-service/domain"
 	"isp-system-service/entity"
+	"isp-system-service/service/tokenhash"
 
 	"github.com/pkg/errors"
 )
 
+type ApplicationSaveTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetApplicationById(ctx context.Context, id int) (*entity.Application, error)
+	CreateApplication(ctx context.Context, id int, name string, desc string, appGroupId int, appType string) (*entity.Application, error)
+	UpdateApplication(ctx context.Context, id int, name string, description string) (*entity.Application, error)
+	UpdateApplicationWithNewId(ctx context.Context, oldId int, newId int, name string, description string) (*entity.Application, error)
+	SetApplicationAllowedCidrs(ctx context.Context, id int, allowedCidrs entity.AddressList) (*entity.Application, error)
+	SetApplicationStatus(ctx context.Context, id int, status string, reason string, changedBy string) (*entity.Application, error)
+}
+
 type ApplicationDeleteTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetApplicationByIdList(ctx context.Context, idList []int) ([]entity.Application, error)
+	GetTokenByAppIdList(ctx context.Context, appIdList []int) ([]entity.Token, error)
 	DeleteApplicationByIdList(ctx context.Context, idList []int) (int, error)
 }
 
 type ApplicationTxRunner interface {
+	ApplicationSaveTx(ctx context.Context, tx func(ctx context.Context, tx ApplicationSaveTx) error) error
 	ApplicationDeleteTx(ctx context.Context, tx func(ctx context.Context, tx ApplicationDeleteTx) error) error
 }
 
@@ -23,6 +40,8 @@ type Application struct {
 	domainRepo  DomainRepo
 	serviceRepo AppGroupRepo
 	tokenRepo   TokenRepo
+	invalidator CacheInvalidator
+	hasher      tokenhash.Hasher
 }
 
 func NewApplication(
@@ -31,6 +50,8 @@ func NewApplication(
 	domainRepo DomainRepo,
 	appGroupRepo AppGroupRepo,
 	tokenRepo TokenRepo,
+	invalidator CacheInvalidator,
+	hasher tokenhash.Hasher,
 ) Application {
 	return Application{
 		txRunner:    txRunner,
@@ -38,6 +59,8 @@ func NewApplication(
 		domainRepo:  domainRepo,
 		serviceRepo: appGroupRepo,
 		tokenRepo:   tokenRepo,
+		invalidator: invalidator,
+		hasher:      hasher,
 	}
 }
 
@@ -56,7 +79,7 @@ func (s Application) GetById(ctx context.Context, appId int) (*domain.Applicatio
 }
 
 func (s Application) GetByToken(ctx context.Context, tokenStr string) (*domain.GetApplicationByTokenResponse, error) {
-	token, err := s.tokenRepo.GetTokenById(ctx, tokenStr)
+	token, err := s.tokenRepo.GetTokenById(ctx, s.hasher.Hash(tokenStr))
 	if err != nil {
 		return nil, errors.WithMessage(err, "get token by id")
 	}
@@ -74,13 +97,30 @@ func (s Application) GetByToken(ctx context.Context, tokenStr string) (*domain.G
 	}, nil
 }
 
-func (s Application) GetByIdList(ctx context.Context, idList []int) ([]*domain.ApplicationWithTokens, error) {
-	res, err := s.appRepo.GetApplicationByIdList(ctx, idList)
+// GetByIdList returns the page of applications with tokens from the id list with the total count
+func (s Application) GetByIdList(
+	ctx context.Context,
+	req domain.ApplicationIdListRequest,
+) (*domain.ApplicationWithTokensListResponse, error) {
+	filter, err := listFilter[int](req.Page, listSortFields)
 	if err != nil {
-		return nil, errors.WithMessage(err, "get application by id list")
+		return nil, err
 	}
+	filter.IdList = req.IdList
 
-	return s.EnrichWithTokens(ctx, res)
+	apps, total, nextCursor, err := s.list(ctx, req.Page, filter)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.EnrichWithTokens(ctx, apps)
+	if err != nil {
+		return nil, errors.WithMessage(err, "application enrich with tokens")
+	}
+	return &domain.ApplicationWithTokensListResponse{
+		Items:      items,
+		Total:      total,
+		NextCursor: nextCursor,
+	}, nil
 }
 
 func (s Application) GetByServiceId(ctx context.Context, id int) ([]*domain.ApplicationWithTokens, error) {
@@ -171,7 +211,9 @@ func (s Application) CreateUpdate(ctx context.Context, req domain.ApplicationCre
 			return nil, errors.WithMessage(err, "next app id")
 		}
 
-		app, err := s.appRepo.CreateApplication(ctx, appId, req.Name, req.Description, req.ServiceId, req.Type)
+		app, err := s.save(ctx, "create", 0, func(ctx context.Context, tx ApplicationSaveTx) (*entity.Application, error) {
+			return tx.CreateApplication(ctx, appId, req.Name, req.Description, req.ServiceId, req.Type)
+		})
 		if err != nil {
 			return nil, errors.WithMessage(err, "create application")
 		}
@@ -184,10 +226,13 @@ func (s Application) CreateUpdate(ctx context.Context, req domain.ApplicationCre
 		return result[0], nil
 	}
 
-	app, err := s.appRepo.UpdateApplication(ctx, req.Id, req.Name, req.Description)
+	app, err := s.save(ctx, "update", req.Id, func(ctx context.Context, tx ApplicationSaveTx) (*entity.Application, error) {
+		return tx.UpdateApplication(ctx, req.Id, req.Name, req.Description)
+	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "update application")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{AppIds: []int{req.Id}})
 
 	result, err := s.EnrichWithTokens(ctx, []entity.Application{*app})
 	if err != nil {
@@ -200,17 +245,40 @@ func (s Application) CreateUpdate(ctx context.Context, req domain.ApplicationCre
 func (s Application) Delete(ctx context.Context, idList []int) (int, error) {
 	count := 0
 	err := s.txRunner.ApplicationDeleteTx(ctx, func(ctx context.Context, tx ApplicationDeleteTx) error {
+		apps, err := tx.GetApplicationByIdList(ctx, idList)
+		if err != nil {
+			return errors.WithMessage(err, "get application by id list")
+		}
+		for _, app := range apps {
+			if app.ManagedByConfig {
+				return errors.WithMessagef(domain.ErrManagedByConfig, "application %d", app.Id)
+			}
+		}
+
+		err = writeApplicationDeleteEvents(ctx, tx, apps)
+		if err != nil {
+			return err
+		}
+
 		deletedApp, err := tx.DeleteApplicationByIdList(ctx, idList)
 		if err != nil {
 			return errors.WithMessage(err, "delete application by id list")
 		}
 
+		for _, app := range apps {
+			err = WriteAuditEvent(ctx, tx, entity.AuditEntityApplication, "delete", app.Id, ConvertApplication(app), nil)
+			if err != nil {
+				return err
+			}
+		}
+
 		count = deletedApp
 		return nil
 	})
 	if err != nil {
 		return 0, errors.WithMessage(err, "transaction application delete")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{AppIds: idList})
 
 	return count, nil
 }
@@ -226,7 +294,7 @@ func (s Application) EnrichWithTokens(ctx context.Context, apps []entity.Applica
 	for i, a := range apps {
 		appIdList[i] = a.Id
 		awt := &domain.ApplicationWithTokens{
-			App:    s.convertApplication(a),
+			App:    ConvertApplication(a),
 			Tokens: make([]domain.Token, 0),
 		}
 		resultByAppId[a.Id] = awt
@@ -240,7 +308,7 @@ func (s Application) EnrichWithTokens(ctx context.Context, apps []entity.Applica
 
 	for _, token := range tokens {
 		r := resultByAppId[token.AppId]
-		r.Tokens = append(r.Tokens, domain.Token(token))
+		r.Tokens = append(r.Tokens, convertToken(token))
 	}
 
 	return result, nil
@@ -254,36 +322,117 @@ func (s Application) NextId(ctx context.Context) (int, error) {
 	return nextId, nil
 }
 
-func (s Application) GetAll(ctx context.Context) ([]domain.Application, error) {
-	apps, err := s.appRepo.GetAllApplications(ctx)
+// GetAll returns the page of applications matching the request with the total count
+func (s Application) GetAll(ctx context.Context, req domain.ListRequest) (*domain.ApplicationListResponse, error) {
+	filter, err := listFilter[int](req, listSortFields)
 	if err != nil {
-		return nil, errors.WithMessage(err, "get applications list")
+		return nil, err
 	}
 
-	result := make([]domain.Application, 0, len(apps))
+	apps, total, nextCursor, err := s.list(ctx, req, filter)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.ApplicationListResponse{
+		Items:      make([]domain.Application, 0, len(apps)),
+		Total:      total,
+		NextCursor: nextCursor,
+	}
 	for _, app := range apps {
-		result = append(result, s.convertApplication(app))
+		result.Items = append(result.Items, ConvertApplication(app))
 	}
 	return result, nil
 }
 
+// list returns the page of applications matching the filter, the total count and the cursor of the next page
+func (s Application) list(
+	ctx context.Context,
+	req domain.ListRequest,
+	filter entity.ListFilter,
+) ([]entity.Application, int, string, error) {
+	apps, err := s.appRepo.ListApplications(ctx, filter)
+	if err != nil {
+		return nil, 0, "", errors.WithMessage(err, "list applications")
+	}
+	total, err := s.appRepo.CountApplications(ctx, filter)
+	if err != nil {
+		return nil, 0, "", errors.WithMessage(err, "count applications")
+	}
+
+	nextCursor := ""
+	if len(apps) > filter.Limit {
+		apps = apps[:filter.Limit]
+		last := apps[len(apps)-1]
+		nextCursor, err = listNextCursor(req, last.Id, last.Name, last.CreatedAt)
+		if err != nil {
+			return nil, 0, "", err
+		}
+	}
+	return apps, total, nextCursor, nil
+}
+
 func (s Application) Create(ctx context.Context, req domain.CreateApplicationRequest) (*domain.ApplicationWithTokens, error) {
-	app, err := s.appRepo.CreateApplication(ctx, req.Id, req.Name, req.Description, req.ApplicationGroupId, req.Type)
+	app, err := s.save(ctx, "create", 0, func(ctx context.Context, tx ApplicationSaveTx) (*entity.Application, error) {
+		return tx.CreateApplication(ctx, req.Id, req.Name, req.Description, req.ApplicationGroupId, req.Type)
+	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "create application")
 	}
 
 	return &domain.ApplicationWithTokens{
-		App:    s.convertApplication(*app),
+		App:    ConvertApplication(*app),
 		Tokens: make([]domain.Token, 0),
 	}, nil
 }
 
 func (s Application) Update(ctx context.Context, req domain.UpdateApplicationRequest) (*domain.ApplicationWithTokens, error) {
-	app, err := s.appRepo.UpdateApplicationWithNewId(ctx, req.OldId, req.NewId, req.Name, req.Description)
+	app, err := s.save(ctx, "update", req.OldId, func(ctx context.Context, tx ApplicationSaveTx) (*entity.Application, error) {
+		return tx.UpdateApplicationWithNewId(ctx, req.OldId, req.NewId, req.Name, req.Description)
+	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "update application")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{AppIds: []int{req.OldId, req.NewId}})
+
+	result, err := s.EnrichWithTokens(ctx, []entity.Application{*app})
+	if err != nil {
+		return nil, errors.WithMessage(err, "enrich application with tokens")
+	}
+
+	return result[0], nil
+}
+
+func (s Application) SetAllowedCidrs(ctx context.Context, req domain.SetAllowedCidrsRequest) (*domain.ApplicationWithTokens, error) {
+	app, err := s.save(ctx, "set_allowed_cidrs", req.AppId, func(ctx context.Context, tx ApplicationSaveTx) (*entity.Application, error) {
+		return tx.SetApplicationAllowedCidrs(ctx, req.AppId, req.AllowedCidrs)
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "set application allowed cidrs")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{AppIds: []int{req.AppId}})
+
+	result, err := s.EnrichWithTokens(ctx, []entity.Application{*app})
+	if err != nil {
+		return nil, errors.WithMessage(err, "enrich application with tokens")
+	}
+
+	return result[0], nil
+}
+
+// SetStatus changes the lifecycle status of the application, tokens of not active applications are not authenticated
+func (s Application) SetStatus(
+	ctx context.Context,
+	id int,
+	status string,
+	reason string,
+) (*domain.ApplicationWithTokens, error) {
+	app, err := s.save(ctx, "set_status", id, func(ctx context.Context, tx ApplicationSaveTx) (*entity.Application, error) {
+		return tx.SetApplicationStatus(ctx, id, status, reason, callerActor(ctx))
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "set application status")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{AppIds: []int{id}})
 
 	result, err := s.EnrichWithTokens(ctx, []entity.Application{*app})
 	if err != nil {
@@ -293,14 +442,75 @@ func (s Application) Update(ctx context.Context, req domain.UpdateApplicationReq
 	return result[0], nil
 }
 
-func (s Application) convertApplication(req entity.Application) domain.Application {
-	return domain.Application{
-		Id:          req.Id,
-		Name:        req.Name,
-		Description: req.Description.String,
-		ServiceId:   req.ApplicationGroupId,
-		Type:        req.Type,
-		CreatedAt:   req.CreatedAt,
-		UpdatedAt:   req.UpdatedAt,
+// save runs the change of the application in a transaction together with its audit event
+// and publishes renaming to subscribers, id is 0 for a new application
+func (s Application) save(
+	ctx context.Context,
+	action string,
+	id int,
+	change func(ctx context.Context, tx ApplicationSaveTx) (*entity.Application, error),
+) (*entity.Application, error) {
+	var app *entity.Application
+	err := s.txRunner.ApplicationSaveTx(ctx, func(ctx context.Context, tx ApplicationSaveTx) error {
+		var (
+			existing *entity.Application
+			before   any
+			err      error
+		)
+		if id != 0 {
+			existing, err = tx.GetApplicationById(ctx, id)
+			if err != nil {
+				return errors.WithMessage(err, "get application by id")
+			}
+			if action == "update" && existing.ManagedByConfig {
+				return errors.WithMessagef(domain.ErrManagedByConfig, "application %d", id)
+			}
+			before = ConvertApplication(*existing)
+		}
+
+		app, err = change(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		err = WriteAuditEvent(ctx, tx, entity.AuditEntityApplication, action, app.Id, before, ConvertApplication(*app))
+		if err != nil {
+			return err
+		}
+		if existing == nil || (existing.Id == app.Id && existing.Name == app.Name) {
+			return nil
+		}
+		return WriteChangeEvent(ctx, tx, domain.ChangeEventApplicationRenamed, domain.ApplicationRenamed{
+			OldAppId: existing.Id,
+			AppId:    app.Id,
+			OldName:  existing.Name,
+			Name:     app.Name,
+		})
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "transaction application %s", action)
+	}
+	return app, nil
+}
+
+// ConvertApplication converts the stored application to its API representation
+func ConvertApplication(req entity.Application) domain.Application {
+	result := domain.Application{
+		Id:              req.Id,
+		Name:            req.Name,
+		Description:     req.Description.String,
+		ServiceId:       req.ApplicationGroupId,
+		Type:            req.Type,
+		CreatedAt:       req.CreatedAt,
+		UpdatedAt:       req.UpdatedAt,
+		AllowedCidrs:    req.AllowedCidrs,
+		Status:          req.Status,
+		StatusReason:    req.StatusReason.String,
+		StatusChangedBy: req.StatusChangedBy.String,
+		ManagedByConfig: req.ManagedByConfig,
+	}
+	if req.StatusChangedAt.Valid {
+		result.StatusChangedAt = &req.StatusChangedAt.Time
 	}
+	return result
 }
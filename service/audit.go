@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/grpc"
+	"github.com/txix-open/isp-kit/json"
+	"github.com/txix-open/isp-kit/requestid"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	defaultAuditSearchLimit = 100
+
+	adminIdHeader = "x-admin-id"
+)
+
+type auditActorKey struct{}
+
+// AuditWriter is a part of every transaction mutating the registry
+type AuditWriter interface {
+	InsertAuditEvent(ctx context.Context, event entity.AuditEvent) error
+}
+
+type AuditRepo interface {
+	SearchAuditEvents(ctx context.Context, filter entity.AuditEventFilter) ([]entity.AuditEvent, error)
+}
+
+type Audit struct {
+	repo AuditRepo
+}
+
+func NewAudit(repo AuditRepo) Audit {
+	return Audit{
+		repo: repo,
+	}
+}
+
+func (s Audit) Search(ctx context.Context, req domain.AuditSearchRequest) (*domain.AuditSearchResponse, error) {
+	filter := entity.AuditEventFilter{
+		EntityType: req.EntityType,
+		EntityId:   req.EntityId,
+		Action:     req.Action,
+		Actor:      req.Actor,
+		RequestId:  req.RequestId,
+		Cursor:     req.Cursor,
+		Limit:      req.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditSearchLimit
+	}
+	if req.From != nil {
+		filter.From = sql.NullTime{Time: req.From.UTC(), Valid: true}
+	}
+	if req.To != nil {
+		filter.To = sql.NullTime{Time: req.To.UTC(), Valid: true}
+	}
+
+	events, err := s.repo.SearchAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, errors.WithMessage(err, "search audit events")
+	}
+	hasNext := len(events) > filter.Limit
+	if hasNext {
+		events = events[:filter.Limit]
+	}
+
+	result := &domain.AuditSearchResponse{
+		Items: make([]domain.AuditEvent, len(events)),
+	}
+	for i, event := range events {
+		result.Items[i] = domain.AuditEvent{
+			Id:         event.Id,
+			CreatedAt:  event.CreatedAt,
+			Actor:      event.Actor.String,
+			RequestId:  event.RequestId.String,
+			Action:     event.Action,
+			EntityType: event.EntityType,
+			EntityId:   event.EntityId,
+			Before:     json.RawMessage(event.Before),
+			After:      json.RawMessage(event.After),
+		}
+	}
+	if hasNext {
+		result.NextCursor = events[len(events)-1].Id
+	}
+	return result, nil
+}
+
+// WriteAuditEvent records the change of the entity in the transaction of the change,
+// before and after are marshaled to JSON, nil is stored as NULL
+func WriteAuditEvent(
+	ctx context.Context,
+	tx AuditWriter,
+	entityType string,
+	action string,
+	entityId any,
+	before any,
+	after any,
+) error {
+	event := entity.AuditEvent{
+		Actor:      nullString(callerActor(ctx)),
+		RequestId:  nullString(requestid.FromContext(ctx)),
+		Action:     entityType + "." + action,
+		EntityType: entityType,
+		EntityId:   fmt.Sprint(entityId),
+	}
+	var err error
+	event.Before, err = auditState(before)
+	if err != nil {
+		return errors.WithMessage(err, "marshal state before")
+	}
+	event.After, err = auditState(after)
+	if err != nil {
+		return errors.WithMessage(err, "marshal state after")
+	}
+
+	err = tx.InsertAuditEvent(ctx, event)
+	if err != nil {
+		return errors.WithMessagef(err, "insert audit event %s", event.Action)
+	}
+	return nil
+}
+
+func auditState(state any) (entity.AuditState, error) {
+	if state == nil {
+		return nil, nil
+	}
+	value, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// WithAuditActor sets the actor of the changes made without an incoming request,
+// it takes precedence over the caller of the request
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// callerActor identifies who made the change: the actor set by WithAuditActor, the admin passed by the gateway,
+// the calling application or its address
+func callerActor(ctx context.Context) string {
+	actor, _ := ctx.Value(auditActorKey{}).(string)
+	if actor != "" {
+		return actor
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	adminId := md.Get(adminIdHeader)
+	if len(adminId) > 0 && adminId[0] != "" {
+		return "admin:" + adminId[0]
+	}
+	appId := md.Get(grpc.ApplicationIdHeader)
+	if len(appId) > 0 && appId[0] != "" {
+		return "application:" + appId[0]
+	}
+	p, ok := peer.FromContext(ctx)
+	if ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -0,0 +1,340 @@
+package baseline
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+	"isp-system-service/service"
+	"isp-system-service/service/acl"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/log"
+)
+
+// seed creates missing seed applications with their domains and groups, updates changed ones,
+// adds missing access rules and saves tokens which are not issued yet,
+// returns true if the registry is changed
+func (s Service) seed(ctx context.Context, tx Transaction) (bool, error) {
+	seeds := seedApplications(s.cfg)
+	if len(seeds) == 0 {
+		s.logger.Info(ctx, "no seed applications, skip seed")
+		return false, nil
+	}
+	err := validateSeeds(seeds)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	for _, seed := range seeds {
+		seedChanged, err := s.seedApplication(ctx, tx, seed)
+		if err != nil {
+			return false, errors.WithMessagef(err, "seed application %d", seed.Id)
+		}
+		changed = changed || seedChanged
+	}
+
+	if changed {
+		s.logger.Info(ctx, "seed applications applied", log.Int("applications", len(seeds)))
+	} else {
+		s.logger.Info(ctx, "seed applications are up to date")
+	}
+	return changed, nil
+}
+
+func (s Service) seedApplication(ctx context.Context, tx Transaction, seed conf.BaselineApplication) (bool, error) {
+	domainId, domainChanged, err := s.seedDomain(ctx, tx, seed.Domain)
+	if err != nil {
+		return false, err
+	}
+	appGroupId, appGroupChanged, err := s.seedAppGroup(ctx, tx, domainId, seed.AppGroup)
+	if err != nil {
+		return false, err
+	}
+
+	app, err := tx.GetApplicationById(ctx, seed.Id)
+	switch {
+	case errors.Is(err, domain.ErrApplicationNotFound):
+		app, err = tx.CreateApplication(ctx, seed.Id, seed.Name, seed.Description, appGroupId, seed.Type)
+		if err != nil {
+			return false, errors.WithMessage(err, "create application")
+		}
+		err = service.WriteAuditEvent(ctx, tx, entity.AuditEntityApplication, "create", app.Id, nil, service.ConvertApplication(*app))
+		if err != nil {
+			return false, err
+		}
+	case err != nil:
+		return false, errors.WithMessage(err, "get application by id")
+	default:
+		app, err = s.updateApplication(ctx, tx, *app, appGroupId, seed)
+		if err != nil {
+			return false, err
+		}
+	}
+	appChanged := app != nil
+
+	accessListChanged, err := s.seedAccessList(ctx, tx, seed)
+	if err != nil {
+		return false, err
+	}
+
+	tokensChanged, err := s.seedTokens(ctx, tx, seed)
+	if err != nil {
+		return false, err
+	}
+
+	return domainChanged || appGroupChanged || appChanged || accessListChanged || tokensChanged, nil
+}
+
+func (s Service) seedDomain(ctx context.Context, tx Transaction, name string) (int, bool, error) {
+	domains, err := tx.GetDomainBySystemId(ctx, domain.DefaultSystemId)
+	if err != nil {
+		return 0, false, errors.WithMessage(err, "get domains by system_id")
+	}
+	idx := slices.IndexFunc(domains, func(e entity.Domain) bool {
+		return e.Name == name
+	})
+	if idx >= 0 {
+		return domains[idx].Id, false, nil
+	}
+
+	created, err := tx.CreateDomain(ctx, name, "", domain.DefaultSystemId)
+	if err != nil {
+		return 0, false, errors.WithMessage(err, "create domain")
+	}
+	err = service.WriteAuditEvent(ctx, tx, entity.AuditEntityDomain, "create", created.Id, nil, domain.Domain{
+		Id:        created.Id,
+		Name:      created.Name,
+		SystemId:  created.SystemId,
+		CreatedAt: created.CreatedAt,
+		UpdatedAt: created.UpdatedAt,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return created.Id, true, nil
+}
+
+func (s Service) seedAppGroup(ctx context.Context, tx Transaction, domainId int, name string) (int, bool, error) {
+	appGroups, err := tx.GetAppGroupByDomainId(ctx, []int{domainId})
+	if err != nil {
+		return 0, false, errors.WithMessage(err, "get application groups by domain_id")
+	}
+	idx := slices.IndexFunc(appGroups, func(e entity.AppGroup) bool {
+		return e.Name == name
+	})
+	if idx >= 0 {
+		return appGroups[idx].Id, false, nil
+	}
+
+	created, err := tx.CreateAppGroup(ctx, name, "", domainId)
+	if err != nil {
+		return 0, false, errors.WithMessage(err, "create application group")
+	}
+	err = service.WriteAuditEvent(ctx, tx, entity.AuditEntityAppGroup, "create", created.Id, nil, domain.AppGroup{
+		Id:        created.Id,
+		Name:      created.Name,
+		CreatedAt: created.CreatedAt,
+		UpdatedAt: created.UpdatedAt,
+		Status:    created.Status,
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return created.Id, true, nil
+}
+
+// updateApplication brings the existing application to the seed definition,
+// returns nil if the application already matches it
+func (s Service) updateApplication(
+	ctx context.Context,
+	tx Transaction,
+	existing entity.Application,
+	appGroupId int,
+	seed conf.BaselineApplication,
+) (*entity.Application, error) {
+	if existing.Name == seed.Name &&
+		existing.Description.String == seed.Description &&
+		existing.ApplicationGroupId == appGroupId &&
+		existing.Type == seed.Type {
+		return nil, nil
+	}
+
+	updated, err := tx.UpdateApplicationDefinition(ctx, existing.Id, seed.Name, seed.Description, appGroupId, seed.Type)
+	if err != nil {
+		return nil, errors.WithMessage(err, "update application")
+	}
+	err = service.WriteAuditEvent(ctx, tx, entity.AuditEntityApplication, "update", updated.Id,
+		service.ConvertApplication(existing), service.ConvertApplication(*updated),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Name != seed.Name {
+		err = service.WriteChangeEvent(ctx, tx, domain.ChangeEventApplicationRenamed, domain.ApplicationRenamed{
+			OldAppId: existing.Id,
+			AppId:    updated.Id,
+			OldName:  existing.Name,
+			Name:     updated.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// seedAccessList upserts the seed rules which are missing or differ,
+// rules of the application which are not in the seed are kept
+func (s Service) seedAccessList(ctx context.Context, tx Transaction, seed conf.BaselineApplication) (bool, error) {
+	existing, err := tx.GetAccessListByAppIdList(ctx, []int{seed.Id})
+	if err != nil {
+		return false, errors.WithMessage(err, "get access list by app_id")
+	}
+
+	upserted := make([]domain.MethodInfo, 0)
+	for _, rule := range seed.AccessList {
+		granted := slices.ContainsFunc(existing, func(e entity.AccessList) bool {
+			return e.HttpMethod == rule.HttpMethod && e.Method == rule.Method && e.Value == rule.Value
+		})
+		if granted {
+			continue
+		}
+		_, err = tx.UpsertAccessList(ctx, entity.AccessList{
+			AppId:      seed.Id,
+			HttpMethod: rule.HttpMethod,
+			Method:     rule.Method,
+			Value:      rule.Value,
+		})
+		if err != nil {
+			return false, errors.WithMessage(err, "upsert access list")
+		}
+		upserted = append(upserted, domain.MethodInfo{HttpMethod: rule.HttpMethod, Method: rule.Method, Value: rule.Value})
+	}
+	if len(upserted) == 0 {
+		return false, nil
+	}
+
+	err = service.WriteAuditEvent(ctx, tx, entity.AuditEntityAccessList, "upsert",
+		fmt.Sprintf("%s:%d", entity.AuditEntityApplication, seed.Id), nil, upserted,
+	)
+	if err != nil {
+		return false, err
+	}
+	err = service.WriteChangeEvent(ctx, tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{AppId: seed.Id})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// seedTokens saves the seed tokens which are not issued yet,
+// a token issued for another application is left as is
+func (s Service) seedTokens(ctx context.Context, tx Transaction, seed conf.BaselineApplication) (bool, error) {
+	changed := false
+	for _, token := range seed.Tokens {
+		tokenHash := s.hasher.Hash(token)
+		existing, err := tx.GetTokenById(ctx, tokenHash)
+		if err != nil {
+			return false, errors.WithMessage(err, "get token by id")
+		}
+		if existing != nil {
+			if existing.AppId != seed.Id {
+				s.logger.Warn(ctx, "seed token is issued for another application, skip token",
+					log.String("tokenId", existing.Id),
+					log.Int("appId", existing.AppId),
+				)
+			}
+			continue
+		}
+
+		tokenId, maskedToken, err := s.identifier.Identify(token)
+		if err != nil {
+			return false, errors.WithMessage(err, "identify token")
+		}
+		saved, err := tx.SaveToken(ctx, entity.Token{
+			Id:          tokenId,
+			TokenHash:   tokenHash,
+			MaskedToken: maskedToken,
+			Kind:        entity.TokenKindOpaque,
+			AppId:       seed.Id,
+			ExpireTime:  -1,
+		})
+		if err != nil {
+			return false, errors.WithMessage(err, "save token")
+		}
+		err = service.WriteAuditEvent(ctx, tx, entity.AuditEntityToken, "create", saved.Id, nil, domain.Token{
+			Id:          saved.Id,
+			MaskedToken: saved.MaskedToken,
+			Kind:        saved.Kind,
+			AppId:       saved.AppId,
+			ExpireTime:  saved.ExpireTime,
+			CreatedAt:   saved.CreatedAt,
+		})
+		if err != nil {
+			return false, err
+		}
+		err = service.WriteChangeEvent(ctx, tx, domain.ChangeEventTokenCreated, domain.TokenChange{
+			TokenId: saved.Id,
+			AppId:   saved.AppId,
+		})
+		if err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// seedApplications returns the seed applications of the config,
+// the initial admin ui token is issued for the admin application which is seeded by default
+func seedApplications(cfg conf.Baseline) []conf.BaselineApplication {
+	result := slices.Clone(cfg.Applications)
+	if cfg.InitialAdminUiToken == "" {
+		return result
+	}
+
+	idx := slices.IndexFunc(result, func(app conf.BaselineApplication) bool {
+		return app.Id == adminAppId
+	})
+	if idx < 0 {
+		result = append(result, conf.BaselineApplication{
+			Domain:     "root",
+			AppGroup:   "rootService",
+			Id:         adminAppId,
+			Name:       "admin",
+			Type:       domain.ApplicationSystemType,
+			AccessList: []conf.DesiredAccessRule{{Method: "admin/auth/login", Value: true}},
+		})
+		idx = len(result) - 1
+	}
+	result[idx].Tokens = append(slices.Clone(result[idx].Tokens), cfg.InitialAdminUiToken)
+	return result
+}
+
+func validateSeeds(seeds []conf.BaselineApplication) error {
+	appIds := make(map[int]bool, len(seeds))
+	for _, seed := range seeds {
+		if appIds[seed.Id] {
+			return errors.Errorf("duplicate seed application %d", seed.Id)
+		}
+		appIds[seed.Id] = true
+
+		methods := make(map[domain.Method]bool, len(seed.AccessList))
+		for _, rule := range seed.AccessList {
+			if !acl.IsValidPattern(rule.Method) {
+				return errors.WithMessagef(domain.ErrInvalidMethodPattern, "seed application %d method %s", seed.Id, rule.Method)
+			}
+			method := domain.Method{HttpMethod: rule.HttpMethod, Method: rule.Method}
+			if methods[method] {
+				return errors.Errorf("seed application %d duplicate method %s %s", seed.Id, rule.HttpMethod, rule.Method)
+			}
+			methods[method] = true
+		}
+	}
+	return nil
+}
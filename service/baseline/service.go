@@ -2,109 +2,198 @@ package baseline
 
 import (
 	"context"
+
 	"isp-system-service/conf"
+	"isp-system-service/domain"
 	"isp-system-service/entity"
+	"isp-system-service/service"
+	"isp-system-service/service/tokenhash"
 
 	"github.com/pkg/errors"
 	"github.com/txix-open/isp-kit/log"
 )
 
-const adminAppId = 1
+const (
+	adminAppId = 1
+
+	auditActor = "baseline"
+)
 
 type Transaction interface {
-	CreateDomain(ctx context.Context, name string, desc string, systemId int) (*entity.Domain, error)
-	CreateAppGroup(ctx context.Context, name string, desc string, domainId int) (*entity.AppGroup, error)
-	CreateApplication(ctx context.Context, id int, name string, desc string, appGroupId int, appType string) (*entity.Application, error)
+	service.ReconcileTx
 	UpsertAccessList(ctx context.Context, e entity.AccessList) (int, error)
-	SaveToken(ctx context.Context, token string, appId int, expireTime int) (*entity.Token, error)
-	GetTokenById(ctx context.Context, token string) (*entity.Token, error)
+	GetApplicationById(ctx context.Context, id int) (*entity.Application, error)
+	SaveToken(ctx context.Context, token entity.Token) (*entity.Token, error)
+	GetTokenById(ctx context.Context, tokenHash string) (*entity.Token, error)
 	TryLock(ctx context.Context, key string) (bool, error)
 }
 
+type Reconciler interface {
+	Reconcile(ctx context.Context, tx service.ReconcileTx, desired domain.DesiredState) (*domain.ReconcileResult, error)
+}
+
+type TokenIdentifier interface {
+	Identify(token string) (string, string, error)
+}
+
 type TxRunner interface {
 	BaselineTx(ctx context.Context, tx func(ctx context.Context, tx Transaction) error) error
 }
 
 type Service struct {
-	cfg      conf.Baseline
-	txRunner TxRunner
-	logger   log.Logger
+	cfg         conf.Baseline
+	desired     conf.Desired
+	txRunner    TxRunner
+	reconciler  Reconciler
+	invalidator service.CacheInvalidator
+	hasher      tokenhash.Hasher
+	identifier  TokenIdentifier
+	logger      log.Logger
 }
 
-func NewService(cfg conf.Baseline, txRunner TxRunner, logger log.Logger) Service {
+func NewService(
+	cfg conf.Baseline,
+	desired conf.Desired,
+	txRunner TxRunner,
+	reconciler Reconciler,
+	invalidator service.CacheInvalidator,
+	hasher tokenhash.Hasher,
+	identifier TokenIdentifier,
+	logger log.Logger,
+) Service {
 	return Service{
-		cfg:      cfg,
-		txRunner: txRunner,
-		logger:   logger,
+		cfg:         cfg,
+		desired:     desired,
+		txRunner:    txRunner,
+		reconciler:  reconciler,
+		invalidator: invalidator,
+		hasher:      hasher,
+		identifier:  identifier,
+		logger:      logger,
 	}
 }
 
 func (s Service) Do(ctx context.Context) error {
 	ctx = log.ToContext(ctx, log.String("worker", "baseline"))
-	if s.cfg.InitialAdminUiToken == "" {
-		s.logger.Info(ctx, "initial admin ui token is empty, skip baseline")
+	ctx = service.WithAuditActor(ctx, auditActor)
+	if len(seedApplications(s.cfg)) == 0 && !s.desired.Enabled {
+		s.logger.Info(ctx, "no seed applications and desired state is disabled, skip baseline")
 		return nil
 	}
 
-	err := s.txRunner.BaselineTx(ctx, s.transaction)
+	changed := false
+	err := s.txRunner.BaselineTx(ctx, func(ctx context.Context, tx Transaction) error {
+		var err error
+		changed, err = s.transaction(ctx, tx)
+		return err
+	})
 	if err != nil {
 		return errors.WithMessage(err, "run baseline transaction")
 	}
+	if changed {
+		s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
+	}
 
 	return nil
 }
 
-func (s Service) transaction(ctx context.Context, tx Transaction) error {
+func (s Service) transaction(ctx context.Context, tx Transaction) (bool, error) {
 	locked, err := tx.TryLock(ctx, "isp-system-service.baseline")
 	if err != nil {
-		return errors.WithMessage(err, "try lock isp-system-service.baseline")
+		return false, errors.WithMessage(err, "try lock isp-system-service.baseline")
 	}
 	if !locked {
 		s.logger.Info(ctx, "baseline is locked, skip baseline")
-		return nil
+		return false, nil
 	}
 
-	token, err := tx.GetTokenById(ctx, s.cfg.InitialAdminUiToken)
+	seeded, err := s.seed(ctx, tx)
 	if err != nil {
-		return errors.WithMessage(err, "get token by id")
+		return false, err
 	}
-	if token != nil {
-		s.logger.Info(ctx, "initial admin ui token is exist, skip baseline")
-		return nil
-	}
-
-	s.logger.Info(ctx, "initial admin ui token is empty, run baseline")
 
-	domain, err := tx.CreateDomain(ctx, "root", "", 1)
-	if err != nil {
-		return errors.WithMessage(err, "create domain")
+	if !s.desired.Enabled {
+		return seeded, nil
 	}
-
-	service, err := tx.CreateAppGroup(ctx, "rootService", "", domain.Id)
+	reconciled, err := s.reconcile(ctx, tx)
 	if err != nil {
-		return errors.WithMessage(err, "create service")
+		return false, err
 	}
+	return seeded || reconciled, nil
+}
 
-	app, err := tx.CreateApplication(ctx, adminAppId, "admin", "", service.Id, "SYSTEM")
+// reconcile brings the registry to the desired state of the config,
+// returns true if the registry is changed
+func (s Service) reconcile(ctx context.Context, tx Transaction) (bool, error) {
+	result, err := s.reconciler.Reconcile(ctx, tx, desiredState(s.desired))
 	if err != nil {
-		return errors.WithMessage(err, "create application")
+		return false, errors.WithMessage(err, "reconcile desired state")
 	}
 
-	_, err = tx.SaveToken(ctx, s.cfg.InitialAdminUiToken, app.Id, -1)
-	if err != nil {
-		return errors.WithMessage(err, "save token")
+	for _, change := range result.Drift {
+		s.logger.Warn(ctx, "managed object is changed bypassing the config, the change is reverted",
+			log.String("action", change.Action),
+			log.String("entityType", change.EntityType),
+			log.String("key", change.Key),
+		)
+	}
+	for _, tokenId := range result.MissingTokenIdList {
+		s.logger.Warn(ctx, "declared token is not issued for its application", log.String("tokenId", tokenId))
+	}
+	if len(result.Changes) == 0 {
+		s.logger.Info(ctx, "registry matches the desired state")
+		return false, nil
 	}
 
-	_, err = tx.UpsertAccessList(ctx, entity.AccessList{
-		AppId:  app.Id,
-		Method: "admin/auth/login",
-		Value:  true,
-	})
+	err = service.WriteAuditEvent(ctx, tx, entity.AuditEntityRegistry, "reconcile", domain.DefaultSystemId, nil, result)
 	if err != nil {
-		return errors.WithMessage(err, "upsert access list")
+		return false, err
 	}
+	s.logger.Info(ctx, "desired state reconciled",
+		log.Int("changes", len(result.Changes)),
+		log.Int("drift", len(result.Drift)),
+	)
+	return true, nil
+}
 
-	s.logger.Info(ctx, "baseline done")
+func desiredState(desired conf.Desired) domain.DesiredState {
+	result := domain.DesiredState{
+		AppGroups: make([]domain.DesiredAppGroup, len(desired.AppGroups)),
+	}
+	for i, appGroup := range desired.AppGroups {
+		apps := make([]domain.DesiredApplication, len(appGroup.Applications))
+		for j, app := range appGroup.Applications {
+			apps[j] = domain.DesiredApplication{
+				Id:          app.Id,
+				Name:        app.Name,
+				Description: app.Description,
+				Type:        app.Type,
+				AccessList:  accessRules(app.AccessList),
+				TokenIdList: app.TokenIdList,
+			}
+		}
+		result.AppGroups[i] = domain.DesiredAppGroup{
+			Domain:       appGroup.Domain,
+			Name:         appGroup.Name,
+			Description:  appGroup.Description,
+			AccessList:   accessRules(appGroup.AccessList),
+			Applications: apps,
+		}
+	}
+	return result
+}
 
-	return nil
+func accessRules(rules []conf.DesiredAccessRule) []domain.RegistryAccessRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	result := make([]domain.RegistryAccessRule, len(rules))
+	for i, rule := range rules {
+		result[i] = domain.RegistryAccessRule{
+			HttpMethod: rule.HttpMethod,
+			Method:     rule.Method,
+			Value:      rule.Value,
+		}
+	}
+	return result
 }
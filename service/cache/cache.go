@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is a size bounded LRU cache with per entry TTL.
+// Cache with non-positive size or ttl is disabled: it never stores values
+type Cache[K comparable, V any] struct {
+	maxSize int
+	ttl     time.Duration
+	now     func() time.Time
+
+	lock    sync.Mutex
+	items   map[K]*list.Element
+	order   *list.List
+	version uint64
+}
+
+func New[K comparable, V any](maxSize int, ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		maxSize: maxSize,
+		ttl:     ttl,
+		now:     time.Now,
+		items:   make(map[K]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *Cache[K, V]) Enabled() bool {
+	return c.maxSize > 0 && c.ttl > 0
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	var empty V
+	if !c.Enabled() {
+		return empty, false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return empty, false
+	}
+	e := elem.Value.(*entry[K, V]) // nolint:forcetypeassert
+	if !c.now().Before(e.expiresAt) {
+		c.remove(elem)
+		return empty, false
+	}
+	c.order.MoveToFront(elem)
+	return e.value, true
+}
+
+// Version returns a counter which is incremented on every invalidation.
+// Capture it before loading a value and pass it to SetIfVersion to avoid caching a value
+// which was invalidated while it was loading
+func (c *Cache[K, V]) Version() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.version
+}
+
+func (c *Cache[K, V]) SetIfVersion(version uint64, key K, value V) {
+	if !c.Enabled() {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.version != version {
+		return
+	}
+
+	expiresAt := c.now().Add(c.ttl)
+	elem, ok := c.items[key]
+	if ok {
+		e := elem.Value.(*entry[K, V]) // nolint:forcetypeassert
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.maxSize {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *Cache[K, V]) Delete(keys ...K) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.version++
+	for _, key := range keys {
+		elem, ok := c.items[key]
+		if ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// DeleteFunc removes all entries for which match returns true
+func (c *Cache[K, V]) DeleteFunc(match func(key K, value V) bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.version++
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		e := elem.Value.(*entry[K, V]) // nolint:forcetypeassert
+		if match(e.key, e.value) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+func (c *Cache[K, V]) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.version++
+	c.items = make(map[K]*list.Element)
+	c.order.Init()
+}
+
+func (c *Cache[K, V]) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *Cache[K, V]) remove(elem *list.Element) {
+	e := c.order.Remove(elem).(*entry[K, V]) // nolint:forcetypeassert
+	delete(c.items, e.key)
+}
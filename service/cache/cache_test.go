@@ -0,0 +1,79 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"isp-system-service/service/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Lru(t *testing.T) {
+	t.Parallel()
+
+	c := cache.New[string, int](2, time.Minute)
+	c.SetIfVersion(c.Version(), "a", 1)
+	c.SetIfVersion(c.Version(), "b", 2)
+	_, ok := c.Get("a")
+	require.True(t, ok)
+
+	c.SetIfVersion(c.Version(), "c", 3)
+	require.Equal(t, 2, c.Len())
+	_, ok = c.Get("b")
+	require.False(t, ok)
+	value, ok := c.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+}
+
+func TestCache_Ttl(t *testing.T) {
+	t.Parallel()
+
+	c := cache.New[string, int](10, 50*time.Millisecond)
+	c.SetIfVersion(c.Version(), "a", 1)
+	_, ok := c.Get("a")
+	require.True(t, ok)
+
+	time.Sleep(60 * time.Millisecond)
+	_, ok = c.Get("a")
+	require.False(t, ok)
+	require.Equal(t, 0, c.Len())
+}
+
+func TestCache_Invalidation(t *testing.T) {
+	t.Parallel()
+
+	c := cache.New[string, int](10, time.Minute)
+	c.SetIfVersion(c.Version(), "a", 1)
+	c.SetIfVersion(c.Version(), "b", 2)
+	c.SetIfVersion(c.Version(), "c", 3)
+
+	c.Delete("a")
+	_, ok := c.Get("a")
+	require.False(t, ok)
+
+	c.DeleteFunc(func(key string, value int) bool {
+		return value == 2
+	})
+	_, ok = c.Get("b")
+	require.False(t, ok)
+	require.Equal(t, 1, c.Len())
+
+	version := c.Version()
+	c.Purge()
+	c.SetIfVersion(version, "d", 4)
+	_, ok = c.Get("d")
+	require.False(t, ok)
+	require.Equal(t, 0, c.Len())
+}
+
+func TestCache_Disabled(t *testing.T) {
+	t.Parallel()
+
+	c := cache.New[string, int](0, time.Minute)
+	require.False(t, c.Enabled())
+	c.SetIfVersion(c.Version(), "a", 1)
+	_, ok := c.Get("a")
+	require.False(t, ok)
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/json"
+	"github.com/txix-open/isp-kit/log"
+)
+
+const (
+	changeEventRelayLockKey = "isp-system-service.change_event_relay"
+	changeEventRelayBatch   = 1000
+	defaultSubscribeLimit   = 100
+)
+
+// ChangeEventWriter is a part of transactions publishing registry changes to subscribers
+type ChangeEventWriter interface {
+	InsertChangeEvent(ctx context.Context, event entity.ChangeEvent) error
+}
+
+type ChangeEventRelayTx interface {
+	TryLock(ctx context.Context, key string) (bool, error)
+	AssignChangeEventOffsets(ctx context.Context, limit int) (int, error)
+	DeleteExpiredChangeEvents(ctx context.Context, retention time.Duration) (int, error)
+}
+
+type ChangeEventRelayTxRunner interface {
+	ChangeEventRelayTx(ctx context.Context, tx func(ctx context.Context, tx ChangeEventRelayTx) error) error
+}
+
+type ChangeEventRepo interface {
+	GetChangeEvents(ctx context.Context, afterOffset int64, limit int) ([]entity.ChangeEvent, error)
+	GetLastChangeEventOffset(ctx context.Context) (int64, error)
+}
+
+// ChangeEventBroker wakes subscribers of the instance waiting for new events
+type ChangeEventBroker struct {
+	lock       sync.Mutex
+	lastOffset int64
+	changed    chan struct{}
+}
+
+func NewChangeEventBroker() *ChangeEventBroker {
+	return &ChangeEventBroker{
+		changed: make(chan struct{}),
+	}
+}
+
+// Publish wakes the waiting subscribers if the offset is greater than the previously published one
+func (b *ChangeEventBroker) Publish(offset int64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if offset <= b.lastOffset {
+		return
+	}
+	b.lastOffset = offset
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Changed returns a channel closed by the next Publish,
+// it must be taken before reading events not to miss the notification
+func (b *ChangeEventBroker) Changed() <-chan struct{} {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.changed
+}
+
+// ChangeEventRelay assigns offsets to committed events and wakes subscribers,
+// it is run by worker on every instance, but only one instance assigns offsets at a time
+type ChangeEventRelay struct {
+	retention time.Duration
+	tx        ChangeEventRelayTxRunner
+	repo      ChangeEventRepo
+	broker    *ChangeEventBroker
+	logger    log.Logger
+}
+
+func NewChangeEventRelay(
+	retention time.Duration,
+	tx ChangeEventRelayTxRunner,
+	repo ChangeEventRepo,
+	broker *ChangeEventBroker,
+	logger log.Logger,
+) ChangeEventRelay {
+	return ChangeEventRelay{
+		retention: retention,
+		tx:        tx,
+		repo:      repo,
+		broker:    broker,
+		logger:    logger,
+	}
+}
+
+func (r ChangeEventRelay) Do(ctx context.Context) {
+	ctx = log.ToContext(ctx, log.String("worker", "change_event_relay"))
+
+	err := r.relay(ctx)
+	if err != nil {
+		r.logger.Error(ctx, errors.WithMessage(err, "relay change events"))
+	}
+
+	// events could be relayed by other instance
+	offset, err := r.repo.GetLastChangeEventOffset(ctx)
+	if err != nil {
+		r.logger.Error(ctx, errors.WithMessage(err, "get last change event offset"))
+		return
+	}
+	r.broker.Publish(offset)
+}
+
+func (r ChangeEventRelay) relay(ctx context.Context) error {
+	err := r.tx.ChangeEventRelayTx(ctx, func(ctx context.Context, tx ChangeEventRelayTx) error {
+		locked, err := tx.TryLock(ctx, changeEventRelayLockKey)
+		if err != nil {
+			return errors.WithMessagef(err, "try lock %s", changeEventRelayLockKey)
+		}
+		if !locked {
+			return nil
+		}
+
+		for {
+			assigned, err := tx.AssignChangeEventOffsets(ctx, changeEventRelayBatch)
+			if err != nil {
+				return errors.WithMessage(err, "assign change event offsets")
+			}
+			if assigned < changeEventRelayBatch {
+				break
+			}
+		}
+
+		if r.retention == 0 {
+			return nil
+		}
+		deleted, err := tx.DeleteExpiredChangeEvents(ctx, r.retention)
+		if err != nil {
+			return errors.WithMessage(err, "delete expired change events")
+		}
+		if deleted > 0 {
+			r.logger.Info(ctx, "expired change events deleted", log.Int("deleted", deleted))
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "change event relay transaction")
+	}
+	return nil
+}
+
+type ChangeEvents struct {
+	repo   ChangeEventRepo
+	broker *ChangeEventBroker
+}
+
+func NewChangeEvents(repo ChangeEventRepo, broker *ChangeEventBroker) ChangeEvents {
+	return ChangeEvents{
+		repo:   repo,
+		broker: broker,
+	}
+}
+
+// Subscribe returns events after the offset, if there are none it waits for new events up to WaitSeconds
+func (s ChangeEvents) Subscribe(ctx context.Context, req domain.SubscribeEventsRequest) (*domain.SubscribeEventsResponse, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultSubscribeLimit
+	}
+	timeout := time.NewTimer(time.Duration(req.WaitSeconds) * time.Second)
+	defer timeout.Stop()
+
+	for {
+		changed := s.broker.Changed()
+		events, err := s.repo.GetChangeEvents(ctx, req.Offset, limit)
+		if err != nil {
+			return nil, errors.WithMessage(err, "get change events")
+		}
+		if len(events) > 0 || req.WaitSeconds == 0 {
+			return s.response(req.Offset, events), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return s.response(req.Offset, nil), nil
+		case <-timeout.C:
+			return s.response(req.Offset, nil), nil
+		case <-changed:
+		}
+	}
+}
+
+func (s ChangeEvents) response(offset int64, events []entity.ChangeEvent) *domain.SubscribeEventsResponse {
+	result := &domain.SubscribeEventsResponse{
+		Events:     make([]domain.ChangeEvent, len(events)),
+		NextOffset: offset,
+	}
+	for i, event := range events {
+		result.Events[i] = domain.ChangeEvent{
+			Offset:    event.StreamOffset.Int64,
+			CreatedAt: event.CreatedAt,
+			Type:      event.Type,
+			Payload:   json.RawMessage(event.Payload),
+		}
+		result.NextOffset = event.StreamOffset.Int64
+	}
+	return result
+}
+
+// WriteChangeEvent adds the event to the outbox in the transaction of the change
+func WriteChangeEvent(ctx context.Context, tx ChangeEventWriter, eventType string, payload any) error {
+	value, err := json.Marshal(payload)
+	if err != nil {
+		return errors.WithMessage(err, "marshal change event payload")
+	}
+
+	err = tx.InsertChangeEvent(ctx, entity.ChangeEvent{
+		Type:    eventType,
+		Payload: value,
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "insert change event %s", eventType)
+	}
+	return nil
+}
+
+// ApplicationDeleteEventTx reads tokens which are deleted by cascade together with their applications
+type ApplicationDeleteEventTx interface {
+	ChangeEventWriter
+	GetTokenByAppIdList(ctx context.Context, appIdList []int) ([]entity.Token, error)
+}
+
+// CascadeDeleteTx reads applications and tokens which are deleted by cascade together with their groups
+type CascadeDeleteTx interface {
+	ApplicationDeleteEventTx
+	GetApplicationByAppGroupIdList(ctx context.Context, appGroupIdList []int) ([]entity.Application, error)
+}
+
+// writeCascadeDeleteEvents adds token.revoked and application.deleted events for applications of the groups
+// before the groups are deleted, applications managed by config can't be deleted by cascade
+func writeCascadeDeleteEvents(ctx context.Context, tx CascadeDeleteTx, appGroupIdList []int) error {
+	if len(appGroupIdList) == 0 {
+		return nil
+	}
+
+	apps, err := tx.GetApplicationByAppGroupIdList(ctx, appGroupIdList)
+	if err != nil {
+		return errors.WithMessage(err, "get applications by app_group_id")
+	}
+	for _, app := range apps {
+		if app.ManagedByConfig {
+			return errors.WithMessagef(domain.ErrManagedByConfig, "application %d", app.Id)
+		}
+	}
+	return writeApplicationDeleteEvents(ctx, tx, apps)
+}
+
+// writeApplicationDeleteEvents adds token.revoked events for tokens of the applications
+// and application.deleted events for the applications before they are deleted
+func writeApplicationDeleteEvents(ctx context.Context, tx ApplicationDeleteEventTx, apps []entity.Application) error {
+	if len(apps) == 0 {
+		return nil
+	}
+	appIdList := make([]int, len(apps))
+	for i, app := range apps {
+		appIdList[i] = app.Id
+	}
+
+	tokens, err := tx.GetTokenByAppIdList(ctx, appIdList)
+	if err != nil {
+		return errors.WithMessage(err, "get tokens by app_id list")
+	}
+	for _, token := range tokens {
+		err = WriteChangeEvent(ctx, tx, domain.ChangeEventTokenRevoked, domain.TokenChange{
+			TokenId: token.Id,
+			AppId:   token.AppId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, app := range apps {
+		err = WriteChangeEvent(ctx, tx, domain.ChangeEventApplicationDeleted, domain.ApplicationDeleted{
+			AppId: app.Id,
+			Name:  app.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -9,13 +9,37 @@ import (
 	"github.com/pkg/errors"
 )
 
+type DomainSaveTx interface {
+	AuditWriter
+	GetDomainById(ctx context.Context, id int) (*entity.Domain, error)
+	CreateDomain(ctx context.Context, name string, desc string, systemId int) (*entity.Domain, error)
+	UpdateDomain(ctx context.Context, id int, name string, description string) (*entity.Domain, error)
+}
+
+type DomainDeleteTx interface {
+	AuditWriter
+	CascadeDeleteTx
+	GetAppGroupByDomainId(ctx context.Context, domainIdList []int) ([]entity.AppGroup, error)
+	GetDomainByIdList(ctx context.Context, idList []int) ([]entity.Domain, error)
+	DeleteDomain(ctx context.Context, idList []int) (int, error)
+}
+
+type DomainTxRunner interface {
+	DomainSaveTx(ctx context.Context, tx func(ctx context.Context, tx DomainSaveTx) error) error
+	DomainDeleteTx(ctx context.Context, tx func(ctx context.Context, tx DomainDeleteTx) error) error
+}
+
 type Domain struct {
-	repo DomainRepo
+	txRunner    DomainTxRunner
+	repo        DomainRepo
+	invalidator CacheInvalidator
 }
 
-func NewDomain(repo DomainRepo) Domain {
+func NewDomain(txRunner DomainTxRunner, repo DomainRepo, invalidator CacheInvalidator) Domain {
 	return Domain{
-		repo: repo,
+		txRunner:    txRunner,
+		repo:        repo,
+		invalidator: invalidator,
 	}
 }
 
@@ -55,7 +79,9 @@ func (s Domain) CreateUpdate(ctx context.Context, req domain.DomainCreateUpdateR
 			return nil, domain.ErrDomainDuplicateName
 		}
 
-		domainEntity, err := s.repo.CreateDomain(ctx, req.Name, req.Description, systemId)
+		domainEntity, err := s.save(ctx, "create", 0, func(ctx context.Context, tx DomainSaveTx) (*entity.Domain, error) {
+			return tx.CreateDomain(ctx, req.Name, req.Description, systemId)
+		})
 		if err != nil {
 			return nil, errors.WithMessage(err, "create domain")
 		}
@@ -68,12 +94,9 @@ func (s Domain) CreateUpdate(ctx context.Context, req domain.DomainCreateUpdateR
 		return nil, domain.ErrDomainDuplicateName
 	}
 
-	_, err = s.repo.GetDomainById(ctx, req.Id)
-	if err != nil {
-		return nil, errors.WithMessage(err, "get domain by id")
-	}
-
-	domainEntity, err := s.repo.UpdateDomain(ctx, req.Id, req.Name, req.Description)
+	domainEntity, err := s.save(ctx, "update", req.Id, func(ctx context.Context, tx DomainSaveTx) (*entity.Domain, error) {
+		return tx.UpdateDomain(ctx, req.Id, req.Name, req.Description)
+	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "update domain")
 	}
@@ -83,14 +106,83 @@ func (s Domain) CreateUpdate(ctx context.Context, req domain.DomainCreateUpdateR
 }
 
 func (s Domain) Delete(ctx context.Context, idList []int) (int, error) {
-	result, err := s.repo.DeleteDomain(ctx, idList)
+	result := 0
+	err := s.txRunner.DomainDeleteTx(ctx, func(ctx context.Context, tx DomainDeleteTx) error {
+		domains, err := tx.GetDomainByIdList(ctx, idList)
+		if err != nil {
+			return errors.WithMessage(err, "get domain by id list")
+		}
+
+		appGroups, err := tx.GetAppGroupByDomainId(ctx, idList)
+		if err != nil {
+			return errors.WithMessage(err, "get application groups by domain_id")
+		}
+		appGroupIdList := make([]int, len(appGroups))
+		for i, appGroup := range appGroups {
+			if appGroup.ManagedByConfig {
+				return errors.WithMessagef(domain.ErrManagedByConfig, "application group %d", appGroup.Id)
+			}
+			appGroupIdList[i] = appGroup.Id
+		}
+		err = writeCascadeDeleteEvents(ctx, tx, appGroupIdList)
+		if err != nil {
+			return err
+		}
+
+		result, err = tx.DeleteDomain(ctx, idList)
+		if err != nil {
+			return errors.WithMessage(err, "delete domain")
+		}
+
+		for _, domainEntity := range domains {
+			err = WriteAuditEvent(ctx, tx, entity.AuditEntityDomain, "delete", domainEntity.Id, s.convertDomain(domainEntity), nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return 0, errors.WithMessage(err, "delete domain")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
 
 	return result, nil
 }
 
+// save runs the change of the domain in a transaction together with its audit event,
+// id is 0 for a new domain
+func (s Domain) save(
+	ctx context.Context,
+	action string,
+	id int,
+	change func(ctx context.Context, tx DomainSaveTx) (*entity.Domain, error),
+) (*entity.Domain, error) {
+	var domainEntity *entity.Domain
+	err := s.txRunner.DomainSaveTx(ctx, func(ctx context.Context, tx DomainSaveTx) error {
+		var before any
+		if id != 0 {
+			existing, err := tx.GetDomainById(ctx, id)
+			if err != nil {
+				return errors.WithMessage(err, "get domain by id")
+			}
+			before = s.convertDomain(*existing)
+		}
+
+		var err error
+		domainEntity, err = change(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		return WriteAuditEvent(ctx, tx, entity.AuditEntityDomain, action, domainEntity.Id, before, s.convertDomain(*domainEntity))
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "transaction domain %s", action)
+	}
+	return domainEntity, nil
+}
+
 func (s Domain) convertDomain(req entity.Domain) domain.Domain {
 	desc := ""
 	if req.Description != nil {
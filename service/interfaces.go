@@ -2,12 +2,23 @@ package service
 
 import (
 	"context"
+	"time"
+
+	"isp-system-service/domain"
 	"isp-system-service/entity"
 )
 
+// CacheInvalidator drops stale authentication and authorization cache entries, it is called after commit
+type CacheInvalidator interface {
+	Invalidate(ctx context.Context, event domain.CacheInvalidation)
+}
+
 type TokenRepo interface {
 	GetTokenByAppIdList(ctx context.Context, appIdList []int) ([]entity.Token, error)
-	GetTokenById(ctx context.Context, token string) (*entity.Token, error)
+	ListTokens(ctx context.Context, appId int, filter entity.ListFilter) ([]entity.Token, error)
+	CountTokens(ctx context.Context, appId int, filter entity.ListFilter) (int, error)
+	GetTokenById(ctx context.Context, tokenHash string) (*entity.Token, error)
+	GetUnusedTokens(ctx context.Context, appId int, unusedSince time.Duration) ([]entity.Token, error)
 }
 
 type DomainRepo interface {
@@ -27,17 +38,22 @@ type ApplicationRepo interface {
 	CreateApplication(ctx context.Context, id int, name string, desc string, appGroupId int, appType string) (*entity.Application, error)
 	UpdateApplication(ctx context.Context, id int, name string, description string) (*entity.Application, error)
 	UpdateApplicationWithNewId(ctx context.Context, oldId int, newId int, name string, description string) (*entity.Application, error)
+	SetApplicationAllowedCidrs(ctx context.Context, id int, allowedCidrs entity.AddressList) (*entity.Application, error)
+	SetApplicationStatus(ctx context.Context, id int, status string, reason string, changedBy string) (*entity.Application, error)
 	NextApplicationId(ctx context.Context) (int, error)
-	GetAllApplications(ctx context.Context) ([]entity.Application, error)
+	ListApplications(ctx context.Context, filter entity.ListFilter) ([]entity.Application, error)
+	CountApplications(ctx context.Context, filter entity.ListFilter) (int, error)
 }
 
 type AppGroupRepo interface {
 	GetAppGroupById(ctx context.Context, id int) (*entity.AppGroup, error)
 	GetAppGroupByIdList(ctx context.Context, idList []int) ([]entity.AppGroup, error)
 	GetAppGroupByDomainId(ctx context.Context, domainIdList []int) ([]entity.AppGroup, error)
-	GetAllAppGroups(ctx context.Context) ([]entity.AppGroup, error)
+	ListAppGroups(ctx context.Context, filter entity.ListFilter) ([]entity.AppGroup, error)
+	CountAppGroups(ctx context.Context, filter entity.ListFilter) (int, error)
 	GetAppGroupByNameAndDomainId(ctx context.Context, name string, domainId int) (*entity.AppGroup, error)
 	CreateAppGroup(ctx context.Context, name string, desc string, domainId int) (*entity.AppGroup, error)
 	UpdateAppGroup(ctx context.Context, id int, name string, description string) (*entity.AppGroup, error)
+	SetAppGroupStatus(ctx context.Context, id int, status string, reason string, changedBy string) (*entity.AppGroup, error)
 	DeleteAppGroup(ctx context.Context, idList []int) (int, error)
 }
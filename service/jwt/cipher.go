@@ -0,0 +1,76 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoEncryptionSecret = errors.New("key encryption secret is not set")
+)
+
+// Cipher encrypts private keys stored in the database with AES-256-GCM,
+// the encryption key is derived from the secret.
+// Without the secret keys are neither encrypted nor decrypted
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func NewCipher(secret string) (Cipher, error) {
+	if secret == "" {
+		return Cipher{}, nil
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return Cipher{}, errors.WithMessage(err, "new aes cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return Cipher{}, errors.WithMessage(err, "new gcm")
+	}
+	return Cipher{aead: aead}, nil
+}
+
+// HasSecret reports whether the encryption secret is set
+func (c Cipher) HasSecret() bool {
+	return c.aead != nil
+}
+
+func (c Cipher) Encrypt(plaintext string) (string, error) {
+	if !c.HasSecret() {
+		return "", ErrNoEncryptionSecret
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", errors.WithMessage(err, "crypto/rand read")
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c Cipher) Decrypt(ciphertext string) (string, error) {
+	if !c.HasSecret() {
+		return "", ErrNoEncryptionSecret
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", errors.WithMessage(err, "decode base64")
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", errors.WithMessage(err, "decrypt, probably encryption secret was changed")
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,146 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+
+	"isp-system-service/domain"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	AlgorithmRs256 = "RS256"
+	AlgorithmEdDsa = "EdDSA"
+
+	minRsaKeyBits = 2048
+)
+
+// Key is a private signing key identified by kid
+type Key struct {
+	id        string
+	algorithm string
+	signer    crypto.Signer
+}
+
+// ParseKey parses PEM encoded RSA (RS256) or Ed25519 (EdDSA) private key
+func ParseKey(id string, privateKeyPem string) (*Key, error) {
+	block, _ := pem.Decode([]byte(privateKeyPem))
+	if block == nil {
+		return nil, errors.Errorf("key %s: pem block not found", id)
+	}
+
+	var (
+		privateKey any
+		err        error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.WithMessagef(err, "key %s: parse private key", id)
+	}
+
+	return NewKey(id, privateKey)
+}
+
+// GenerateKey generates a new key for the algorithm and returns it together with its PEM encoded private key
+func GenerateKey(id string, algorithm string) (*Key, string, error) {
+	var (
+		privateKey any
+		err        error
+	)
+	switch algorithm {
+	case AlgorithmRs256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, minRsaKeyBits)
+	case AlgorithmEdDsa:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, "", errors.Errorf("unsupported algorithm %s", algorithm)
+	}
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "generate key")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "marshal private key")
+	}
+	key, err := NewKey(id, privateKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func NewKey(id string, privateKey any) (*Key, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRsaKeyBits {
+			return nil, errors.Errorf("key %s: rsa key must be at least %d bits", id, minRsaKeyBits)
+		}
+		return &Key{id: id, algorithm: AlgorithmRs256, signer: key}, nil
+	case ed25519.PrivateKey:
+		return &Key{id: id, algorithm: AlgorithmEdDsa, signer: key}, nil
+	default:
+		return nil, errors.Errorf("key %s: unsupported key type %T", id, privateKey)
+	}
+}
+
+func (k *Key) Id() string {
+	return k.id
+}
+
+func (k *Key) Algorithm() string {
+	return k.algorithm
+}
+
+func (k *Key) sign(input []byte) ([]byte, error) {
+	if k.algorithm == AlgorithmEdDsa {
+		return k.signer.Sign(nil, input, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(input)
+	return k.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func (k *Key) verify(input []byte, signature []byte) bool {
+	switch public := k.signer.Public().(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(public, input, signature)
+	default:
+		return false
+	}
+}
+
+// Jwk returns the public part of the key in JWK format
+func (k *Key) Jwk() domain.Jwk {
+	jwk := domain.Jwk{
+		Kid: k.id,
+		Alg: k.algorithm,
+		Use: "sig",
+	}
+	switch public := k.signer.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
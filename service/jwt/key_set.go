@@ -0,0 +1,166 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"isp-system-service/domain"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/json"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid jwt")
+	ErrUnknownKey   = errors.WithMessage(ErrInvalidToken, "unknown key")
+	ErrNoSigningKey = errors.New("there is no active signing key")
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// Claims of application token
+type Claims struct {
+	Id         string `json:"jti"`
+	AppId      int    `json:"appId"`
+	AppName    string `json:"appName"`
+	AppGroupId int    `json:"appGroupId"`
+	DomainId   int    `json:"domainId"`
+	SystemId   int    `json:"systemId"`
+	IssuedAt   int64  `json:"iat"`
+	// zero for non-expiring tokens
+	ExpiresAt int64 `json:"exp,omitempty"`
+	// space-separated methods the token is restricted to, empty for not restricted tokens
+	Scope string `json:"scope,omitempty"`
+	// CIDR the token is restricted to, CIDR of the application are checked separately
+	AllowedCidrs []string `json:"allowedCidrs,omitempty"`
+}
+
+// KeySet signs tokens with the signing key and verifies them with any key of the set
+type KeySet struct {
+	signing *Key
+	keys    []*Key
+}
+
+// NewKeySet returns the set which signs tokens with the signing key and verifies them with any of keys.
+// Signing key could be nil, then the set could only verify tokens
+func NewKeySet(signing *Key, keys []*Key) *KeySet {
+	return &KeySet{
+		signing: signing,
+		keys:    keys,
+	}
+}
+
+func (s *KeySet) CanSign() bool {
+	return s.signing != nil
+}
+
+func (s *KeySet) Sign(claims Claims) (string, error) {
+	if s.signing == nil {
+		return "", ErrNoSigningKey
+	}
+
+	headerJson, err := json.Marshal(header{Alg: s.signing.algorithm, Typ: "JWT", Kid: s.signing.id})
+	if err != nil {
+		return "", errors.WithMessage(err, "marshal header")
+	}
+	claimsJson, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.WithMessage(err, "marshal claims")
+	}
+
+	input := encode(headerJson) + "." + encode(claimsJson)
+	signature, err := s.signing.sign([]byte(input))
+	if err != nil {
+		return "", errors.WithMessage(err, "sign")
+	}
+
+	return input + "." + encode(signature), nil
+}
+
+// Verify checks the signature and returns claims, the expiration is checked by caller
+func (s *KeySet) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 { //nolint:mnd
+		return nil, ErrInvalidToken
+	}
+
+	h := header{}
+	err := decodeJson(parts[0], &h)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	key := s.key(h.Kid)
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	if key.algorithm != h.Alg {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	claims := Claims{}
+	err = decodeJson(parts[1], &claims)
+	if err != nil || claims.Id == "" {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+func (s *KeySet) Jwks() domain.Jwks {
+	keys := make([]domain.Jwk, len(s.keys))
+	for i, key := range s.keys {
+		keys[i] = key.Jwk()
+	}
+	return domain.Jwks{Keys: keys}
+}
+
+func (s *KeySet) key(id string) *Key {
+	for _, key := range s.keys {
+		if key.id == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// IsJwt reports whether the token has JWS compact serialization form
+func IsJwt(token string) bool {
+	return strings.Count(token, ".") == 2 //nolint:mnd
+}
+
+// UnverifiedId returns jti of the token without signature verification
+func UnverifiedId(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 { //nolint:mnd
+		return "", ErrInvalidToken
+	}
+	claims := Claims{}
+	err := decodeJson(parts[1], &claims)
+	if err != nil || claims.Id == "" {
+		return "", ErrInvalidToken
+	}
+	return claims.Id, nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeJson(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,97 @@
+package jwt_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"isp-system-service/service/jwt"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySet_SignVerify(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, privateKey := range []any{rsaKey, edKey} {
+		key, err := jwt.ParseKey("kid", pemKey(t, privateKey))
+		require.NoError(t, err)
+		keySet := jwt.NewKeySet(key, []*jwt.Key{key})
+
+		claims := jwt.Claims{Id: "jti", AppId: 7, AppName: "app", AppGroupId: 5, DomainId: 3, SystemId: 1, IssuedAt: 100, ExpiresAt: 200}
+		token, err := keySet.Sign(claims)
+		require.NoError(t, err)
+		require.True(t, jwt.IsJwt(token))
+
+		verified, err := keySet.Verify(token)
+		require.NoError(t, err)
+		require.Equal(t, claims, *verified)
+
+		id, err := jwt.UnverifiedId(token)
+		require.NoError(t, err)
+		require.Equal(t, "jti", id)
+
+		parts := strings.Split(token, ".")
+		tampered, err := keySet.Sign(jwt.Claims{Id: "jti", AppId: 8})
+		require.NoError(t, err)
+		_, err = keySet.Verify(parts[0] + "." + strings.Split(tampered, ".")[1] + "." + parts[2])
+		require.ErrorIs(t, err, jwt.ErrInvalidToken)
+
+		jwks := keySet.Jwks()
+		require.Len(t, jwks.Keys, 1)
+		require.Equal(t, "kid", jwks.Keys[0].Kid)
+		require.Equal(t, key.Algorithm(), jwks.Keys[0].Alg)
+	}
+}
+
+func TestKeySet_UnknownKey(t *testing.T) {
+	t.Parallel()
+
+	_, first, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, second, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	firstKey, err := jwt.NewKey("first", first)
+	require.NoError(t, err)
+	secondKey, err := jwt.NewKey("second", second)
+	require.NoError(t, err)
+
+	signer := jwt.NewKeySet(secondKey, []*jwt.Key{firstKey, secondKey})
+	token, err := signer.Sign(jwt.Claims{Id: "jti"})
+	require.NoError(t, err)
+
+	verifier := jwt.NewKeySet(nil, []*jwt.Key{firstKey})
+	_, err = verifier.Verify(token)
+	require.ErrorIs(t, err, jwt.ErrUnknownKey)
+	require.ErrorIs(t, err, jwt.ErrInvalidToken)
+
+	_, err = verifier.Sign(jwt.Claims{Id: "jti"})
+	require.ErrorIs(t, err, jwt.ErrNoSigningKey)
+}
+
+func TestParseKey_WeakRsaKey(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024) //nolint:gosec
+	require.NoError(t, err)
+	_, err = jwt.ParseKey("kid", pemKey(t, rsaKey))
+	require.Error(t, err)
+}
+
+func pemKey(t *testing.T, privateKey any) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
@@ -0,0 +1,145 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	keysReloadInterval = time.Minute
+	// limits reloads caused by tokens signed with unknown keys
+	unknownKeyReloadInterval = time.Second
+)
+
+type KeyRepo interface {
+	GetNotRetiredSigningKeys(ctx context.Context) ([]entity.SigningKey, error)
+}
+
+// KeyStore keeps not retired keys loaded from the database.
+// The active key signs tokens, retiring keys only verify them.
+// Keys are reloaded periodically, after Reset and when a token signed with an unknown key is met,
+// so keys rotated by other instances become known without restart
+type KeyStore struct {
+	repo   KeyRepo
+	cipher Cipher
+
+	lock             sync.Mutex
+	keys             *KeySet
+	loadedAt         time.Time
+	unknownKeyLoadAt time.Time
+}
+
+func NewKeyStore(repo KeyRepo, cipher Cipher) *KeyStore {
+	return &KeyStore{
+		repo:   repo,
+		cipher: cipher,
+	}
+}
+
+func (s *KeyStore) Sign(ctx context.Context, claims Claims) (string, error) {
+	keys, err := s.keySet(ctx)
+	if err != nil {
+		return "", err
+	}
+	return keys.Sign(claims)
+}
+
+// Verify checks the signature with any not retired key, errors caused by the token wrap ErrInvalidToken
+func (s *KeyStore) Verify(ctx context.Context, token string) (*Claims, error) {
+	keys, err := s.keySet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := keys.Verify(token)
+	if !errors.Is(err, ErrUnknownKey) {
+		return claims, err
+	}
+
+	keys, err = s.reloadForUnknownKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keys.Verify(token)
+}
+
+func (s *KeyStore) Jwks(ctx context.Context) (domain.Jwks, error) {
+	keys, err := s.keySet(ctx)
+	if err != nil {
+		return domain.Jwks{}, err
+	}
+	return keys.Jwks(), nil
+}
+
+// Reset makes the store reload keys on the next call
+func (s *KeyStore) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.keys = nil
+}
+
+func (s *KeyStore) keySet(ctx context.Context) (*KeySet, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.keys != nil && time.Since(s.loadedAt) < keysReloadInterval {
+		return s.keys, nil
+	}
+	return s.reload(ctx)
+}
+
+func (s *KeyStore) reloadForUnknownKey(ctx context.Context) (*KeySet, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.keys != nil && time.Since(s.unknownKeyLoadAt) < unknownKeyReloadInterval {
+		return s.keys, nil
+	}
+	s.unknownKeyLoadAt = time.Now()
+	return s.reload(ctx)
+}
+
+func (s *KeyStore) reload(ctx context.Context) (*KeySet, error) {
+	keys, err := s.load(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "load signing keys")
+	}
+	s.keys = keys
+	s.loadedAt = time.Now()
+	return keys, nil
+}
+
+func (s *KeyStore) load(ctx context.Context) (*KeySet, error) {
+	signingKeys, err := s.repo.GetNotRetiredSigningKeys(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get not retired signing keys")
+	}
+
+	var signing *Key
+	keys := make([]*Key, 0, len(signingKeys))
+	for _, signingKey := range signingKeys {
+		privateKey, err := s.cipher.Decrypt(signingKey.PrivateKey)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "decrypt key %s", signingKey.Id)
+		}
+		key, err := ParseKey(signingKey.Id, privateKey)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "parse key %s", signingKey.Id)
+		}
+		if key.Algorithm() != signingKey.Algorithm {
+			return nil, errors.Errorf("key %s algorithm %s doesn't match %s", signingKey.Id, key.Algorithm(), signingKey.Algorithm)
+		}
+
+		keys = append(keys, key)
+		if signingKey.Status == entity.SigningKeyStatusActive {
+			signing = key
+		}
+	}
+	return NewKeySet(signing, keys), nil
+}
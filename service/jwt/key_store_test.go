@@ -0,0 +1,115 @@
+package jwt_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"isp-system-service/entity"
+	"isp-system-service/service/jwt"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher_EncryptDecrypt(t *testing.T) {
+	t.Parallel()
+
+	cipher, err := jwt.NewCipher("secret")
+	require.NoError(t, err)
+	encrypted, err := cipher.Encrypt("private key")
+	require.NoError(t, err)
+	require.NotContains(t, encrypted, "private key")
+
+	decrypted, err := cipher.Decrypt(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, "private key", decrypted)
+
+	other, err := jwt.NewCipher("other_secret")
+	require.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	require.Error(t, err)
+
+	empty, err := jwt.NewCipher("")
+	require.NoError(t, err)
+	require.False(t, empty.HasSecret())
+	_, err = empty.Encrypt("private key")
+	require.ErrorIs(t, err, jwt.ErrNoEncryptionSecret)
+	_, err = empty.Decrypt(encrypted)
+	require.ErrorIs(t, err, jwt.ErrNoEncryptionSecret)
+}
+
+func TestKeyStore_Rotation(t *testing.T) {
+	t.Parallel()
+
+	cipher, err := jwt.NewCipher("secret")
+	require.NoError(t, err)
+	firstKey, firstPem, err := jwt.GenerateKey("first", jwt.AlgorithmEdDsa)
+	require.NoError(t, err)
+	secondKey, secondPem, err := jwt.GenerateKey("second", jwt.AlgorithmEdDsa)
+	require.NoError(t, err)
+
+	repo := &keyRepo{}
+	store := jwt.NewKeyStore(repo, cipher)
+
+	_, err = store.Sign(t.Context(), jwt.Claims{Id: "jti"})
+	require.ErrorIs(t, err, jwt.ErrNoSigningKey)
+
+	repo.set(signingKey(t, cipher, firstKey, firstPem, entity.SigningKeyStatusActive))
+	store.Reset()
+	firstToken, err := store.Sign(t.Context(), jwt.Claims{Id: "first"})
+	require.NoError(t, err)
+
+	// other instance rotated keys, the token signed with the new key causes reload
+	repo.set(
+		signingKey(t, cipher, firstKey, firstPem, entity.SigningKeyStatusRetiring),
+		signingKey(t, cipher, secondKey, secondPem, entity.SigningKeyStatusActive),
+	)
+	secondToken, err := jwt.NewKeySet(secondKey, []*jwt.Key{secondKey}).Sign(jwt.Claims{Id: "second"})
+	require.NoError(t, err)
+
+	claims, err := store.Verify(t.Context(), secondToken)
+	require.NoError(t, err)
+	require.Equal(t, "second", claims.Id)
+	claims, err = store.Verify(t.Context(), firstToken)
+	require.NoError(t, err)
+	require.Equal(t, "first", claims.Id)
+
+	jwks, err := store.Jwks(t.Context())
+	require.NoError(t, err)
+	require.Len(t, jwks.Keys, 2)
+
+	repo.set(signingKey(t, cipher, secondKey, secondPem, entity.SigningKeyStatusActive))
+	store.Reset()
+	_, err = store.Verify(t.Context(), firstToken)
+	require.ErrorIs(t, err, jwt.ErrInvalidToken)
+}
+
+type keyRepo struct {
+	lock sync.Mutex
+	keys []entity.SigningKey
+}
+
+func (r *keyRepo) set(keys ...entity.SigningKey) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.keys = keys
+}
+
+func (r *keyRepo) GetNotRetiredSigningKeys(_ context.Context) ([]entity.SigningKey, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.keys, nil
+}
+
+func signingKey(t *testing.T, cipher jwt.Cipher, key *jwt.Key, pem string, status string) entity.SigningKey {
+	t.Helper()
+
+	encrypted, err := cipher.Encrypt(pem)
+	require.NoError(t, err)
+	return entity.SigningKey{
+		Id:         key.Id(),
+		Algorithm:  key.Algorithm(),
+		PrivateKey: encrypted,
+		Status:     status,
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"slices"
+	"time"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/json"
+)
+
+const (
+	defaultListLimit = 100
+)
+
+var (
+	listSortFields = []string{domain.ListSortId, domain.ListSortName, domain.ListSortCreatedAt}
+)
+
+// listCursor is the position after the last item of the page, clients get it as an opaque string
+type listCursor[ID int | string] struct {
+	SortField     string
+	SortDirection string
+	Id            ID
+	Name          string     `json:",omitempty"`
+	CreatedAt     *time.Time `json:",omitempty"`
+}
+
+// listFilter converts the page request to the filter, the sort is one of sortFields,
+// the cursor must be issued for the same sort
+func listFilter[ID int | string](req domain.ListRequest, sortFields []string) (entity.ListFilter, error) {
+	req = listSort(req)
+	if !slices.Contains(sortFields, req.SortField) {
+		return entity.ListFilter{}, errors.WithMessagef(domain.ErrInvalidListRequest, "sorting by %s is not supported", req.SortField)
+	}
+
+	filter := entity.ListFilter{
+		Search:         req.Search,
+		Types:          req.Types,
+		AppGroupIdList: req.AppGroupIdList,
+		SortField:      req.SortField,
+		Descending:     req.SortDirection == domain.ListSortDesc,
+		Limit:          req.Limit,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+	if req.CreatedFrom != nil {
+		filter.CreatedFrom = sql.NullTime{Time: req.CreatedFrom.UTC(), Valid: true}
+	}
+	if req.CreatedTo != nil {
+		filter.CreatedTo = sql.NullTime{Time: req.CreatedTo.UTC(), Valid: true}
+	}
+	if req.Cursor == "" {
+		return filter, nil
+	}
+
+	cursor := listCursor[ID]{}
+	value, err := base64.RawURLEncoding.DecodeString(req.Cursor)
+	if err == nil {
+		err = json.Unmarshal(value, &cursor)
+	}
+	if err != nil {
+		return entity.ListFilter{}, errors.WithMessage(domain.ErrInvalidListRequest, "malformed cursor")
+	}
+	if cursor.SortField != req.SortField || cursor.SortDirection != req.SortDirection {
+		return entity.ListFilter{}, errors.WithMessage(domain.ErrInvalidListRequest, "cursor is issued for another sort")
+	}
+	switch {
+	case req.SortField == domain.ListSortName:
+		filter.After = []any{cursor.Name, cursor.Id}
+	case req.SortField == domain.ListSortCreatedAt && cursor.CreatedAt != nil:
+		filter.After = []any{cursor.CreatedAt.UTC(), cursor.Id}
+	case req.SortField == domain.ListSortId:
+		filter.After = []any{cursor.Id}
+	default:
+		return entity.ListFilter{}, errors.WithMessage(domain.ErrInvalidListRequest, "malformed cursor")
+	}
+	return filter, nil
+}
+
+// listNextCursor returns the cursor after the last item of the page
+func listNextCursor[ID int | string](req domain.ListRequest, id ID, name string, createdAt time.Time) (string, error) {
+	req = listSort(req)
+	cursor := listCursor[ID]{
+		SortField:     req.SortField,
+		SortDirection: req.SortDirection,
+		Id:            id,
+	}
+	switch req.SortField {
+	case domain.ListSortName:
+		cursor.Name = name
+	case domain.ListSortCreatedAt:
+		cursor.CreatedAt = &createdAt
+	}
+
+	value, err := json.Marshal(cursor)
+	if err != nil {
+		return "", errors.WithMessage(err, "marshal list cursor")
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+// listSort fills the default sort: from the latest created items
+func listSort(req domain.ListRequest) domain.ListRequest {
+	if req.SortField == "" {
+		req.SortField = domain.ListSortCreatedAt
+	}
+	if req.SortDirection == "" {
+		req.SortDirection = domain.ListSortDesc
+	}
+	return req
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+	"isp-system-service/service/acl"
+
+	"github.com/pkg/errors"
+)
+
+type RateLimitRepo interface {
+	GetRateLimitsByAppId(ctx context.Context, appId int) ([]entity.RateLimit, error)
+	GetRateLimitsByAppGroupId(ctx context.Context, appGroupId int) ([]entity.RateLimit, error)
+	GetEffectiveRateLimits(ctx context.Context, appId int) ([]entity.RateLimit, error)
+}
+
+type RateLimitTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetRateLimitsByAppId(ctx context.Context, appId int) ([]entity.RateLimit, error)
+	GetRateLimitsByAppGroupId(ctx context.Context, appGroupId int) ([]entity.RateLimit, error)
+	GetRateLimitsByIdList(ctx context.Context, idList []int) ([]entity.RateLimit, error)
+	UpsertRateLimit(ctx context.Context, rateLimit entity.RateLimit) (*entity.RateLimit, error)
+	DeleteRateLimits(ctx context.Context, idList []int) (int, error)
+}
+
+type RateLimitTxRunner interface {
+	RateLimitTx(ctx context.Context, tx func(ctx context.Context, tx RateLimitTx) error) error
+}
+
+type RateLimit struct {
+	txRunner      RateLimitTxRunner
+	rateLimitRepo RateLimitRepo
+	appRepo       ApplicationRepo
+	appGroupRepo  AppGroupRepo
+	invalidator   CacheInvalidator
+}
+
+func NewRateLimit(
+	txRunner RateLimitTxRunner,
+	rateLimitRepo RateLimitRepo,
+	appRepo ApplicationRepo,
+	appGroupRepo AppGroupRepo,
+	invalidator CacheInvalidator,
+) RateLimit {
+	return RateLimit{
+		txRunner:      txRunner,
+		rateLimitRepo: rateLimitRepo,
+		appRepo:       appRepo,
+		appGroupRepo:  appGroupRepo,
+		invalidator:   invalidator,
+	}
+}
+
+func (s RateLimit) GetByAppId(ctx context.Context, appId int) ([]domain.RateLimit, error) {
+	rateLimits, err := s.rateLimitRepo.GetRateLimitsByAppId(ctx, appId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get rate limits by app id")
+	}
+	return convertRateLimits(rateLimits), nil
+}
+
+func (s RateLimit) GetByAppGroupId(ctx context.Context, appGroupId int) ([]domain.RateLimit, error) {
+	rateLimits, err := s.rateLimitRepo.GetRateLimitsByAppGroupId(ctx, appGroupId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get rate limits by app group id")
+	}
+	return convertRateLimits(rateLimits), nil
+}
+
+// GetEffective returns policies applied to the application, the same policies are returned with auth data
+func (s RateLimit) GetEffective(ctx context.Context, appId int) ([]domain.RateLimit, error) {
+	rateLimits, err := s.rateLimitRepo.GetEffectiveRateLimits(ctx, appId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get effective rate limits")
+	}
+	return convertRateLimits(rateLimits), nil
+}
+
+// Set creates or replaces the policy of the application or the application group for the method
+func (s RateLimit) Set(ctx context.Context, req domain.SetRateLimitRequest) (*domain.RateLimit, error) {
+	if !acl.IsValidPattern(req.Method) {
+		return nil, domain.ErrInvalidMethodPattern
+	}
+
+	rateLimit := entity.RateLimit{
+		Method:            req.Method,
+		RequestsPerSecond: req.RequestsPerSecond,
+		RequestsPerMinute: req.RequestsPerMinute,
+		Burst:             req.Burst,
+	}
+	var event domain.CacheInvalidation
+	if req.AppId != 0 {
+		_, err := s.appRepo.GetApplicationById(ctx, req.AppId)
+		if err != nil {
+			return nil, errors.WithMessage(err, "get application by id")
+		}
+		rateLimit.AppId = sql.NullInt64{Int64: int64(req.AppId), Valid: true}
+		event.AppIds = []int{req.AppId}
+	} else {
+		_, err := s.appGroupRepo.GetAppGroupById(ctx, req.AppGroupId)
+		if err != nil {
+			return nil, errors.WithMessage(err, "get app group by id")
+		}
+		rateLimit.AppGroupId = sql.NullInt64{Int64: int64(req.AppGroupId), Valid: true}
+		// auth data is cached by application, so policies of the group invalidate the whole cache
+		event.All = true
+	}
+
+	var result *entity.RateLimit
+	err := s.txRunner.RateLimitTx(ctx, func(ctx context.Context, tx RateLimitTx) error {
+		before, err := s.currentRateLimit(ctx, tx, rateLimit)
+		if err != nil {
+			return err
+		}
+
+		result, err = tx.UpsertRateLimit(ctx, rateLimit)
+		if err != nil {
+			return errors.WithMessage(err, "upsert rate limit")
+		}
+
+		err = WriteAuditEvent(ctx, tx, entity.AuditEntityRateLimit, "set", result.Id, before, convertRateLimit(*result))
+		if err != nil {
+			return err
+		}
+
+		return writeRateLimitChangeEvent(ctx, tx, *result)
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction rate limit set")
+	}
+	s.invalidator.Invalidate(ctx, event)
+
+	converted := convertRateLimit(*result)
+	return &converted, nil
+}
+
+func (s RateLimit) DeleteList(ctx context.Context, req domain.IdListRequest) (*domain.DeleteResponse, error) {
+	deleted := 0
+	err := s.txRunner.RateLimitTx(ctx, func(ctx context.Context, tx RateLimitTx) error {
+		rateLimits, err := tx.GetRateLimitsByIdList(ctx, req.IdList)
+		if err != nil {
+			return errors.WithMessage(err, "get rate limits by id list")
+		}
+
+		deleted, err = tx.DeleteRateLimits(ctx, req.IdList)
+		if err != nil {
+			return errors.WithMessage(err, "delete rate limits")
+		}
+
+		for _, rateLimit := range rateLimits {
+			err = WriteAuditEvent(ctx, tx, entity.AuditEntityRateLimit, "delete", rateLimit.Id, convertRateLimit(rateLimit), nil)
+			if err != nil {
+				return err
+			}
+			err = writeRateLimitChangeEvent(ctx, tx, rateLimit)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction rate limit delete")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
+
+	return &domain.DeleteResponse{
+		Deleted: deleted,
+	}, nil
+}
+
+// currentRateLimit returns the policy of the same owner and method which is replaced by the upsert,
+// nil if there is no such policy
+func (s RateLimit) currentRateLimit(ctx context.Context, tx RateLimitTx, rateLimit entity.RateLimit) (any, error) {
+	var (
+		existing []entity.RateLimit
+		err      error
+	)
+	if rateLimit.AppId.Valid {
+		existing, err = tx.GetRateLimitsByAppId(ctx, int(rateLimit.AppId.Int64))
+	} else {
+		existing, err = tx.GetRateLimitsByAppGroupId(ctx, int(rateLimit.AppGroupId.Int64))
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "get rate limits by owner")
+	}
+	for _, e := range existing {
+		if e.Method == rateLimit.Method {
+			return convertRateLimit(e), nil
+		}
+	}
+	return nil, nil
+}
+
+// writeRateLimitChangeEvent publishes the change of the policy of the application or the application group
+func writeRateLimitChangeEvent(ctx context.Context, tx RateLimitTx, rateLimit entity.RateLimit) error {
+	return WriteChangeEvent(ctx, tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{
+		AppId:      int(rateLimit.AppId.Int64),
+		AppGroupId: int(rateLimit.AppGroupId.Int64),
+	})
+}
+
+func convertRateLimits(rateLimits []entity.RateLimit) []domain.RateLimit {
+	result := make([]domain.RateLimit, len(rateLimits))
+	for i, rateLimit := range rateLimits {
+		result[i] = convertRateLimit(rateLimit)
+	}
+	return result
+}
+
+func convertRateLimit(rateLimit entity.RateLimit) domain.RateLimit {
+	return domain.RateLimit{
+		Id:                rateLimit.Id,
+		AppId:             int(rateLimit.AppId.Int64),
+		AppGroupId:        int(rateLimit.AppGroupId.Int64),
+		Method:            rateLimit.Method,
+		RequestsPerSecond: rateLimit.RequestsPerSecond,
+		RequestsPerMinute: rateLimit.RequestsPerMinute,
+		Burst:             rateLimit.Burst,
+		CreatedAt:         rateLimit.CreatedAt,
+		UpdatedAt:         rateLimit.UpdatedAt,
+	}
+}
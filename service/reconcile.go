@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/validator"
+)
+
+type ReconcileTx interface {
+	RegistryTx
+	GetTokenByAppIdList(ctx context.Context, appIdList []int) ([]entity.Token, error)
+	MarkManagedAppGroups(ctx context.Context, idList []int) error
+	MarkManagedApplications(ctx context.Context, idList []int) error
+	MarkManagedTokens(ctx context.Context, idList []string) error
+}
+
+// Reconcile brings groups, applications and their access lists declared in the remote config to the desired state
+// in the transaction of the caller and marks them as managed by config.
+// Managed applications and empty managed groups which are no longer declared are deleted,
+// changes of managed objects made bypassing the config are reverted and returned as drift
+func (s Registry) Reconcile(ctx context.Context, tx ReconcileTx, desired domain.DesiredState) (*domain.ReconcileResult, error) {
+	err := validateDesiredState(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := readRegistry(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	managedKeys := state.managedKeys()
+
+	sync := &registrySync{tx: tx, state: state}
+	keptAppGroups := make(map[int]bool)
+	keptApps := make(map[int]bool)
+	appGroupIdList := make([]int, 0)
+	appIdList := make([]int, 0)
+	for _, appGroup := range desired.AppGroups {
+		domainId, err := sync.domainId(ctx, appGroup.Domain)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "domain %s", appGroup.Domain)
+		}
+
+		appGroupId, err := sync.syncAppGroup(ctx, appGroup.Domain, domainId, domain.RegistryAppGroup{
+			Name:        appGroup.Name,
+			Description: appGroup.Description,
+			AccessList:  appGroup.AccessList,
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "application group %s/%s", appGroup.Domain, appGroup.Name)
+		}
+		keptAppGroups[appGroupId] = true
+		appGroupIdList = append(appGroupIdList, appGroupId)
+
+		for _, app := range appGroup.Applications {
+			err = sync.syncApplication(ctx, appGroupId, domain.RegistryApplication{
+				Id:          app.Id,
+				Name:        app.Name,
+				Description: app.Description,
+				Type:        app.Type,
+				AccessList:  app.AccessList,
+			})
+			if err != nil {
+				return nil, errors.WithMessagef(err, "application %d", app.Id)
+			}
+			keptApps[app.Id] = true
+			appIdList = append(appIdList, app.Id)
+		}
+	}
+
+	result := &domain.ReconcileResult{
+		Drift:              make([]domain.RegistryChange, 0),
+		MissingTokenIdList: make([]string, 0),
+	}
+	for _, change := range sync.changes {
+		if managedKeys[change.EntityType+" "+change.Key] {
+			result.Drift = append(result.Drift, change)
+		}
+	}
+
+	err = s.pruneManaged(ctx, tx, sync, keptAppGroups, keptApps)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := tx.GetTokenByAppIdList(ctx, appIdList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get tokens by app_id list")
+	}
+	tokenIdList := make([]string, 0)
+	for _, appGroup := range desired.AppGroups {
+		for _, app := range appGroup.Applications {
+			for _, tokenId := range app.TokenIdList {
+				issued := slices.ContainsFunc(tokens, func(token entity.Token) bool {
+					return token.Id == tokenId && token.AppId == app.Id
+				})
+				if !issued {
+					result.MissingTokenIdList = append(result.MissingTokenIdList, tokenId)
+					continue
+				}
+				tokenIdList = append(tokenIdList, tokenId)
+			}
+		}
+	}
+
+	err = tx.MarkManagedAppGroups(ctx, appGroupIdList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "mark managed application groups")
+	}
+	err = tx.MarkManagedApplications(ctx, appIdList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "mark managed applications")
+	}
+	err = tx.MarkManagedTokens(ctx, tokenIdList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "mark managed tokens")
+	}
+
+	result.Changes = sync.changes
+	if result.Changes == nil {
+		result.Changes = make([]domain.RegistryChange, 0)
+	}
+	return result, nil
+}
+
+// pruneManaged deletes managed applications which are no longer declared,
+// managed groups which are no longer declared are deleted only if they have no applications left,
+// otherwise they are just released from the config
+func (s Registry) pruneManaged(
+	ctx context.Context,
+	tx ReconcileTx,
+	sync *registrySync,
+	keptAppGroups map[int]bool,
+	keptApps map[int]bool,
+) error {
+	apps := make([]entity.Application, 0)
+	for _, app := range sync.state.apps {
+		if app.ManagedByConfig && !keptApps[app.Id] {
+			apps = append(apps, app)
+		}
+	}
+	err := sync.deleteApplications(ctx, apps)
+	if err != nil {
+		return err
+	}
+
+	released := make([]entity.AppGroup, 0)
+	releasedIdList := make([]int, 0)
+	for _, appGroup := range sync.state.appGroups {
+		if appGroup.ManagedByConfig && !keptAppGroups[appGroup.Id] {
+			released = append(released, appGroup)
+			releasedIdList = append(releasedIdList, appGroup.Id)
+		}
+	}
+	if len(released) == 0 {
+		return nil
+	}
+	remainingApps, err := tx.GetApplicationByAppGroupIdList(ctx, releasedIdList)
+	if err != nil {
+		return errors.WithMessage(err, "get applications by app_group_id")
+	}
+	appGroups := make([]entity.AppGroup, 0)
+	for _, appGroup := range released {
+		hasApps := slices.ContainsFunc(remainingApps, func(app entity.Application) bool {
+			return app.ApplicationGroupId == appGroup.Id
+		})
+		if !hasApps {
+			appGroups = append(appGroups, appGroup)
+		}
+	}
+	return sync.deleteAppGroups(ctx, appGroups)
+}
+
+// managedKeys returns the keys of changes of objects managed by config
+func (s *registryState) managedKeys() map[string]bool {
+	result := make(map[string]bool)
+	for _, appGroup := range s.appGroups {
+		if !appGroup.ManagedByConfig {
+			continue
+		}
+		key := s.appGroupKey(appGroup)
+		result[entity.AuditEntityAppGroup+" "+key] = true
+		result[entity.AuditEntityAccessList+" "+entity.AuditEntityAppGroup+":"+key] = true
+	}
+	for _, app := range s.apps {
+		if !app.ManagedByConfig {
+			continue
+		}
+		key := strconv.Itoa(app.Id)
+		result[entity.AuditEntityApplication+" "+key] = true
+		result[entity.AuditEntityAccessList+" "+entity.AuditEntityApplication+":"+key] = true
+	}
+	return result
+}
+
+func validateDesiredState(desired domain.DesiredState) error {
+	err := validator.Default.ValidateToError(desired)
+	if err != nil {
+		return errors.WithMessage(domain.ErrInvalidRegistryDocument, err.Error())
+	}
+
+	appGroupKeys := make(map[string]bool)
+	appIds := make(map[int]bool)
+	for _, appGroup := range desired.AppGroups {
+		key := appGroup.Domain + "/" + appGroup.Name
+		if appGroupKeys[key] {
+			return errors.WithMessagef(domain.ErrInvalidRegistryDocument, "duplicate application group %s", key)
+		}
+		appGroupKeys[key] = true
+		err = validateRegistryAccessList(appGroup.AccessList)
+		if err != nil {
+			return errors.WithMessagef(err, "application group %s", key)
+		}
+
+		for _, app := range appGroup.Applications {
+			if appIds[app.Id] {
+				return errors.WithMessagef(domain.ErrInvalidRegistryDocument, "duplicate application %d", app.Id)
+			}
+			appIds[app.Id] = true
+			err = validateRegistryAccessList(app.AccessList)
+			if err != nil {
+				return errors.WithMessagef(err, "application %d", app.Id)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,770 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+	"isp-system-service/service/acl"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/validator"
+	"gopkg.in/yaml.v3"
+)
+
+// errRegistryDryRun rolls back the import transaction in the dry-run mode
+var errRegistryDryRun = errors.New("registry import dry run")
+
+type RegistryTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetDomainBySystemId(ctx context.Context, systemId int) ([]entity.Domain, error)
+	CreateDomain(ctx context.Context, name string, desc string, systemId int) (*entity.Domain, error)
+	UpdateDomain(ctx context.Context, id int, name string, description string) (*entity.Domain, error)
+	DeleteDomain(ctx context.Context, idList []int) (int, error)
+	GetAppGroupByDomainId(ctx context.Context, domainIdList []int) ([]entity.AppGroup, error)
+	CreateAppGroup(ctx context.Context, name string, desc string, domainId int) (*entity.AppGroup, error)
+	UpdateAppGroup(ctx context.Context, id int, name string, description string) (*entity.AppGroup, error)
+	DeleteAppGroup(ctx context.Context, idList []int) (int, error)
+	GetApplicationByAppGroupIdList(ctx context.Context, appGroupIdList []int) ([]entity.Application, error)
+	CreateApplication(ctx context.Context, id int, name string, desc string, appGroupId int, appType string) (*entity.Application, error)
+	UpdateApplicationDefinition(
+		ctx context.Context,
+		id int,
+		name string,
+		description string,
+		appGroupId int,
+		appType string,
+	) (*entity.Application, error)
+	DeleteApplicationByIdList(ctx context.Context, idList []int) (int, error)
+	GetTokenByAppIdList(ctx context.Context, appIdList []int) ([]entity.Token, error)
+	GetAccessListByAppIdList(ctx context.Context, appIdList []int) ([]entity.AccessList, error)
+	DeleteAccessListByAppId(ctx context.Context, appId int) ([]entity.AccessList, error)
+	InsertArrayAccessList(ctx context.Context, entity []entity.AccessList) error
+	GetAppGroupAccessList(ctx context.Context, appGroupId int) ([]entity.AccessRule, error)
+	UpsertAppGroupAccessList(ctx context.Context, appGroupId int, methods []entity.AccessRule) error
+	DeleteAppGroupAccessList(ctx context.Context, appGroupId int) error
+	GetDomainAccessList(ctx context.Context, domainId int) ([]entity.AccessRule, error)
+	UpsertDomainAccessList(ctx context.Context, domainId int, methods []entity.AccessRule) error
+	DeleteDomainAccessList(ctx context.Context, domainId int) error
+}
+
+type RegistryTxRunner interface {
+	RegistryTx(ctx context.Context, tx func(ctx context.Context, tx RegistryTx) error) error
+}
+
+// Registry exports the tree of the system with access lists as a document
+// and applies such documents declaratively
+type Registry struct {
+	txRunner    RegistryTxRunner
+	invalidator CacheInvalidator
+}
+
+func NewRegistry(txRunner RegistryTxRunner, invalidator CacheInvalidator) Registry {
+	return Registry{
+		txRunner:    txRunner,
+		invalidator: invalidator,
+	}
+}
+
+func (s Registry) Export(ctx context.Context, req domain.RegistryExportRequest) (*domain.RegistryExportResponse, error) {
+	var document *domain.RegistryDocument
+	err := s.txRunner.RegistryTx(ctx, func(ctx context.Context, tx RegistryTx) error {
+		state, err := readRegistry(ctx, tx)
+		if err != nil {
+			return err
+		}
+		document = state.document()
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction registry export")
+	}
+
+	if req.Format != domain.RegistryFormatYaml {
+		return &domain.RegistryExportResponse{Document: document}, nil
+	}
+	content, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal registry yaml")
+	}
+	return &domain.RegistryExportResponse{Yaml: string(content)}, nil
+}
+
+// Import makes the registry of the system match the document: missing entities are created,
+// changed ones are updated and entities absent in the document are deleted, all in one transaction.
+// In the dry-run mode the transaction is rolled back and only the changes are returned
+func (s Registry) Import(ctx context.Context, req domain.RegistryImportRequest) (*domain.RegistryImportResponse, error) {
+	document, err := parseRegistryDocument(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []domain.RegistryChange
+	err = s.txRunner.RegistryTx(ctx, func(ctx context.Context, tx RegistryTx) error {
+		state, err := readRegistry(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		sync := &registrySync{tx: tx, state: state, protectManaged: true}
+		err = sync.apply(ctx, *document)
+		if err != nil {
+			return err
+		}
+		changes = sync.changes
+
+		if req.DryRun {
+			return errRegistryDryRun
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return WriteAuditEvent(
+			ctx, tx, entity.AuditEntityRegistry, "import",
+			domain.DefaultSystemId, nil, changes,
+		)
+	})
+	if err != nil && !errors.Is(err, errRegistryDryRun) {
+		return nil, errors.WithMessage(err, "transaction registry import")
+	}
+	if !req.DryRun && len(changes) > 0 {
+		s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
+	}
+
+	if changes == nil {
+		changes = make([]domain.RegistryChange, 0)
+	}
+	return &domain.RegistryImportResponse{
+		DryRun:  req.DryRun,
+		Changes: changes,
+	}, nil
+}
+
+func parseRegistryDocument(req domain.RegistryImportRequest) (*domain.RegistryDocument, error) {
+	document := req.Document
+	switch {
+	case document != nil && req.Yaml != "":
+		return nil, errors.WithMessage(domain.ErrInvalidRegistryDocument, "both document and yaml are passed")
+	case req.Yaml != "":
+		document = &domain.RegistryDocument{}
+		err := yaml.Unmarshal([]byte(req.Yaml), document)
+		if err != nil {
+			return nil, errors.WithMessagef(domain.ErrInvalidRegistryDocument, "unmarshal yaml: %v", err)
+		}
+	case document == nil:
+		return nil, errors.WithMessage(domain.ErrInvalidRegistryDocument, "document or yaml is required")
+	}
+
+	err := validator.Default.ValidateToError(document)
+	if err != nil {
+		return nil, errors.WithMessage(domain.ErrInvalidRegistryDocument, err.Error())
+	}
+	if document.Version != domain.RegistryDocumentVersion {
+		return nil, errors.WithMessagef(domain.ErrInvalidRegistryDocument, "unsupported version %d", document.Version)
+	}
+
+	domainNames := make(map[string]bool)
+	appIds := make(map[int]bool)
+	for _, d := range document.Domains {
+		if domainNames[d.Name] {
+			return nil, errors.WithMessagef(domain.ErrInvalidRegistryDocument, "duplicate domain %s", d.Name)
+		}
+		domainNames[d.Name] = true
+		err = validateRegistryAccessList(d.AccessList)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "domain %s", d.Name)
+		}
+
+		appGroupNames := make(map[string]bool)
+		for _, appGroup := range d.AppGroups {
+			if appGroupNames[appGroup.Name] {
+				return nil, errors.WithMessagef(domain.ErrInvalidRegistryDocument, "duplicate application group %s/%s", d.Name, appGroup.Name)
+			}
+			appGroupNames[appGroup.Name] = true
+			err = validateRegistryAccessList(appGroup.AccessList)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "application group %s/%s", d.Name, appGroup.Name)
+			}
+
+			for _, app := range appGroup.Applications {
+				if appIds[app.Id] {
+					return nil, errors.WithMessagef(domain.ErrInvalidRegistryDocument, "duplicate application %d", app.Id)
+				}
+				appIds[app.Id] = true
+				err = validateRegistryAccessList(app.AccessList)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "application %d", app.Id)
+				}
+			}
+		}
+	}
+	return document, nil
+}
+
+func validateRegistryAccessList(accessList []domain.RegistryAccessRule) error {
+	methods := make(map[domain.Method]bool, len(accessList))
+	for _, rule := range accessList {
+		if !acl.IsValidPattern(rule.Method) {
+			return errors.WithMessagef(domain.ErrInvalidMethodPattern, "method %s", rule.Method)
+		}
+		method := domain.Method{HttpMethod: rule.HttpMethod, Method: rule.Method}
+		if methods[method] {
+			return errors.WithMessagef(domain.ErrInvalidRegistryDocument, "duplicate method %s %s", rule.HttpMethod, rule.Method)
+		}
+		methods[method] = true
+	}
+	return nil
+}
+
+type registryState struct {
+	domains             []entity.Domain
+	appGroups           []entity.AppGroup
+	apps                []entity.Application
+	appAccessLists      map[int][]domain.RegistryAccessRule
+	appGroupAccessLists map[int][]domain.RegistryAccessRule
+	domainAccessLists   map[int][]domain.RegistryAccessRule
+}
+
+func readRegistry(ctx context.Context, tx RegistryTx) (*registryState, error) {
+	state := &registryState{
+		appAccessLists:      make(map[int][]domain.RegistryAccessRule),
+		appGroupAccessLists: make(map[int][]domain.RegistryAccessRule),
+		domainAccessLists:   make(map[int][]domain.RegistryAccessRule),
+	}
+
+	var err error
+	state.domains, err = tx.GetDomainBySystemId(ctx, domain.DefaultSystemId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get domains by system_id")
+	}
+	domainIdList := make([]int, len(state.domains))
+	for i, d := range state.domains {
+		domainIdList[i] = d.Id
+		rules, err := tx.GetDomainAccessList(ctx, d.Id)
+		if err != nil {
+			return nil, errors.WithMessage(err, "get access list by domain_id")
+		}
+		state.domainAccessLists[d.Id] = registryRules(rules)
+	}
+
+	state.appGroups, err = tx.GetAppGroupByDomainId(ctx, domainIdList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get application groups by domain_id")
+	}
+	appGroupIdList := make([]int, len(state.appGroups))
+	for i, appGroup := range state.appGroups {
+		appGroupIdList[i] = appGroup.Id
+		rules, err := tx.GetAppGroupAccessList(ctx, appGroup.Id)
+		if err != nil {
+			return nil, errors.WithMessage(err, "get access list by app_group_id")
+		}
+		state.appGroupAccessLists[appGroup.Id] = registryRules(rules)
+	}
+
+	state.apps, err = tx.GetApplicationByAppGroupIdList(ctx, appGroupIdList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get applications by app_group_id")
+	}
+	appIdList := make([]int, len(state.apps))
+	for i, app := range state.apps {
+		appIdList[i] = app.Id
+	}
+	accessList, err := tx.GetAccessListByAppIdList(ctx, appIdList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get access list by app_id list")
+	}
+	for _, access := range accessList {
+		state.appAccessLists[access.AppId] = append(state.appAccessLists[access.AppId], domain.RegistryAccessRule{
+			HttpMethod: access.HttpMethod,
+			Method:     access.Method,
+			Value:      access.Value,
+		})
+	}
+	for appId, rules := range state.appAccessLists {
+		state.appAccessLists[appId] = sortRegistryRules(rules)
+	}
+
+	return state, nil
+}
+
+// document orders entities by names and applications by ids, so documents of environments can be compared
+func (s *registryState) document() *domain.RegistryDocument {
+	result := &domain.RegistryDocument{
+		Version: domain.RegistryDocumentVersion,
+		Domains: make([]domain.RegistryDomain, 0, len(s.domains)),
+	}
+	for _, d := range s.domains {
+		registryDomain := domain.RegistryDomain{
+			Name:        d.Name,
+			Description: domainDescription(d),
+			AccessList:  s.domainAccessLists[d.Id],
+		}
+		for _, appGroup := range s.appGroups {
+			if appGroup.DomainId != d.Id {
+				continue
+			}
+			registryAppGroup := domain.RegistryAppGroup{
+				Name:        appGroup.Name,
+				Description: appGroup.Description.String,
+				AccessList:  s.appGroupAccessLists[appGroup.Id],
+			}
+			for _, app := range s.apps {
+				if app.ApplicationGroupId != appGroup.Id {
+					continue
+				}
+				registryAppGroup.Applications = append(registryAppGroup.Applications, domain.RegistryApplication{
+					Id:          app.Id,
+					Name:        app.Name,
+					Description: app.Description.String,
+					Type:        app.Type,
+					AccessList:  s.appAccessLists[app.Id],
+				})
+			}
+			slices.SortFunc(registryAppGroup.Applications, func(a, b domain.RegistryApplication) int {
+				return cmp.Compare(a.Id, b.Id)
+			})
+			registryDomain.AppGroups = append(registryDomain.AppGroups, registryAppGroup)
+		}
+		slices.SortFunc(registryDomain.AppGroups, func(a, b domain.RegistryAppGroup) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+		result.Domains = append(result.Domains, registryDomain)
+	}
+	slices.SortFunc(result.Domains, func(a, b domain.RegistryDomain) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return result
+}
+
+// appGroupKey identifies the group by the names of its domain and itself
+func (s *registryState) appGroupKey(appGroup entity.AppGroup) string {
+	for _, d := range s.domains {
+		if d.Id == appGroup.DomainId {
+			return d.Name + "/" + appGroup.Name
+		}
+	}
+	return "/" + appGroup.Name
+}
+
+// registrySync applies the document to the registry state and collects the changes,
+// objects managed by the remote config are changed only if they are not protected
+type registrySync struct {
+	tx             RegistryTx
+	state          *registryState
+	protectManaged bool
+	changes        []domain.RegistryChange
+}
+
+func (s *registrySync) apply(ctx context.Context, document domain.RegistryDocument) error {
+	keptDomains := make(map[int]bool)
+	keptAppGroups := make(map[int]bool)
+	keptApps := make(map[int]bool)
+	for _, d := range document.Domains {
+		domainId, err := s.syncDomain(ctx, d)
+		if err != nil {
+			return errors.WithMessagef(err, "domain %s", d.Name)
+		}
+		keptDomains[domainId] = true
+
+		for _, appGroup := range d.AppGroups {
+			appGroupId, err := s.syncAppGroup(ctx, d.Name, domainId, appGroup)
+			if err != nil {
+				return errors.WithMessagef(err, "application group %s/%s", d.Name, appGroup.Name)
+			}
+			keptAppGroups[appGroupId] = true
+
+			for _, app := range appGroup.Applications {
+				err = s.syncApplication(ctx, appGroupId, app)
+				if err != nil {
+					return errors.WithMessagef(err, "application %d", app.Id)
+				}
+				keptApps[app.Id] = true
+			}
+		}
+	}
+
+	return s.deleteAbsent(ctx, keptDomains, keptAppGroups, keptApps)
+}
+
+func (s *registrySync) syncDomain(ctx context.Context, d domain.RegistryDomain) (int, error) {
+	key := d.Name
+	idx := slices.IndexFunc(s.state.domains, func(e entity.Domain) bool {
+		return e.Name == d.Name
+	})
+	if idx < 0 {
+		created, err := s.tx.CreateDomain(ctx, d.Name, d.Description, domain.DefaultSystemId)
+		if err != nil {
+			return 0, errors.WithMessage(err, "create domain")
+		}
+		s.record(domain.RegistryChangeCreate, entity.AuditEntityDomain, key)
+		return created.Id, s.syncDomainAccessList(ctx, key, created.Id, d.AccessList)
+	}
+
+	existing := s.state.domains[idx]
+	if domainDescription(existing) != d.Description {
+		_, err := s.tx.UpdateDomain(ctx, existing.Id, d.Name, d.Description)
+		if err != nil {
+			return 0, errors.WithMessage(err, "update domain")
+		}
+		s.record(domain.RegistryChangeUpdate, entity.AuditEntityDomain, key, "description")
+	}
+	return existing.Id, s.syncDomainAccessList(ctx, key, existing.Id, d.AccessList)
+}
+
+func (s *registrySync) syncDomainAccessList(ctx context.Context, key string, domainId int, accessList []domain.RegistryAccessRule) error {
+	changed := s.recordAccessList(entity.AuditEntityDomain, key, s.state.domainAccessLists[domainId], accessList)
+	if !changed {
+		return nil
+	}
+
+	err := s.tx.DeleteDomainAccessList(ctx, domainId)
+	if err != nil {
+		return errors.WithMessage(err, "delete access list by domain_id")
+	}
+	if len(accessList) > 0 {
+		err = s.tx.UpsertDomainAccessList(ctx, domainId, registryAccessRules(accessList))
+		if err != nil {
+			return errors.WithMessage(err, "upsert domain access list")
+		}
+	}
+	return WriteChangeEvent(ctx, s.tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{DomainId: domainId})
+}
+
+func (s *registrySync) syncAppGroup(ctx context.Context, domainName string, domainId int, appGroup domain.RegistryAppGroup) (int, error) {
+	key := domainName + "/" + appGroup.Name
+	idx := slices.IndexFunc(s.state.appGroups, func(e entity.AppGroup) bool {
+		return e.DomainId == domainId && e.Name == appGroup.Name
+	})
+	if idx < 0 {
+		created, err := s.tx.CreateAppGroup(ctx, appGroup.Name, appGroup.Description, domainId)
+		if err != nil {
+			return 0, errors.WithMessage(err, "create application group")
+		}
+		s.record(domain.RegistryChangeCreate, entity.AuditEntityAppGroup, key)
+		return created.Id, s.syncAppGroupAccessList(ctx, key, created.Id, appGroup.AccessList)
+	}
+
+	existing := s.state.appGroups[idx]
+	if existing.Description.String != appGroup.Description {
+		err := s.checkManaged(existing.ManagedByConfig, entity.AuditEntityAppGroup, key)
+		if err != nil {
+			return 0, err
+		}
+		_, err = s.tx.UpdateAppGroup(ctx, existing.Id, appGroup.Name, appGroup.Description)
+		if err != nil {
+			return 0, errors.WithMessage(err, "update application group")
+		}
+		s.record(domain.RegistryChangeUpdate, entity.AuditEntityAppGroup, key, "description")
+	}
+	return existing.Id, s.syncAppGroupAccessList(ctx, key, existing.Id, appGroup.AccessList)
+}
+
+func (s *registrySync) syncAppGroupAccessList(ctx context.Context, key string, appGroupId int, accessList []domain.RegistryAccessRule) error {
+	changed := s.recordAccessList(entity.AuditEntityAppGroup, key, s.state.appGroupAccessLists[appGroupId], accessList)
+	if !changed {
+		return nil
+	}
+	managed := slices.ContainsFunc(s.state.appGroups, func(e entity.AppGroup) bool {
+		return e.Id == appGroupId && e.ManagedByConfig
+	})
+	err := s.checkManaged(managed, entity.AuditEntityAppGroup, key)
+	if err != nil {
+		return err
+	}
+
+	err = s.tx.DeleteAppGroupAccessList(ctx, appGroupId)
+	if err != nil {
+		return errors.WithMessage(err, "delete access list by app_group_id")
+	}
+	if len(accessList) > 0 {
+		err = s.tx.UpsertAppGroupAccessList(ctx, appGroupId, registryAccessRules(accessList))
+		if err != nil {
+			return errors.WithMessage(err, "upsert app group access list")
+		}
+	}
+	return WriteChangeEvent(ctx, s.tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{AppGroupId: appGroupId})
+}
+
+func (s *registrySync) syncApplication(ctx context.Context, appGroupId int, app domain.RegistryApplication) error {
+	key := strconv.Itoa(app.Id)
+	idx := slices.IndexFunc(s.state.apps, func(e entity.Application) bool {
+		return e.Id == app.Id
+	})
+	if idx < 0 {
+		_, err := s.tx.CreateApplication(ctx, app.Id, app.Name, app.Description, appGroupId, app.Type)
+		if err != nil {
+			return errors.WithMessage(err, "create application")
+		}
+		s.record(domain.RegistryChangeCreate, entity.AuditEntityApplication, key)
+		return s.syncAppAccessList(ctx, app.Id, app.AccessList)
+	}
+
+	existing := s.state.apps[idx]
+	fields := make([]string, 0)
+	if existing.Name != app.Name {
+		fields = append(fields, "name")
+	}
+	if existing.Description.String != app.Description {
+		fields = append(fields, "description")
+	}
+	if existing.Type != app.Type {
+		fields = append(fields, "type")
+	}
+	if existing.ApplicationGroupId != appGroupId {
+		fields = append(fields, "appGroup")
+	}
+	if len(fields) > 0 {
+		err := s.checkManaged(existing.ManagedByConfig, entity.AuditEntityApplication, key)
+		if err != nil {
+			return err
+		}
+		_, err = s.tx.UpdateApplicationDefinition(ctx, app.Id, app.Name, app.Description, appGroupId, app.Type)
+		if err != nil {
+			return errors.WithMessage(err, "update application")
+		}
+		s.record(domain.RegistryChangeUpdate, entity.AuditEntityApplication, key, fields...)
+	}
+	if existing.Name != app.Name {
+		err := WriteChangeEvent(ctx, s.tx, domain.ChangeEventApplicationRenamed, domain.ApplicationRenamed{
+			OldAppId: existing.Id,
+			AppId:    app.Id,
+			OldName:  existing.Name,
+			Name:     app.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return s.syncAppAccessList(ctx, app.Id, app.AccessList)
+}
+
+func (s *registrySync) syncAppAccessList(ctx context.Context, appId int, accessList []domain.RegistryAccessRule) error {
+	key := strconv.Itoa(appId)
+	changed := s.recordAccessList(entity.AuditEntityApplication, key, s.state.appAccessLists[appId], accessList)
+	if !changed {
+		return nil
+	}
+	managed := slices.ContainsFunc(s.state.apps, func(e entity.Application) bool {
+		return e.Id == appId && e.ManagedByConfig
+	})
+	err := s.checkManaged(managed, entity.AuditEntityApplication, key)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.tx.DeleteAccessListByAppId(ctx, appId)
+	if err != nil {
+		return errors.WithMessage(err, "delete access list by app_id")
+	}
+	if len(accessList) > 0 {
+		newAccessList := make([]entity.AccessList, len(accessList))
+		for i, rule := range accessList {
+			newAccessList[i] = entity.AccessList{
+				AppId:      appId,
+				HttpMethod: rule.HttpMethod,
+				Method:     rule.Method,
+				Value:      rule.Value,
+			}
+		}
+		err = s.tx.InsertArrayAccessList(ctx, newAccessList)
+		if err != nil {
+			return errors.WithMessage(err, "insert access list")
+		}
+	}
+	return WriteChangeEvent(ctx, s.tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{AppId: appId})
+}
+
+// deleteAbsent deletes entities of the system which are not in the document,
+// access lists and tokens of deleted entities are deleted by cascade
+func (s *registrySync) deleteAbsent(ctx context.Context, keptDomains map[int]bool, keptAppGroups map[int]bool, keptApps map[int]bool) error {
+	apps := make([]entity.Application, 0)
+	for _, app := range s.state.apps {
+		if !keptApps[app.Id] {
+			apps = append(apps, app)
+		}
+	}
+	err := s.deleteApplications(ctx, apps)
+	if err != nil {
+		return err
+	}
+
+	appGroups := make([]entity.AppGroup, 0)
+	for _, appGroup := range s.state.appGroups {
+		if !keptAppGroups[appGroup.Id] {
+			appGroups = append(appGroups, appGroup)
+		}
+	}
+	err = s.deleteAppGroups(ctx, appGroups)
+	if err != nil {
+		return err
+	}
+
+	domainIdList := make([]int, 0)
+	for _, d := range s.state.domains {
+		if keptDomains[d.Id] {
+			continue
+		}
+		domainIdList = append(domainIdList, d.Id)
+		s.record(domain.RegistryChangeDelete, entity.AuditEntityDomain, d.Name)
+	}
+	if len(domainIdList) > 0 {
+		_, err := s.tx.DeleteDomain(ctx, domainIdList)
+		if err != nil {
+			return errors.WithMessage(err, "delete domains")
+		}
+	}
+	return nil
+}
+
+func (s *registrySync) deleteApplications(ctx context.Context, apps []entity.Application) error {
+	if len(apps) == 0 {
+		return nil
+	}
+
+	appIdList := make([]int, len(apps))
+	for i, app := range apps {
+		key := strconv.Itoa(app.Id)
+		err := s.checkManaged(app.ManagedByConfig, entity.AuditEntityApplication, key)
+		if err != nil {
+			return err
+		}
+		appIdList[i] = app.Id
+		s.record(domain.RegistryChangeDelete, entity.AuditEntityApplication, key)
+	}
+	err := writeApplicationDeleteEvents(ctx, s.tx, apps)
+	if err != nil {
+		return err
+	}
+	_, err = s.tx.DeleteApplicationByIdList(ctx, appIdList)
+	if err != nil {
+		return errors.WithMessage(err, "delete applications")
+	}
+	return nil
+}
+
+func (s *registrySync) deleteAppGroups(ctx context.Context, appGroups []entity.AppGroup) error {
+	if len(appGroups) == 0 {
+		return nil
+	}
+
+	appGroupIdList := make([]int, len(appGroups))
+	for i, appGroup := range appGroups {
+		key := s.state.appGroupKey(appGroup)
+		err := s.checkManaged(appGroup.ManagedByConfig, entity.AuditEntityAppGroup, key)
+		if err != nil {
+			return err
+		}
+		appGroupIdList[i] = appGroup.Id
+		s.record(domain.RegistryChangeDelete, entity.AuditEntityAppGroup, key)
+	}
+	_, err := s.tx.DeleteAppGroup(ctx, appGroupIdList)
+	if err != nil {
+		return errors.WithMessage(err, "delete application groups")
+	}
+	return nil
+}
+
+// checkManaged rejects the change of the object declared in the desired state of the remote config,
+// only reconcile changes such objects
+func (s *registrySync) checkManaged(managed bool, entityType string, key string) error {
+	if managed && s.protectManaged {
+		return errors.WithMessagef(domain.ErrManagedByConfig, "%s %s", entityType, key)
+	}
+	return nil
+}
+
+// domainId returns the id of the domain of the system by its name, a missing domain is created
+func (s *registrySync) domainId(ctx context.Context, name string) (int, error) {
+	idx := slices.IndexFunc(s.state.domains, func(e entity.Domain) bool {
+		return e.Name == name
+	})
+	if idx >= 0 {
+		return s.state.domains[idx].Id, nil
+	}
+
+	created, err := s.tx.CreateDomain(ctx, name, "", domain.DefaultSystemId)
+	if err != nil {
+		return 0, errors.WithMessage(err, "create domain")
+	}
+	s.state.domains = append(s.state.domains, *created)
+	s.record(domain.RegistryChangeCreate, entity.AuditEntityDomain, name)
+	return created.Id, nil
+}
+
+// recordAccessList records the change of the access list if the rules differ regardless of the order
+func (s *registrySync) recordAccessList(
+	ownerType string,
+	ownerKey string,
+	before []domain.RegistryAccessRule,
+	after []domain.RegistryAccessRule,
+) bool {
+	if slices.Equal(sortRegistryRules(before), sortRegistryRules(after)) {
+		return false
+	}
+
+	action := domain.RegistryChangeUpdate
+	switch {
+	case len(before) == 0:
+		action = domain.RegistryChangeCreate
+	case len(after) == 0:
+		action = domain.RegistryChangeDelete
+	}
+	s.record(action, entity.AuditEntityAccessList, fmt.Sprintf("%s:%s", ownerType, ownerKey))
+	return true
+}
+
+func (s *registrySync) record(action string, entityType string, key string, fields ...string) {
+	s.changes = append(s.changes, domain.RegistryChange{
+		Action:     action,
+		EntityType: entityType,
+		Key:        key,
+		Fields:     fields,
+	})
+}
+
+func domainDescription(d entity.Domain) string {
+	if d.Description == nil {
+		return ""
+	}
+	return *d.Description
+}
+
+func registryRules(rules []entity.AccessRule) []domain.RegistryAccessRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	result := make([]domain.RegistryAccessRule, len(rules))
+	for i, rule := range rules {
+		result[i] = domain.RegistryAccessRule{
+			HttpMethod: rule.HttpMethod,
+			Method:     rule.Method,
+			Value:      rule.Value,
+		}
+	}
+	return sortRegistryRules(result)
+}
+
+func registryAccessRules(accessList []domain.RegistryAccessRule) []entity.AccessRule {
+	result := make([]entity.AccessRule, len(accessList))
+	for i, rule := range accessList {
+		result[i] = entity.AccessRule{
+			HttpMethod: rule.HttpMethod,
+			Method:     rule.Method,
+			Value:      rule.Value,
+		}
+	}
+	return result
+}
+
+func sortRegistryRules(rules []domain.RegistryAccessRule) []domain.RegistryAccessRule {
+	result := slices.Clone(rules)
+	slices.SortFunc(result, func(a, b domain.RegistryAccessRule) int {
+		return cmp.Or(cmp.Compare(a.Method, b.Method), cmp.Compare(a.HttpMethod, b.HttpMethod))
+	})
+	return result
+}
@@ -0,0 +1,455 @@
+package service
+
+import (
+	"context"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+	"isp-system-service/service/acl"
+
+	"github.com/pkg/errors"
+)
+
+type RoleRepo interface {
+	GetRoleByIdList(ctx context.Context, idList []int) ([]entity.Role, error)
+	GetAllRoles(ctx context.Context) ([]entity.Role, error)
+	GetRolesByAppId(ctx context.Context, appId int) ([]entity.Role, error)
+	GetRolesByAppGroupId(ctx context.Context, appGroupId int) ([]entity.Role, error)
+	GetRoleAccessListByRoleIdList(ctx context.Context, roleIdList []int) ([]entity.RoleAccessList, error)
+}
+
+type RoleSaveTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetRoleByIdList(ctx context.Context, idList []int) ([]entity.Role, error)
+	GetRoleAccessListByRoleIdList(ctx context.Context, roleIdList []int) ([]entity.RoleAccessList, error)
+	CreateRole(ctx context.Context, name string, description string) (*entity.Role, error)
+	UpdateRole(ctx context.Context, id int, name string, description string) (*entity.Role, error)
+	DeleteRoles(ctx context.Context, idList []int) (int, error)
+	InsertRoleAccessList(ctx context.Context, accessList []entity.RoleAccessList) error
+	DeleteRoleAccessListByRoleId(ctx context.Context, roleId int) error
+	GetAppIdListByRoleIdList(ctx context.Context, roleIdList []int) ([]int, error)
+	GetAppGroupIdListByRoleIdList(ctx context.Context, roleIdList []int) ([]int, error)
+}
+
+type RoleAssignTx interface {
+	AuditWriter
+	ChangeEventWriter
+	GetRoleByIdList(ctx context.Context, idList []int) ([]entity.Role, error)
+	GetRolesByAppId(ctx context.Context, appId int) ([]entity.Role, error)
+	GetRolesByAppGroupId(ctx context.Context, appGroupId int) ([]entity.Role, error)
+	SetApplicationRoles(ctx context.Context, appId int, roleIdList []int) error
+	SetAppGroupRoles(ctx context.Context, appGroupId int, roleIdList []int) error
+}
+
+type RoleTxRunner interface {
+	RoleSaveTx(ctx context.Context, tx func(ctx context.Context, tx RoleSaveTx) error) error
+	RoleAssignTx(ctx context.Context, tx func(ctx context.Context, tx RoleAssignTx) error) error
+}
+
+type Role struct {
+	tx           RoleTxRunner
+	roleRepo     RoleRepo
+	appRepo      ApplicationRepo
+	appGroupRepo AppGroupRepo
+	invalidator  CacheInvalidator
+}
+
+func NewRole(
+	tx RoleTxRunner,
+	roleRepo RoleRepo,
+	appRepo ApplicationRepo,
+	appGroupRepo AppGroupRepo,
+	invalidator CacheInvalidator,
+) Role {
+	return Role{
+		tx:           tx,
+		roleRepo:     roleRepo,
+		appRepo:      appRepo,
+		appGroupRepo: appGroupRepo,
+		invalidator:  invalidator,
+	}
+}
+
+func (s Role) Create(ctx context.Context, req domain.CreateRoleRequest) (*domain.Role, error) {
+	err := validateMethods(req.Methods)
+	if err != nil {
+		return nil, err
+	}
+
+	var role *entity.Role
+	err = s.tx.RoleSaveTx(ctx, func(ctx context.Context, tx RoleSaveTx) error {
+		role, err = tx.CreateRole(ctx, req.Name, req.Description)
+		if err != nil {
+			return errors.WithMessage(err, "create role")
+		}
+
+		err = s.insertMethods(ctx, tx, role.Id, req.Methods)
+		if err != nil {
+			return errors.WithMessage(err, "insert role access list")
+		}
+
+		after, err := enrichRoles(ctx, tx, []entity.Role{*role})
+		if err != nil {
+			return err
+		}
+		return WriteAuditEvent(ctx, tx, entity.AuditEntityRole, "create", role.Id, nil, after[0])
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction role create")
+	}
+
+	return s.enrichOne(ctx, *role)
+}
+
+func (s Role) Update(ctx context.Context, req domain.UpdateRoleRequest) (*domain.Role, error) {
+	err := validateMethods(req.Methods)
+	if err != nil {
+		return nil, err
+	}
+
+	var role *entity.Role
+	err = s.tx.RoleSaveTx(ctx, func(ctx context.Context, tx RoleSaveTx) error {
+		before, err := roleStates(ctx, tx, []int{req.Id})
+		if err != nil {
+			return err
+		}
+
+		role, err = tx.UpdateRole(ctx, req.Id, req.Name, req.Description)
+		if err != nil {
+			return errors.WithMessage(err, "update role")
+		}
+
+		err = tx.DeleteRoleAccessListByRoleId(ctx, role.Id)
+		if err != nil {
+			return errors.WithMessage(err, "delete role access list")
+		}
+
+		err = s.insertMethods(ctx, tx, role.Id, req.Methods)
+		if err != nil {
+			return errors.WithMessage(err, "insert role access list")
+		}
+
+		after, err := enrichRoles(ctx, tx, []entity.Role{*role})
+		if err != nil {
+			return err
+		}
+		var beforeState any
+		if len(before) > 0 {
+			beforeState = before[0]
+		}
+		err = WriteAuditEvent(ctx, tx, entity.AuditEntityRole, "update", role.Id, beforeState, after[0])
+		if err != nil {
+			return err
+		}
+
+		return writeRoleChangeEvents(ctx, tx, []int{role.Id})
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction role update")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
+
+	return s.enrichOne(ctx, *role)
+}
+
+func (s Role) DeleteList(ctx context.Context, req domain.IdListRequest) (*domain.DeleteResponse, error) {
+	deleted := 0
+	err := s.tx.RoleSaveTx(ctx, func(ctx context.Context, tx RoleSaveTx) error {
+		roles, err := roleStates(ctx, tx, req.IdList)
+		if err != nil {
+			return err
+		}
+
+		err = writeRoleChangeEvents(ctx, tx, req.IdList)
+		if err != nil {
+			return err
+		}
+
+		deleted, err = tx.DeleteRoles(ctx, req.IdList)
+		if err != nil {
+			return errors.WithMessage(err, "delete roles")
+		}
+
+		for _, role := range roles {
+			err = WriteAuditEvent(ctx, tx, entity.AuditEntityRole, "delete", role.Id, role, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction role delete")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
+
+	return &domain.DeleteResponse{
+		Deleted: deleted,
+	}, nil
+}
+
+func (s Role) GetByIdList(ctx context.Context, idList []int) ([]domain.Role, error) {
+	roles, err := s.roleRepo.GetRoleByIdList(ctx, idList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles by id list")
+	}
+	return s.enrich(ctx, roles)
+}
+
+func (s Role) GetAll(ctx context.Context) ([]domain.Role, error) {
+	roles, err := s.roleRepo.GetAllRoles(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get all roles")
+	}
+	return s.enrich(ctx, roles)
+}
+
+func (s Role) GetByAppId(ctx context.Context, appId int) ([]domain.Role, error) {
+	_, err := s.appRepo.GetApplicationById(ctx, appId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get application by id")
+	}
+
+	roles, err := s.roleRepo.GetRolesByAppId(ctx, appId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles by app_id")
+	}
+	return s.enrich(ctx, roles)
+}
+
+func (s Role) GetByAppGroupId(ctx context.Context, appGroupId int) ([]domain.Role, error) {
+	_, err := s.appGroupRepo.GetAppGroupById(ctx, appGroupId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get application group by id")
+	}
+
+	roles, err := s.roleRepo.GetRolesByAppGroupId(ctx, appGroupId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles by app_group_id")
+	}
+	return s.enrich(ctx, roles)
+}
+
+func (s Role) SetForApplication(ctx context.Context, req domain.SetApplicationRolesRequest) ([]domain.Role, error) {
+	_, err := s.appRepo.GetApplicationById(ctx, req.AppId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get application by id")
+	}
+
+	err = s.tx.RoleAssignTx(ctx, func(ctx context.Context, tx RoleAssignTx) error {
+		err := requireRoles(ctx, tx, req.RoleIdList)
+		if err != nil {
+			return err
+		}
+
+		before, err := tx.GetRolesByAppId(ctx, req.AppId)
+		if err != nil {
+			return errors.WithMessage(err, "get roles by app_id")
+		}
+
+		err = tx.SetApplicationRoles(ctx, req.AppId, req.RoleIdList)
+		if err != nil {
+			return errors.WithMessage(err, "set application roles")
+		}
+
+		err = WriteAuditEvent(ctx, tx, entity.AuditEntityApplication, "set_roles", req.AppId,
+			roleIdList(before), req.RoleIdList,
+		)
+		if err != nil {
+			return err
+		}
+
+		return WriteChangeEvent(ctx, tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{AppId: req.AppId})
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction role assign")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{AppIds: []int{req.AppId}})
+
+	return s.GetByAppId(ctx, req.AppId)
+}
+
+func (s Role) SetForAppGroup(ctx context.Context, req domain.SetAppGroupRolesRequest) ([]domain.Role, error) {
+	_, err := s.appGroupRepo.GetAppGroupById(ctx, req.AppGroupId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get application group by id")
+	}
+
+	err = s.tx.RoleAssignTx(ctx, func(ctx context.Context, tx RoleAssignTx) error {
+		err := requireRoles(ctx, tx, req.RoleIdList)
+		if err != nil {
+			return err
+		}
+
+		before, err := tx.GetRolesByAppGroupId(ctx, req.AppGroupId)
+		if err != nil {
+			return errors.WithMessage(err, "get roles by app_group_id")
+		}
+
+		err = tx.SetAppGroupRoles(ctx, req.AppGroupId, req.RoleIdList)
+		if err != nil {
+			return errors.WithMessage(err, "set application group roles")
+		}
+
+		err = WriteAuditEvent(ctx, tx, entity.AuditEntityAppGroup, "set_roles", req.AppGroupId,
+			roleIdList(before), req.RoleIdList,
+		)
+		if err != nil {
+			return err
+		}
+
+		return WriteChangeEvent(ctx, tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{AppGroupId: req.AppGroupId})
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "transaction role assign")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
+
+	return s.GetByAppGroupId(ctx, req.AppGroupId)
+}
+
+func (s Role) insertMethods(ctx context.Context, tx RoleSaveTx, roleId int, methods []domain.MethodInfo) error {
+	if len(methods) == 0 {
+		return nil
+	}
+
+	accessList := make([]entity.RoleAccessList, len(methods))
+	for i, m := range methods {
+		accessList[i] = entity.RoleAccessList{
+			RoleId:     roleId,
+			HttpMethod: m.HttpMethod,
+			Method:     m.Method,
+			Value:      m.Value,
+		}
+	}
+	return tx.InsertRoleAccessList(ctx, accessList)
+}
+
+func (s Role) enrichOne(ctx context.Context, role entity.Role) (*domain.Role, error) {
+	roles, err := s.enrich(ctx, []entity.Role{role})
+	if err != nil {
+		return nil, err
+	}
+	return &roles[0], nil
+}
+
+func (s Role) enrich(ctx context.Context, roles []entity.Role) ([]domain.Role, error) {
+	return enrichRoles(ctx, s.roleRepo, roles)
+}
+
+type roleAccessListReader interface {
+	GetRoleAccessListByRoleIdList(ctx context.Context, roleIdList []int) ([]entity.RoleAccessList, error)
+}
+
+func enrichRoles(ctx context.Context, reader roleAccessListReader, roles []entity.Role) ([]domain.Role, error) {
+	idList := make([]int, len(roles))
+	for i, role := range roles {
+		idList[i] = role.Id
+	}
+
+	accessList, err := reader.GetRoleAccessListByRoleIdList(ctx, idList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get role access list")
+	}
+
+	methodsByRoleId := make(map[int][]domain.MethodInfo)
+	for _, access := range accessList {
+		methodsByRoleId[access.RoleId] = append(methodsByRoleId[access.RoleId], domain.MethodInfo{
+			HttpMethod: access.HttpMethod,
+			Method:     access.Method,
+			Value:      access.Value,
+		})
+	}
+
+	result := make([]domain.Role, len(roles))
+	for i, role := range roles {
+		methods := methodsByRoleId[role.Id]
+		if methods == nil {
+			methods = make([]domain.MethodInfo, 0)
+		}
+		result[i] = domain.Role{
+			Id:          role.Id,
+			Name:        role.Name,
+			Description: role.Description.String,
+			Methods:     methods,
+			CreatedAt:   role.CreatedAt,
+			UpdatedAt:   role.UpdatedAt,
+		}
+	}
+	return result, nil
+}
+
+// roleStates returns the roles with their methods as they are recorded in the audit
+func roleStates(ctx context.Context, tx RoleSaveTx, idList []int) ([]domain.Role, error) {
+	roles, err := tx.GetRoleByIdList(ctx, idList)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get roles by id list")
+	}
+	return enrichRoles(ctx, tx, roles)
+}
+
+func roleIdList(roles []entity.Role) []int {
+	result := make([]int, len(roles))
+	for i, role := range roles {
+		result[i] = role.Id
+	}
+	return result
+}
+
+// writeRoleChangeEvents publishes the change of the access lists of applications and groups the roles are assigned to
+func writeRoleChangeEvents(ctx context.Context, tx RoleSaveTx, roleIdList []int) error {
+	appIdList, err := tx.GetAppIdListByRoleIdList(ctx, roleIdList)
+	if err != nil {
+		return errors.WithMessage(err, "get app_id list by role_id list")
+	}
+	for _, appId := range appIdList {
+		err = WriteChangeEvent(ctx, tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{AppId: appId})
+		if err != nil {
+			return err
+		}
+	}
+
+	appGroupIdList, err := tx.GetAppGroupIdListByRoleIdList(ctx, roleIdList)
+	if err != nil {
+		return errors.WithMessage(err, "get app_group_id list by role_id list")
+	}
+	for _, appGroupId := range appGroupIdList {
+		err = WriteChangeEvent(ctx, tx, domain.ChangeEventAccessListChanged, domain.AccessListChange{AppGroupId: appGroupId})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func requireRoles(ctx context.Context, tx RoleAssignTx, roleIdList []int) error {
+	if len(roleIdList) == 0 {
+		return nil
+	}
+
+	roles, err := tx.GetRoleByIdList(ctx, roleIdList)
+	if err != nil {
+		return errors.WithMessage(err, "get roles by id list")
+	}
+
+	found := make(map[int]bool, len(roles))
+	for _, role := range roles {
+		found[role.Id] = true
+	}
+	for _, id := range roleIdList {
+		if !found[id] {
+			return errors.WithMessagef(domain.ErrRoleNotFound, "role %d", id)
+		}
+	}
+	return nil
+}
+
+func validateMethods(methods []domain.MethodInfo) error {
+	for _, m := range methods {
+		if !acl.IsValidPattern(m.Method) {
+			return domain.ErrInvalidMethodPattern
+		}
+	}
+	return nil
+}
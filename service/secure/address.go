@@ -0,0 +1,47 @@
+package secure
+
+import (
+	"net/netip"
+
+	"isp-system-service/entity"
+)
+
+// addressAllowed reports whether the client address belongs to the allowed CIDR of the token and of its application,
+// a token without restrictions is allowed from any address, even unknown one
+func addressAllowed(authData entity.AuthData, clientAddress string) bool {
+	if len(authData.AllowedCidrs) == 0 && len(authData.AppAllowedCidrs) == 0 {
+		return true
+	}
+
+	addr, ok := parseAddress(clientAddress)
+	if !ok {
+		return false
+	}
+	return inCidrs(authData.AppAllowedCidrs, addr) && inCidrs(authData.AllowedCidrs, addr)
+}
+
+func inCidrs(cidrs []string, addr netip.Addr) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddress accepts an address with or without port
+func parseAddress(address string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(address)
+	if err == nil {
+		return addr.Unmap(), true
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	return netip.Addr{}, false
+}
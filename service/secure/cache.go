@@ -0,0 +1,63 @@
+package secure
+
+import (
+	"slices"
+	"time"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+	"isp-system-service/service/cache"
+	"isp-system-service/service/jwt"
+)
+
+// Cache keeps auth data by token hash, effective access rules and rate limits by application id
+// and JWT signing keys
+type Cache struct {
+	authData    *cache.Cache[string, entity.AuthData]
+	accessRules *cache.Cache[int, []entity.AccessRule]
+	rateLimits  *cache.Cache[int, []entity.RateLimit]
+	keys        *jwt.KeyStore
+}
+
+func NewCache(maxSize int, ttl time.Duration, keys *jwt.KeyStore) *Cache {
+	return &Cache{
+		authData:    cache.New[string, entity.AuthData](maxSize, ttl),
+		accessRules: cache.New[int, []entity.AccessRule](maxSize, ttl),
+		rateLimits:  cache.New[int, []entity.RateLimit](maxSize, ttl),
+		keys:        keys,
+	}
+}
+
+func (c *Cache) Enabled() bool {
+	return c.authData.Enabled()
+}
+
+func (c *Cache) Invalidate(event domain.CacheInvalidation) {
+	if event.All {
+		c.Purge()
+		return
+	}
+
+	if event.SigningKeys {
+		// auth data of tokens signed with a retired key must not outlive the key
+		c.authData.Purge()
+		c.keys.Reset()
+	}
+	if len(event.TokenHashes) > 0 {
+		c.authData.Delete(event.TokenHashes...)
+	}
+	if len(event.AppIds) > 0 {
+		c.authData.DeleteFunc(func(_ string, authData entity.AuthData) bool {
+			return slices.Contains(event.AppIds, authData.AppId)
+		})
+		c.accessRules.Delete(event.AppIds...)
+		c.rateLimits.Delete(event.AppIds...)
+	}
+}
+
+func (c *Cache) Purge() {
+	c.authData.Purge()
+	c.accessRules.Purge()
+	c.rateLimits.Purge()
+	c.keys.Reset()
+}
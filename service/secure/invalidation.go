@@ -0,0 +1,139 @@
+package secure
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"isp-system-service/domain"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/json"
+	"github.com/txix-open/isp-kit/log"
+)
+
+const (
+	CacheInvalidationChannel = "isp_system_service_cache_invalidation"
+
+	// postgres limits notification payload with 8000 bytes
+	maxNotificationPayloadSize = 7900
+	listenReconnectDelay       = 3 * time.Second
+)
+
+type Notifier interface {
+	Notify(ctx context.Context, channel string, payload string) error
+}
+
+// Invalidator drops stale entries from the local cache and notifies other instances
+type Invalidator struct {
+	cache    *Cache
+	notifier Notifier
+	logger   log.Logger
+}
+
+func NewInvalidator(cache *Cache, notifier Notifier, logger log.Logger) Invalidator {
+	return Invalidator{
+		cache:    cache,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// Invalidate must be called after the transaction which changed the data is committed.
+// Notification errors are logged, stale entries on other instances expire by ttl.
+// Signing keys are kept regardless of the cache settings, so their changes are always propagated
+func (i Invalidator) Invalidate(ctx context.Context, event domain.CacheInvalidation) {
+	if !i.cache.Enabled() && !event.SigningKeys {
+		return
+	}
+
+	i.cache.Invalidate(event)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		i.logger.Error(ctx, errors.WithMessage(err, "marshal cache invalidation"))
+		return
+	}
+	if len(payload) > maxNotificationPayloadSize {
+		payload, err = json.Marshal(domain.CacheInvalidation{All: true})
+		if err != nil {
+			i.logger.Error(ctx, errors.WithMessage(err, "marshal cache invalidation"))
+			return
+		}
+	}
+
+	err = i.notifier.Notify(ctx, CacheInvalidationChannel, string(payload))
+	if err != nil {
+		i.logger.Error(ctx, errors.WithMessage(err, "notify cache invalidation"))
+	}
+}
+
+type Listener interface {
+	Listen(ctx context.Context, channel string, onListen func(), handler func(payload string)) error
+}
+
+// CacheListener applies invalidations received from other instances to the current cache
+type CacheListener struct {
+	listener Listener
+	logger   log.Logger
+
+	cache     atomic.Pointer[Cache]
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+func NewCacheListener(listener Listener, logger log.Logger) *CacheListener {
+	return &CacheListener{
+		listener: listener,
+		logger:   logger,
+		ready:    make(chan struct{}),
+	}
+}
+
+// Upgrade replaces the cache, the listener starts listening after the first call
+func (l *CacheListener) Upgrade(cache *Cache) {
+	l.cache.Store(cache)
+	l.readyOnce.Do(func() {
+		close(l.ready)
+	})
+}
+
+// Run blocks until ctx is done, reconnecting on failures
+func (l *CacheListener) Run(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-l.ready:
+	}
+
+	for {
+		// notifications could be missed while there was no connection
+		err := l.listener.Listen(ctx, CacheInvalidationChannel, l.purge, l.handle)
+		if ctx.Err() != nil {
+			return
+		}
+		l.logger.Error(ctx, errors.WithMessage(err, "listen cache invalidation"))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenReconnectDelay):
+		}
+	}
+}
+
+func (l *CacheListener) purge() {
+	l.cache.Load().Purge()
+}
+
+func (l *CacheListener) handle(payload string) {
+	event := domain.CacheInvalidation{}
+	err := json.Unmarshal([]byte(payload), &event)
+	if err != nil {
+		l.logger.Error(context.Background(), errors.WithMessage(err, "unmarshal cache invalidation"))
+		l.purge()
+		return
+	}
+	l.cache.Load().Invalidate(event)
+}
@@ -2,67 +2,422 @@ package secure
 
 import (
 	"context"
+	"database/sql"
+	"strings"
 	"time"
 
 	"isp-system-service/domain"
 	"isp-system-service/entity"
+	"isp-system-service/service/acl"
+	"isp-system-service/service/jwt"
+	"isp-system-service/service/tokenhash"
 
 	"github.com/pkg/errors"
 )
 
 type TokenRep interface {
-	AuthDataByToken(ctx context.Context, token string) (*entity.AuthData, error)
+	AuthDataByToken(ctx context.Context, tokenHash string) (*entity.AuthData, error)
+	AuthDataWithAccessRules(ctx context.Context, tokenHash string, httpMethod string, method string) (*entity.AuthData, []entity.AccessRule, error)
+	GetRevokedToken(ctx context.Context, id string) (*entity.RevokedToken, error)
+}
+
+type AppRep interface {
+	GetApplicationById(ctx context.Context, id int) (*entity.Application, error)
+}
+
+type AppGroupRep interface {
+	GetAppGroupById(ctx context.Context, id int) (*entity.AppGroup, error)
+}
+
+type RateLimitRep interface {
+	GetEffectiveRateLimits(ctx context.Context, appId int) ([]entity.RateLimit, error)
 }
 
 type AccessListRep interface {
-	GetAccessListByAppIdAndMethod(ctx context.Context, appId int, httpMethod string, method string) (*entity.AccessList, error)
+	GetApplicableAccessRules(ctx context.Context, appId int, httpMethod string, method string) ([]entity.AccessRule, error)
+	GetApplicableAccessRulesBatch(ctx context.Context, appId int, httpMethods []string, methods []string) ([]entity.AccessRule, error)
+	GetEffectiveAccessRules(ctx context.Context, appId int) ([]entity.AccessRule, error)
 }
 
 type Service struct {
 	tokenRep      TokenRep
+	appRep        AppRep
+	appGroupRep   AppGroupRep
 	accessListRep AccessListRep
+	rateLimitRep  RateLimitRep
+	cache         *Cache
+	hasher        tokenhash.Hasher
+	jwtKeys       *jwt.KeyStore
+	usage         *UsageTracker
 }
 
 func NewService(
 	tokenRep TokenRep,
+	appRep AppRep,
+	appGroupRep AppGroupRep,
 	accessListRep AccessListRep,
+	rateLimitRep RateLimitRep,
+	cache *Cache,
+	hasher tokenhash.Hasher,
+	jwtKeys *jwt.KeyStore,
+	usage *UsageTracker,
 ) Service {
 	return Service{
 		tokenRep:      tokenRep,
+		appRep:        appRep,
+		appGroupRep:   appGroupRep,
 		accessListRep: accessListRep,
+		rateLimitRep:  rateLimitRep,
+		cache:         cache,
+		hasher:        hasher,
+		jwtKeys:       jwtKeys,
+		usage:         usage,
+	}
+}
+
+func (s Service) Jwks(ctx context.Context) (*domain.Jwks, error) {
+	jwks, err := s.jwtKeys.Jwks(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get jwks")
 	}
+	return &jwks, nil
 }
 
-func (s Service) Authenticate(ctx context.Context, token string) (*domain.AuthData, error) {
-	authData, err := s.tokenRep.AuthDataByToken(ctx, token)
+// Authenticate checks the client address against allowed CIDR of the token
+// and tracks usage of the authenticated token by the caller address.
+// The client address is passed by the gateway explicitly and could be empty,
+// then tokens restricted by CIDR are rejected
+func (s Service) Authenticate(
+	ctx context.Context,
+	token string,
+	clientAddress string,
+	callerAddress string,
+) (*domain.AuthData, error) {
+	authData, err := s.authData(ctx, token)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get auth data by token")
 	}
 
-	if authData.ExpireTime != -1 &&
-		authData.CreatedAt.Add(time.Millisecond*time.Duration(authData.ExpireTime)).Before(time.Now().UTC()) {
+	if isExpired(*authData) {
+		return nil, domain.ErrTokenExpired
+	}
+	err = statusError(*authData)
+	if err != nil {
+		return nil, err
+	}
+	if !addressAllowed(*authData, clientAddress) {
+		return nil, domain.ErrSourceAddressNotAllowed
+	}
+	s.usage.Track(authData.TokenId, callerAddress)
+
+	return s.authDataWithRateLimits(ctx, *authData)
+}
+
+// Check authenticates the token and authorizes its application to the endpoint
+// using a single query or the cache.
+// Token errors are returned as errors, an authorization refusal is returned as ErrorReason
+func (s Service) Check(
+	ctx context.Context,
+	req domain.CheckRequest,
+	clientAddress string,
+	callerAddress string,
+) (*domain.CheckResponse, error) {
+	authData, rules, err := s.authDataWithAccessRules(ctx, req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get auth data with access rules")
+	}
+
+	if isExpired(*authData) {
 		return nil, domain.ErrTokenExpired
 	}
+	err = statusError(*authData)
+	if err != nil {
+		return nil, err
+	}
+	if !addressAllowed(*authData, clientAddress) {
+		return nil, domain.ErrSourceAddressNotAllowed
+	}
+	s.usage.Track(authData.TokenId, callerAddress)
+
+	converted, err := s.authDataWithRateLimits(ctx, *authData)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.CheckResponse{
+		Authenticated: true,
+		AuthData:      converted,
+	}
+	rule := acl.ResolveInherited(rules, req.HttpMethod, req.Endpoint)
+	switch {
+	case !acl.InScope(authData.Scope, req.Endpoint):
+		result.ErrorReason = domain.ErrOutOfTokenScope.Error()
+	case rule == nil:
+		result.ErrorReason = domain.ErrAccessListNotFound.Error()
+	case !rule.Value:
+		result.ErrorReason = domain.ErrAccessDenied.Error()
+		result.Rule = convertAccessRule(rule)
+	default:
+		result.Authorized = true
+		result.Rule = convertAccessRule(rule)
+	}
+	return result, nil
+}
+
+// Authorize checks the access list of the application, access of a scoped token is limited by its scope as well
+func (s Service) Authorize(ctx context.Context, req domain.AuthorizeRequest) (*domain.AuthorizeResponse, error) {
+	if !acl.InScope(req.Scope, req.Endpoint) {
+		return &domain.AuthorizeResponse{OutOfScope: true}, nil
+	}
+
+	rules, err := s.applicableAccessRules(ctx, req.ApplicationId, req.HttpMethod, req.Endpoint)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get applicable access rules")
+	}
+
+	rule := acl.ResolveInherited(rules, req.HttpMethod, req.Endpoint)
+	if rule == nil {
+		return nil, domain.ErrAccessListNotFound
+	}
+
+	return &domain.AuthorizeResponse{
+		Authorized: rule.Value,
+		Rule:       convertAccessRule(rule),
+	}, nil
+}
+
+// AuthorizeBatch resolves access to every endpoint using the rules fetched by a single query
+func (s Service) AuthorizeBatch(ctx context.Context, req domain.AuthorizeBatchRequest) ([]domain.AuthorizeBatchResult, error) {
+	httpMethods := make([]string, 0, len(req.Endpoints))
+	methods := make([]string, 0, len(req.Endpoints))
+	for _, e := range req.Endpoints {
+		httpMethods = append(httpMethods, e.HttpMethod)
+		methods = append(methods, e.Endpoint)
+	}
+
+	rules, err := s.applicableAccessRulesBatch(ctx, req.ApplicationId, httpMethods, methods)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get applicable access rules batch")
+	}
+
+	result := make([]domain.AuthorizeBatchResult, len(req.Endpoints))
+	for i, e := range req.Endpoints {
+		result[i] = domain.AuthorizeBatchResult{
+			HttpMethod: e.HttpMethod,
+			Endpoint:   e.Endpoint,
+		}
+		if !acl.InScope(req.Scope, e.Endpoint) {
+			result[i].OutOfScope = true
+			continue
+		}
+		rule := acl.ResolveInherited(rules, e.HttpMethod, e.Endpoint)
+		if rule != nil {
+			result[i].Authorized = rule.Value
+			result[i].Rule = convertAccessRule(rule)
+		}
+	}
+	return result, nil
+}
+
+func (s Service) authData(ctx context.Context, token string) (*entity.AuthData, error) {
+	tokenHash := s.hasher.Hash(token)
+	cached, ok := s.cache.authData.Get(tokenHash)
+	if ok {
+		return &cached, nil
+	}
+
+	version := s.cache.authData.Version()
+	authData, err := s.loadAuthData(ctx, token, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.authData.SetIfVersion(version, tokenHash, *authData)
+	return authData, nil
+}
+
+// loadAuthData validates JWT locally with any not retired key checking only the deny list,
+// other tokens are looked up by hash.
+// Rotated JWT expires when it is revoked, so it is rejected as expired the same way as rotated opaque token.
+// Allowed CIDR and statuses of the application and its group are not in the claims and are loaded by id
+func (s Service) loadAuthData(ctx context.Context, token string, tokenHash string) (*entity.AuthData, error) {
+	if !jwt.IsJwt(token) {
+		return s.tokenRep.AuthDataByToken(ctx, tokenHash)
+	}
 
+	claims, err := s.jwtKeys.Verify(ctx, token)
+	switch {
+	case errors.Is(err, jwt.ErrInvalidToken):
+		return nil, domain.ErrTokenInvalid
+	case err != nil:
+		return nil, errors.WithMessage(err, "verify jwt")
+	}
+
+	revoked, err := s.tokenRep.GetRevokedToken(ctx, claims.Id)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get revoked token")
+	}
+	if revoked != nil && !revoked.Rotated {
+		return nil, domain.ErrTokenNotFound
+	}
+
+	app, err := s.appRep.GetApplicationById(ctx, claims.AppId)
+	switch {
+	case errors.Is(err, domain.ErrApplicationNotFound):
+		return nil, domain.ErrTokenNotFound
+	case err != nil:
+		return nil, errors.WithMessage(err, "get application by id")
+	}
+
+	appGroup, err := s.appGroupRep.GetAppGroupById(ctx, app.ApplicationGroupId)
+	switch {
+	case errors.Is(err, domain.ErrAppGroupNotFound):
+		return nil, domain.ErrTokenNotFound
+	case err != nil:
+		return nil, errors.WithMessage(err, "get app group by id")
+	}
+
+	authData := authDataFromClaims(*claims)
+	authData.AppAllowedCidrs = app.AllowedCidrs
+	authData.AppStatus = app.Status
+	authData.AppGroupStatus = appGroup.Status
+	if revoked == nil {
+		return authData, nil
+	}
+	if !authData.ExpiresAt.Valid || revoked.RevokedAt.Before(authData.ExpiresAt.Time) {
+		authData.ExpiresAt = sql.NullTime{Time: revoked.RevokedAt, Valid: true}
+	}
+	return authData, nil
+}
+
+// applicableAccessRules returns rules which could be applied to the method,
+// if the cache is enabled all effective rules of the application are returned
+func (s Service) applicableAccessRules(ctx context.Context, appId int, httpMethod string, method string) ([]entity.AccessRule, error) {
+	if s.cache.Enabled() {
+		return s.effectiveAccessRules(ctx, appId)
+	}
+	return s.accessListRep.GetApplicableAccessRules(ctx, appId, httpMethod, method)
+}
+
+func (s Service) applicableAccessRulesBatch(
+	ctx context.Context,
+	appId int,
+	httpMethods []string,
+	methods []string,
+) ([]entity.AccessRule, error) {
+	if s.cache.Enabled() {
+		return s.effectiveAccessRules(ctx, appId)
+	}
+	return s.accessListRep.GetApplicableAccessRulesBatch(ctx, appId, httpMethods, methods)
+}
+
+func (s Service) effectiveAccessRules(ctx context.Context, appId int) ([]entity.AccessRule, error) {
+	cached, ok := s.cache.accessRules.Get(appId)
+	if ok {
+		return cached, nil
+	}
+
+	version := s.cache.accessRules.Version()
+	rules, err := s.accessListRep.GetEffectiveAccessRules(ctx, appId)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.accessRules.SetIfVersion(version, appId, rules)
+	return rules, nil
+}
+
+func (s Service) authDataWithRateLimits(ctx context.Context, authData entity.AuthData) (*domain.AuthData, error) {
+	rateLimits, err := s.effectiveRateLimits(ctx, authData.AppId)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get effective rate limits")
+	}
+
+	result := convertAuthData(authData)
+	for _, rateLimit := range rateLimits {
+		source := entity.AccessRuleSourceApplication
+		if rateLimit.AppGroupId.Valid {
+			source = entity.AccessRuleSourceAppGroup
+		}
+		result.RateLimits = append(result.RateLimits, domain.RateLimitPolicy{
+			Source:            source,
+			Method:            rateLimit.Method,
+			RequestsPerSecond: rateLimit.RequestsPerSecond,
+			RequestsPerMinute: rateLimit.RequestsPerMinute,
+			Burst:             rateLimit.Burst,
+		})
+	}
+	return result, nil
+}
+
+func (s Service) effectiveRateLimits(ctx context.Context, appId int) ([]entity.RateLimit, error) {
+	cached, ok := s.cache.rateLimits.Get(appId)
+	if ok {
+		return cached, nil
+	}
+
+	version := s.cache.rateLimits.Version()
+	rateLimits, err := s.rateLimitRep.GetEffectiveRateLimits(ctx, appId)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.rateLimits.SetIfVersion(version, appId, rateLimits)
+	return rateLimits, nil
+}
+
+func (s Service) authDataWithAccessRules(ctx context.Context, req domain.CheckRequest) (*entity.AuthData, []entity.AccessRule, error) {
+	if !s.cache.Enabled() && !jwt.IsJwt(req.Token) {
+		return s.tokenRep.AuthDataWithAccessRules(ctx, s.hasher.Hash(req.Token), req.HttpMethod, req.Endpoint)
+	}
+
+	authData, err := s.authData(ctx, req.Token)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, err := s.applicableAccessRules(ctx, authData.AppId, req.HttpMethod, req.Endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return authData, rules, nil
+}
+
+// isExpired checks JWT and cached auth data, the database doesn't return expired tokens
+func isExpired(authData entity.AuthData) bool {
+	return authData.ExpiresAt.Valid && !authData.ExpiresAt.Time.After(time.Now().UTC())
+}
+
+func authDataFromClaims(claims jwt.Claims) *entity.AuthData {
+	authData := &entity.AuthData{
+		TokenId:            claims.Id,
+		AppName:            claims.AppName,
+		SystemId:           claims.SystemId,
+		DomainId:           claims.DomainId,
+		ApplicationGroupId: claims.AppGroupId,
+		AppId:              claims.AppId,
+		CreatedAt:          time.Unix(claims.IssuedAt, 0).UTC(),
+		Scope:              strings.Fields(claims.Scope),
+		AllowedCidrs:       claims.AllowedCidrs,
+	}
+	if claims.ExpiresAt != 0 {
+		authData.ExpiresAt = sql.NullTime{Time: time.Unix(claims.ExpiresAt, 0).UTC(), Valid: true}
+	}
+	return authData
+}
+
+func convertAuthData(authData entity.AuthData) *domain.AuthData {
 	return &domain.AuthData{
 		AppName:       authData.AppName,
 		SystemId:      authData.SystemId,
 		DomainId:      authData.DomainId,
 		ServiceId:     authData.ApplicationGroupId,
 		ApplicationId: authData.AppId,
-	}, nil
+		Scope:         authData.Scope,
+	}
 }
 
-func (s Service) Authorize(ctx context.Context, req domain.AuthorizeRequest) (bool, error) {
-	accessList, err := s.accessListRep.GetAccessListByAppIdAndMethod(
-		ctx,
-		req.ApplicationId,
-		req.HttpMethod,
-		req.Endpoint,
-	)
-	if err != nil {
-		return false, errors.WithMessage(err, "get access list by app_id and method")
+func convertAccessRule(rule *entity.AccessRule) *domain.AccessRule {
+	return &domain.AccessRule{
+		Source:     rule.Source,
+		SourceId:   rule.SourceId,
+		HttpMethod: rule.HttpMethod,
+		Method:     rule.Method,
+		Value:      rule.Value,
 	}
-
-	return accessList.Value, nil
 }
@@ -0,0 +1,19 @@
+package secure
+
+import (
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+)
+
+// statusError rejects tokens of not active applications,
+// the status of the group applies to all its applications and disabled takes precedence over suspended
+func statusError(authData entity.AuthData) error {
+	switch {
+	case authData.AppStatus == domain.StatusDisabled || authData.AppGroupStatus == domain.StatusDisabled:
+		return domain.ErrApplicationDisabled
+	case authData.AppStatus == domain.StatusSuspended || authData.AppGroupStatus == domain.StatusSuspended:
+		return domain.ErrApplicationSuspended
+	default:
+		return nil
+	}
+}
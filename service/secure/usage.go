@@ -0,0 +1,95 @@
+package secure
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"isp-system-service/entity"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/log"
+)
+
+type UsageRep interface {
+	UpdateTokenUsage(ctx context.Context, usages []entity.TokenUsage) error
+}
+
+// UsageTracker coalesces token usages in memory, only the latest usage of every token is kept
+// until it is written by Flush
+type UsageTracker struct {
+	rep UsageRep
+
+	lock   sync.Mutex
+	usages map[string]entity.TokenUsage
+}
+
+func NewUsageTracker(rep UsageRep) *UsageTracker {
+	return &UsageTracker{
+		rep:    rep,
+		usages: make(map[string]entity.TokenUsage),
+	}
+}
+
+func (t *UsageTracker) Track(tokenId string, address string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.usages[tokenId] = entity.TokenUsage{
+		TokenId: tokenId,
+		UsedAt:  time.Now().UTC(),
+		Address: address,
+	}
+}
+
+// Flush writes collected usages, if writing fails they are merged back and written by the next call
+func (t *UsageTracker) Flush(ctx context.Context) error {
+	t.lock.Lock()
+	pending := t.usages
+	t.usages = make(map[string]entity.TokenUsage, len(pending))
+	t.lock.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usages := make([]entity.TokenUsage, 0, len(pending))
+	for _, usage := range pending {
+		usages = append(usages, usage)
+	}
+	err := t.rep.UpdateTokenUsage(ctx, usages)
+	if err == nil {
+		return nil
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for tokenId, usage := range pending {
+		newer, ok := t.usages[tokenId]
+		if !ok || newer.UsedAt.Before(usage.UsedAt) {
+			t.usages[tokenId] = usage
+		}
+	}
+	return errors.WithMessage(err, "update token usage")
+}
+
+// UsageFlushJob writes token usages by worker
+type UsageFlushJob struct {
+	tracker *UsageTracker
+	logger  log.Logger
+}
+
+func NewUsageFlushJob(tracker *UsageTracker, logger log.Logger) UsageFlushJob {
+	return UsageFlushJob{
+		tracker: tracker,
+		logger:  logger,
+	}
+}
+
+func (j UsageFlushJob) Do(ctx context.Context) {
+	ctx = log.ToContext(ctx, log.String("worker", "token_usage_flush"))
+	err := j.tracker.Flush(ctx)
+	if err != nil {
+		j.logger.Error(ctx, errors.WithMessage(err, "flush token usage"))
+	}
+}
@@ -12,15 +12,18 @@ import (
 type Service struct {
 	domainRepo  DomainRepo
 	serviceRepo AppGroupRepo
+	invalidator CacheInvalidator
 }
 
 func NewService(
 	domainRepo DomainRepo,
 	serviceRepo AppGroupRepo,
+	invalidator CacheInvalidator,
 ) Service {
 	return Service{
 		domainRepo:  domainRepo,
 		serviceRepo: serviceRepo,
+		invalidator: invalidator,
 	}
 }
 
@@ -78,10 +81,13 @@ func (s Service) CreateUpdate(ctx context.Context, req domain.ServiceCreateUpdat
 		return &result, nil
 	}
 
-	_, err = s.serviceRepo.GetAppGroupById(ctx, req.Id)
+	existing, err := s.serviceRepo.GetAppGroupById(ctx, req.Id)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get service by id")
 	}
+	if existing.ManagedByConfig {
+		return nil, errors.WithMessagef(domain.ErrManagedByConfig, "service %d", req.Id)
+	}
 
 	serviceEntity, err := s.serviceRepo.UpdateAppGroup(ctx, req.Id, req.Name, req.Description)
 	if err != nil {
@@ -93,10 +99,21 @@ func (s Service) CreateUpdate(ctx context.Context, req domain.ServiceCreateUpdat
 }
 
 func (s Service) Delete(ctx context.Context, idList []int) (int, error) {
+	services, err := s.serviceRepo.GetAppGroupByIdList(ctx, idList)
+	if err != nil {
+		return 0, errors.WithMessage(err, "get services by id list")
+	}
+	for _, service := range services {
+		if service.ManagedByConfig {
+			return 0, errors.WithMessagef(domain.ErrManagedByConfig, "service %d", service.Id)
+		}
+	}
+
 	result, err := s.serviceRepo.DeleteAppGroup(ctx, idList)
 	if err != nil {
 		return 0, errors.WithMessage(err, "delete service")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{All: true})
 
 	return result, nil
 }
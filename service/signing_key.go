@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+	"isp-system-service/service/jwt"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/log"
+)
+
+type SigningKeyRepo interface {
+	GetSigningKeys(ctx context.Context) ([]entity.SigningKey, error)
+}
+
+type SigningKeyTx interface {
+	LockSigningKeys(ctx context.Context) error
+	GetNotRetiredSigningKeys(ctx context.Context) ([]entity.SigningKey, error)
+	GetSigningKeyById(ctx context.Context, id string) (*entity.SigningKey, error)
+	InsertSigningKey(ctx context.Context, key entity.SigningKey) (*entity.SigningKey, error)
+	MarkActiveSigningKeyRetiring(ctx context.Context) error
+	RetireSigningKeys(ctx context.Context, idList []string) error
+}
+
+type SigningKeyTxRunner interface {
+	SigningKeyTx(ctx context.Context, tx func(ctx context.Context, tx SigningKeyTx) error) error
+}
+
+type SigningKeyRotation struct {
+	Algorithm string
+	// the active key is rotated when it becomes older, 0 disables scheduled rotation
+	Interval time.Duration
+	// retiring keys are retired when they become older, 0 disables scheduled retirement
+	Overlap time.Duration
+	// the first key is generated if there is no active key
+	Bootstrap bool
+}
+
+type SigningKey struct {
+	rotation    SigningKeyRotation
+	tx          SigningKeyTxRunner
+	repo        SigningKeyRepo
+	cipher      jwt.Cipher
+	invalidator CacheInvalidator
+	logger      log.Logger
+}
+
+func NewSigningKey(
+	rotation SigningKeyRotation,
+	tx SigningKeyTxRunner,
+	repo SigningKeyRepo,
+	cipher jwt.Cipher,
+	invalidator CacheInvalidator,
+	logger log.Logger,
+) SigningKey {
+	return SigningKey{
+		rotation:    rotation,
+		tx:          tx,
+		repo:        repo,
+		cipher:      cipher,
+		invalidator: invalidator,
+		logger:      logger,
+	}
+}
+
+func (s SigningKey) List(ctx context.Context) ([]domain.SigningKey, error) {
+	keys, err := s.repo.GetSigningKeys(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get signing keys")
+	}
+
+	result := make([]domain.SigningKey, len(keys))
+	for i, key := range keys {
+		converted, err := s.convertSigningKey(key)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "convert signing key %s", key.Id)
+		}
+		result[i] = *converted
+	}
+	return result, nil
+}
+
+// Rotate generates a new active key, the previous active key becomes retiring
+// and verifies already issued tokens until it is retired
+func (s SigningKey) Rotate(ctx context.Context) (*domain.SigningKey, error) {
+	var key *entity.SigningKey
+	err := s.tx.SigningKeyTx(ctx, func(ctx context.Context, tx SigningKeyTx) error {
+		err := tx.LockSigningKeys(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "lock signing keys")
+		}
+
+		key, err = s.rotate(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "signing key transaction")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{SigningKeys: true})
+
+	return s.convertSigningKey(*key)
+}
+
+// Retire retires the retiring key, tokens signed with it become invalid
+func (s SigningKey) Retire(ctx context.Context, id string) ([]domain.SigningKey, error) {
+	err := s.tx.SigningKeyTx(ctx, func(ctx context.Context, tx SigningKeyTx) error {
+		err := tx.LockSigningKeys(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "lock signing keys")
+		}
+
+		key, err := tx.GetSigningKeyById(ctx, id)
+		if err != nil {
+			return errors.WithMessage(err, "get signing key by id")
+		}
+		if key == nil {
+			return domain.ErrSigningKeyNotFound
+		}
+		if key.Status == entity.SigningKeyStatusActive {
+			return domain.ErrSigningKeyActive
+		}
+
+		err = tx.RetireSigningKeys(ctx, []string{id})
+		if err != nil {
+			return errors.WithMessage(err, "retire signing keys")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "signing key transaction")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{SigningKeys: true})
+	s.logger.Info(ctx, "signing key retired", log.String("kid", id))
+
+	return s.List(ctx)
+}
+
+// CheckEncryption returns an error if signing keys which are still in use exist,
+// but the key encryption secret is not set, so private keys could not be read
+func (s SigningKey) CheckEncryption(ctx context.Context) error {
+	if s.cipher.HasSecret() {
+		return nil
+	}
+
+	keys, err := s.repo.GetSigningKeys(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "get signing keys")
+	}
+	for _, key := range keys {
+		if key.Status != entity.SigningKeyStatusRetired {
+			return errors.WithMessagef(jwt.ErrNoEncryptionSecret, "signing key %s is %s", key.Id, key.Status)
+		}
+	}
+	return nil
+}
+
+// RotateIfNeeded generates the first key, rotates the active key older than the rotation interval
+// and retires retiring keys older than the overlap.
+// Keys are locked, so concurrent calls from several instances are safe
+func (s SigningKey) RotateIfNeeded(ctx context.Context) error {
+	changed := false
+	err := s.tx.SigningKeyTx(ctx, func(ctx context.Context, tx SigningKeyTx) error {
+		err := tx.LockSigningKeys(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "lock signing keys")
+		}
+
+		keys, err := tx.GetNotRetiredSigningKeys(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "get not retired signing keys")
+		}
+
+		now := time.Now().UTC()
+		var active *entity.SigningKey
+		expired := make([]string, 0)
+		for _, key := range keys {
+			switch {
+			case key.Status == entity.SigningKeyStatusActive:
+				active = &key
+			case s.rotation.Overlap > 0 && key.RetiringAt.Valid && now.Sub(key.RetiringAt.Time) >= s.rotation.Overlap:
+				expired = append(expired, key.Id)
+			}
+		}
+
+		if (active == nil && s.rotation.Bootstrap) ||
+			(active != nil && s.rotation.Interval > 0 && now.Sub(active.CreatedAt) >= s.rotation.Interval) {
+			_, err = s.rotate(ctx, tx)
+			if err != nil {
+				return err
+			}
+			changed = true
+		}
+
+		if len(expired) > 0 {
+			err = tx.RetireSigningKeys(ctx, expired)
+			if err != nil {
+				return errors.WithMessage(err, "retire signing keys")
+			}
+			s.logger.Info(ctx, "signing keys retired", log.Any("kids", expired))
+			changed = true
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "signing key transaction")
+	}
+	if changed {
+		s.invalidator.Invalidate(ctx, domain.CacheInvalidation{SigningKeys: true})
+	}
+
+	return nil
+}
+
+func (s SigningKey) rotate(ctx context.Context, tx SigningKeyTx) (*entity.SigningKey, error) {
+	id, err := randomHex(tokenIdBytes)
+	if err != nil {
+		return nil, errors.WithMessage(err, "generate key id")
+	}
+	_, privateKey, err := jwt.GenerateKey(id, s.rotation.Algorithm)
+	if err != nil {
+		return nil, errors.WithMessage(err, "generate key")
+	}
+	encrypted, err := s.cipher.Encrypt(privateKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "encrypt private key")
+	}
+
+	err = tx.MarkActiveSigningKeyRetiring(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "mark active signing key retiring")
+	}
+	key, err := tx.InsertSigningKey(ctx, entity.SigningKey{
+		Id:         id,
+		Algorithm:  s.rotation.Algorithm,
+		PrivateKey: encrypted,
+		Status:     entity.SigningKeyStatusActive,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "insert signing key")
+	}
+	s.logger.Info(ctx, "signing key rotated", log.String("kid", id))
+
+	return key, nil
+}
+
+func (s SigningKey) convertSigningKey(key entity.SigningKey) (*domain.SigningKey, error) {
+	result := &domain.SigningKey{
+		Id:        key.Id,
+		Algorithm: key.Algorithm,
+		Status:    key.Status,
+		CreatedAt: key.CreatedAt,
+	}
+	if key.RetiringAt.Valid {
+		result.RetiringAt = &key.RetiringAt.Time
+	}
+	if key.RetiredAt.Valid {
+		result.RetiredAt = &key.RetiredAt.Time
+	}
+	if key.Status == entity.SigningKeyStatusRetired {
+		return result, nil
+	}
+
+	privateKey, err := s.cipher.Decrypt(key.PrivateKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "decrypt private key")
+	}
+	parsed, err := jwt.ParseKey(key.Id, privateKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "parse private key")
+	}
+	publicKey := parsed.Jwk()
+	result.PublicKey = &publicKey
+	return result, nil
+}
+
+// SigningKeyRotationJob runs scheduled rotation by worker
+type SigningKeyRotationJob struct {
+	service SigningKey
+	logger  log.Logger
+}
+
+func NewSigningKeyRotationJob(service SigningKey, logger log.Logger) SigningKeyRotationJob {
+	return SigningKeyRotationJob{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (j SigningKeyRotationJob) Do(ctx context.Context) {
+	ctx = log.ToContext(ctx, log.String("worker", "signing_key_rotation"))
+	err := j.service.RotateIfNeeded(ctx)
+	if err != nil {
+		j.logger.Error(ctx, errors.WithMessage(err, "rotate signing keys"))
+	}
+}
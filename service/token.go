@@ -2,15 +2,22 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"slices"
+	"time"
 
 	"isp-system-service/domain"
 	"isp-system-service/entity"
+	"isp-system-service/service/acl"
+	"isp-system-service/service/tokenhash"
 
 	"github.com/pkg/errors"
 )
 
 type ApplicationTokenCreator interface {
-	CreateApplicationToken() (string, error)
+	CreateApplicationToken(ctx context.Context, authData entity.AuthData) (string, error)
+	Identify(token string) (string, string, error)
+	Kind(token string) string
 }
 
 type AppEnricher interface {
@@ -18,29 +25,55 @@ type AppEnricher interface {
 }
 
 type TokenCreateTx interface {
-	SaveToken(ctx context.Context, token string, appId int, expireTime int) (*entity.Token, error)
+	AuditWriter
+	ChangeEventWriter
+	SaveToken(ctx context.Context, token entity.Token) (*entity.Token, error)
 }
 
 type TokenRevokeTx interface {
-	DeleteToken(ctx context.Context, tokens []string) (int, error)
+	AuditWriter
+	ChangeEventWriter
+	GetTokenByAppIdList(ctx context.Context, appIdList []int) ([]entity.Token, error)
+	DeleteToken(ctx context.Context, tokenHashes []string) (int, error)
+	DeleteTokensByIdList(ctx context.Context, appId int, idList []string) ([]string, error)
+	ScheduleJwtRevocation(ctx context.Context, appId int, id string, revokeAt time.Time) error
+	ShortenTokenExpiry(ctx context.Context, appId int, id string, expiresAt time.Time) (*entity.Token, error)
+}
+
+type TokenRotateTx interface {
+	TokenCreateTx
+	GetTokenByAppIdAndId(ctx context.Context, appId int, id string) (*entity.Token, error)
+	ScheduleJwtRevocation(ctx context.Context, appId int, id string, revokeAt time.Time) error
+	ShortenTokenExpiry(ctx context.Context, appId int, id string, expiresAt time.Time) (*entity.Token, error)
+}
+
+type TokenHashTx interface {
+	GetNotHashedTokens(ctx context.Context) ([]string, error)
+	ReplaceWithHashes(ctx context.Context, tokens []string, tokenHashes []string, maskedTokens []string) error
 }
 
 type TokenTxRunner interface {
 	TokenCreateTx(ctx context.Context, tx func(ctx context.Context, tx TokenCreateTx) error) error
 	TokenRevokeTx(ctx context.Context, tx func(ctx context.Context, tx TokenRevokeTx) error) error
+	TokenRotateTx(ctx context.Context, tx func(ctx context.Context, tx TokenRotateTx) error) error
+	TokenHashTx(ctx context.Context, tx func(ctx context.Context, tx TokenHashTx) error) error
 }
 
 type Token struct {
-	jwt         ApplicationTokenCreator
-	appEnricher AppEnricher
-	tx          TokenTxRunner
-	appRepo     ApplicationRepo
-	domainRepo  DomainRepo
-	serviceRepo AppGroupRepo
-	tokenRepo   TokenRepo
+	rotationOverlap time.Duration
+	jwt             ApplicationTokenCreator
+	appEnricher     AppEnricher
+	tx              TokenTxRunner
+	appRepo         ApplicationRepo
+	domainRepo      DomainRepo
+	serviceRepo     AppGroupRepo
+	tokenRepo       TokenRepo
+	invalidator     CacheInvalidator
+	hasher          tokenhash.Hasher
 }
 
 func NewToken(
+	rotationOverlap time.Duration,
 	jwtGenerate ApplicationTokenCreator,
 	appEnricher AppEnricher,
 	tx TokenTxRunner,
@@ -48,60 +81,66 @@ func NewToken(
 	domainRepo DomainRepo,
 	appGroupRepo AppGroupRepo,
 	tokenRepo TokenRepo,
+	invalidator CacheInvalidator,
+	hasher tokenhash.Hasher,
 ) Token {
 	return Token{
-		appEnricher: appEnricher,
-		jwt:         jwtGenerate,
-		tx:          tx,
-		appRepo:     appRepo,
-		domainRepo:  domainRepo,
-		serviceRepo: appGroupRepo,
-		tokenRepo:   tokenRepo,
+		rotationOverlap: rotationOverlap,
+		appEnricher:     appEnricher,
+		jwt:             jwtGenerate,
+		tx:              tx,
+		appRepo:         appRepo,
+		domainRepo:      domainRepo,
+		serviceRepo:     appGroupRepo,
+		tokenRepo:       tokenRepo,
+		invalidator:     invalidator,
+		hasher:          hasher,
 	}
 }
 
-func (s Token) GetByAppId(ctx context.Context, appId int) ([]domain.Token, error) {
-	tokenEntity, err := s.tokenRepo.GetTokenByAppIdList(ctx, []int{appId})
+// GetByAppId returns the page of tokens of the application with the total count
+func (s Token) GetByAppId(ctx context.Context, req domain.TokenListRequest) (*domain.TokenListResponse, error) {
+	filter, err := listFilter[string](req.Page, []string{domain.ListSortId, domain.ListSortCreatedAt})
 	if err != nil {
-		return nil, errors.WithMessage(err, "get token by app_id list")
+		return nil, err
 	}
 
-	result := make([]domain.Token, len(tokenEntity))
-	for i, token := range tokenEntity {
-		result[i] = domain.Token(token)
-	}
-
-	return result, nil
-}
-
-func (s Token) Create(ctx context.Context, req domain.TokenCreateRequest) (*domain.ApplicationWithTokens, error) {
-	applicationEntity, err := s.appRepo.GetApplicationById(ctx, req.AppId)
+	tokens, err := s.tokenRepo.ListTokens(ctx, req.Id, filter)
 	if err != nil {
-		return nil, errors.WithMessage(err, "get application by id")
+		return nil, errors.WithMessage(err, "list tokens")
 	}
-
-	serviceEntity, err := s.serviceRepo.GetAppGroupById(ctx, applicationEntity.ApplicationGroupId)
+	total, err := s.tokenRepo.CountTokens(ctx, req.Id, filter)
 	if err != nil {
-		return nil, errors.WithMessage(err, "get service by id")
+		return nil, errors.WithMessage(err, "count tokens")
 	}
 
-	_, err = s.domainRepo.GetDomainById(ctx, serviceEntity.DomainId)
-	if err != nil {
-		return nil, errors.WithMessage(err, "get domain by id")
+	result := &domain.TokenListResponse{
+		Total: total,
+	}
+	if len(tokens) > filter.Limit {
+		tokens = tokens[:filter.Limit]
+		last := tokens[len(tokens)-1]
+		result.NextCursor, err = listNextCursor(req.Page, last.Id, "", last.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
 	}
+	result.Items = make([]domain.Token, len(tokens))
+	for i, token := range tokens {
+		result.Items[i] = convertToken(token)
+	}
+	return result, nil
+}
 
-	token, err := s.jwt.CreateApplicationToken()
+func (s Token) Create(ctx context.Context, req domain.TokenCreateRequest) (*domain.TokenCreateResponse, error) {
+	applicationEntity, token, issued, err := s.issueToken(ctx, req)
 	if err != nil {
-		return nil, errors.WithMessage(err, "create application token")
+		return nil, err
 	}
 
 	err = s.tx.TokenCreateTx(ctx, func(ctx context.Context, tx TokenCreateTx) error {
-		_, err = tx.SaveToken(ctx, token, req.AppId, req.ExpireTimeMs)
-		if err != nil {
-			return errors.WithMessage(err, "tx save token")
-		}
-
-		return nil
+		_, err := saveToken(ctx, tx, issued)
+		return err
 	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "token create transaction")
@@ -112,7 +151,11 @@ func (s Token) Create(ctx context.Context, req domain.TokenCreateRequest) (*doma
 		return nil, errors.WithMessage(err, "application enrich with tokens")
 	}
 
-	return arr[0], nil
+	return &domain.TokenCreateResponse{
+		Token:  token,
+		App:    arr[0].App,
+		Tokens: arr[0].Tokens,
+	}, nil
 }
 
 func (s Token) Revoke(ctx context.Context, req domain.TokenRevokeRequest) (*domain.ApplicationWithTokens, error) {
@@ -121,7 +164,7 @@ func (s Token) Revoke(ctx context.Context, req domain.TokenRevokeRequest) (*doma
 		return nil, errors.WithMessage(err, "get application by id")
 	}
 
-	_, err = s.revokeTokens(ctx, req.Tokens)
+	err = s.revokeTokensByIdList(ctx, req.AppId, req.TokenIdList)
 	if err != nil {
 		return nil, errors.WithMessage(err, "revoke tokens")
 	}
@@ -139,35 +182,322 @@ func (s Token) RevokeByAppId(ctx context.Context, appId int) (*domain.DeleteResp
 	if err != nil {
 		return nil, errors.WithMessage(err, "get token by app_id list")
 	}
+	for _, token := range tokens {
+		if token.ManagedByConfig {
+			return nil, errors.WithMessagef(domain.ErrManagedByConfig, "token %s", token.Id)
+		}
+	}
 
-	tokenIdList := make([]string, len(tokens))
-	for i, t := range tokens {
-		tokenIdList[i] = t.Token
+	return s.revokeTokens(ctx, tokens)
+}
+
+// Rotate issues a new token with the scope and allowed CIDR of the previous one for its application,
+// the previous token expires after the overlap period unless it expires earlier
+func (s Token) Rotate(ctx context.Context, req domain.TokenRotateRequest) (*domain.TokenRotateResponse, error) {
+	overlap := s.rotationOverlap
+	if req.OverlapHours > 0 {
+		overlap = time.Duration(req.OverlapHours) * time.Hour
+	}
+
+	var (
+		token    string
+		created  *entity.Token
+		previous *entity.Token
+	)
+	err := s.tx.TokenRotateTx(ctx, func(ctx context.Context, tx TokenRotateTx) error {
+		found, err := tx.GetTokenByAppIdAndId(ctx, req.AppId, req.TokenId)
+		if err != nil {
+			return errors.WithMessage(err, "tx get token by app_id and id")
+		}
+		if found == nil {
+			return errors.WithMessagef(domain.ErrTokenNotFound, "token %s", req.TokenId)
+		}
+		if found.ManagedByConfig {
+			return errors.WithMessagef(domain.ErrManagedByConfig, "token %s", req.TokenId)
+		}
+
+		// the new token keeps the restrictions of the previous one
+		var issued entity.Token
+		_, token, issued, err = s.issueToken(ctx, domain.TokenCreateRequest{
+			AppId:        req.AppId,
+			ExpireTimeMs: req.ExpireTimeMs,
+			Scope:        found.Scope,
+			AllowedCidrs: found.AllowedCidrs,
+		})
+		if err != nil {
+			return err
+		}
+		created, err = saveToken(ctx, tx, issued)
+		if err != nil {
+			return err
+		}
+
+		expiresAt := time.Now().UTC().Add(overlap)
+		// the deny list entry takes the original expiration, so it is added before the expiration is shortened
+		err = tx.ScheduleJwtRevocation(ctx, req.AppId, req.TokenId, expiresAt)
+		if err != nil {
+			return errors.WithMessage(err, "tx schedule jwt revocation")
+		}
+		previous, err = tx.ShortenTokenExpiry(ctx, req.AppId, req.TokenId, expiresAt)
+		if err != nil {
+			return errors.WithMessage(err, "tx shorten token expiry")
+		}
+		if previous == nil {
+			return errors.WithMessagef(domain.ErrTokenNotFound, "token %s", req.TokenId)
+		}
+		return WriteAuditEvent(ctx, tx, entity.AuditEntityToken, "rotate", previous.Id, convertToken(*found), convertToken(*previous))
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "token rotate transaction")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{TokenHashes: []string{previous.TokenHash}})
+
+	return &domain.TokenRotateResponse{
+		Token:         token,
+		NewToken:      convertToken(*created),
+		PreviousToken: convertToken(*previous),
+	}, nil
+}
+
+func (s Token) GetUnused(ctx context.Context, req domain.GetUnusedTokensRequest) ([]domain.Token, error) {
+	tokens, err := s.tokenRepo.GetUnusedTokens(ctx, req.AppId, time.Duration(req.UnusedDays)*24*time.Hour)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get unused tokens")
+	}
+
+	result := make([]domain.Token, len(tokens))
+	for i, token := range tokens {
+		result[i] = convertToken(token)
+	}
+	return result, nil
+}
+
+// HashLegacyTokens replaces raw tokens stored before hashing was introduced with their hashes.
+// Rows are locked, so concurrent calls from several instances are safe
+func (s Token) HashLegacyTokens(ctx context.Context) error {
+	err := s.tx.TokenHashTx(ctx, func(ctx context.Context, tx TokenHashTx) error {
+		tokens, err := tx.GetNotHashedTokens(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "get not hashed tokens")
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+
+		maskedTokens := make([]string, len(tokens))
+		for i, token := range tokens {
+			_, maskedTokens[i], err = s.jwt.Identify(token)
+			if err != nil {
+				return errors.WithMessage(err, "identify token")
+			}
+		}
+
+		err = tx.ReplaceWithHashes(ctx, tokens, s.hasher.HashList(tokens), maskedTokens)
+		if err != nil {
+			return errors.WithMessage(err, "replace tokens with hashes")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "token hash transaction")
+	}
+
+	return nil
+}
+
+// issueToken creates the token of the application, the token is not saved
+func (s Token) issueToken(
+	ctx context.Context,
+	req domain.TokenCreateRequest,
+) (*entity.Application, string, entity.Token, error) {
+	applicationEntity, err := s.appRepo.GetApplicationById(ctx, req.AppId)
+	if err != nil {
+		return nil, "", entity.Token{}, errors.WithMessage(err, "get application by id")
+	}
+
+	serviceEntity, err := s.serviceRepo.GetAppGroupById(ctx, applicationEntity.ApplicationGroupId)
+	if err != nil {
+		return nil, "", entity.Token{}, errors.WithMessage(err, "get service by id")
+	}
+
+	domainEntity, err := s.domainRepo.GetDomainById(ctx, serviceEntity.DomainId)
+	if err != nil {
+		return nil, "", entity.Token{}, errors.WithMessage(err, "get domain by id")
+	}
+
+	for _, method := range req.Scope {
+		if !acl.IsValidPattern(method) {
+			return nil, "", entity.Token{}, errors.WithMessagef(domain.ErrInvalidMethodPattern, "scope %s", method)
+		}
 	}
 
-	return s.revokeTokens(ctx, tokenIdList)
+	createdAt := time.Now().UTC()
+	expiresAt := sql.NullTime{}
+	if req.ExpireTimeMs != -1 {
+		expiresAt = sql.NullTime{Time: createdAt.Add(time.Duration(req.ExpireTimeMs) * time.Millisecond), Valid: true}
+	}
+	token, err := s.jwt.CreateApplicationToken(ctx, entity.AuthData{
+		AppName:            applicationEntity.Name,
+		SystemId:           domainEntity.SystemId,
+		DomainId:           domainEntity.Id,
+		ApplicationGroupId: serviceEntity.Id,
+		AppId:              applicationEntity.Id,
+		ExpiresAt:          expiresAt,
+		CreatedAt:          createdAt,
+		Scope:              req.Scope,
+		AllowedCidrs:       req.AllowedCidrs,
+	})
+	if err != nil {
+		return nil, "", entity.Token{}, errors.WithMessage(err, "create application token")
+	}
+	id, maskedToken, err := s.jwt.Identify(token)
+	if err != nil {
+		return nil, "", entity.Token{}, errors.WithMessage(err, "identify application token")
+	}
+
+	return applicationEntity, token, entity.Token{
+		Id:           id,
+		TokenHash:    s.hasher.Hash(token),
+		MaskedToken:  maskedToken,
+		Kind:         s.jwt.Kind(token),
+		AppId:        req.AppId,
+		ExpireTime:   req.ExpireTimeMs,
+		ExpiresAt:    expiresAt,
+		Scope:        req.Scope,
+		AllowedCidrs: req.AllowedCidrs,
+	}, nil
 }
 
-func (s Token) revokeTokens(ctx context.Context, tokens []string) (*domain.DeleteResponse, error) {
+// saveToken saves the issued token with its audit and change events
+func saveToken(ctx context.Context, tx TokenCreateTx, token entity.Token) (*entity.Token, error) {
+	saved, err := tx.SaveToken(ctx, token)
+	if err != nil {
+		return nil, errors.WithMessage(err, "tx save token")
+	}
+
+	err = WriteAuditEvent(ctx, tx, entity.AuditEntityToken, "create", saved.Id, nil, convertToken(*saved))
+	if err != nil {
+		return nil, err
+	}
+	err = WriteChangeEvent(ctx, tx, domain.ChangeEventTokenCreated, domain.TokenChange{
+		TokenId: saved.Id,
+		AppId:   saved.AppId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (s Token) revokeTokensByIdList(ctx context.Context, appId int, idList []string) error {
+	if len(idList) == 0 {
+		return nil
+	}
+
+	var tokenHashes []string
+	err := s.tx.TokenRevokeTx(ctx, func(ctx context.Context, tx TokenRevokeTx) error {
+		tokens, err := tx.GetTokenByAppIdList(ctx, []int{appId})
+		if err != nil {
+			return errors.WithMessage(err, "tx get token by app_id list")
+		}
+		for _, token := range tokens {
+			if token.ManagedByConfig && slices.Contains(idList, token.Id) {
+				return errors.WithMessagef(domain.ErrManagedByConfig, "token %s", token.Id)
+			}
+		}
+
+		tokenHashes, err = tx.DeleteTokensByIdList(ctx, appId, idList)
+		if err != nil {
+			return errors.WithMessage(err, "tx delete tokens by id list")
+		}
+
+		for _, token := range tokens {
+			if !slices.Contains(tokenHashes, token.TokenHash) {
+				continue
+			}
+			err = writeTokenRevocation(ctx, tx, token)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "token revoke transaction")
+	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{TokenHashes: tokenHashes})
+
+	return nil
+}
+
+func (s Token) revokeTokens(ctx context.Context, tokens []entity.Token) (*domain.DeleteResponse, error) {
 	if len(tokens) == 0 {
 		return &domain.DeleteResponse{Deleted: 0}, nil
 	}
 
+	tokenHashes := make([]string, len(tokens))
+	for i, t := range tokens {
+		tokenHashes[i] = t.TokenHash
+	}
+
 	var count int
 	err := s.tx.TokenRevokeTx(ctx, func(ctx context.Context, tx TokenRevokeTx) error {
-		deleted, err := tx.DeleteToken(ctx, tokens)
+		deleted, err := tx.DeleteToken(ctx, tokenHashes)
 		if err != nil {
 			return errors.WithMessage(err, "tx delete token")
 		}
 
+		for _, token := range tokens {
+			err = writeTokenRevocation(ctx, tx, token)
+			if err != nil {
+				return err
+			}
+		}
+
 		count = deleted
 		return nil
 	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "token revoke transaction")
 	}
+	s.invalidator.Invalidate(ctx, domain.CacheInvalidation{TokenHashes: tokenHashes})
 
 	return &domain.DeleteResponse{
 		Deleted: count,
 	}, nil
 }
+
+func convertToken(token entity.Token) domain.Token {
+	result := domain.Token{
+		Id:          token.Id,
+		MaskedToken: token.MaskedToken,
+		Kind:        token.Kind,
+		AppId:       token.AppId,
+		ExpireTime:  token.ExpireTime,
+		CreatedAt:   token.CreatedAt,
+	}
+	if token.ExpiresAt.Valid {
+		result.ExpiresAt = &token.ExpiresAt.Time
+	}
+	if token.LastUsedAt.Valid {
+		result.LastUsedAt = &token.LastUsedAt.Time
+	}
+	result.LastUsedAddress = token.LastUsedAddress.String
+	result.Scope = token.Scope
+	result.AllowedCidrs = token.AllowedCidrs
+	result.ManagedByConfig = token.ManagedByConfig
+	return result
+}
+
+// writeTokenRevocation records the revoked token and publishes its revocation to subscribers
+func writeTokenRevocation(ctx context.Context, tx TokenRevokeTx, token entity.Token) error {
+	err := WriteAuditEvent(ctx, tx, entity.AuditEntityToken, "revoke", token.Id, convertToken(token), nil)
+	if err != nil {
+		return err
+	}
+	return WriteChangeEvent(ctx, tx, domain.ChangeEventTokenRevoked, domain.TokenChange{
+		TokenId: token.Id,
+		AppId:   token.AppId,
+	})
+}
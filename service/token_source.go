@@ -1,25 +1,118 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"strings"
+
+	"isp-system-service/entity"
+	"isp-system-service/service/jwt"
 
 	"github.com/pkg/errors"
 )
 
-type TokenSource struct{}
+const (
+	tokenPrefix      = "isp"
+	tokenIdBytes     = 8
+	tokenSecretBytes = 128
+	maskedTailLength = 4
+	maskedPart       = "****"
+)
+
+type TokenSource struct {
+	jwtEnabled bool
+	jwtKeys    *jwt.KeyStore
+}
+
+func NewTokenSource(jwtEnabled bool, jwtKeys *jwt.KeyStore) TokenSource {
+	return TokenSource{
+		jwtEnabled: jwtEnabled,
+		jwtKeys:    jwtKeys,
+	}
+}
+
+// CreateApplicationToken returns JWT signed with the current key if JWT mode is enabled,
+// otherwise token in format isp_<id>_<secret>.
+// The id (jti for JWT) is public and is used to display and revoke the token
+func (s TokenSource) CreateApplicationToken(ctx context.Context, authData entity.AuthData) (string, error) {
+	id, err := randomHex(tokenIdBytes)
+	if err != nil {
+		return "", errors.WithMessage(err, "generate token id")
+	}
+
+	if s.jwtEnabled {
+		return s.createJwt(ctx, id, authData)
+	}
+	secret, err := randomHex(tokenSecretBytes)
+	if err != nil {
+		return "", errors.WithMessage(err, "generate token secret")
+	}
+
+	return strings.Join([]string{tokenPrefix, id, secret}, "_"), nil
+}
+
+// Identify returns the public id and the masked form of the token.
+// Tokens issued before the isp_<id>_<secret> format get a random id
+func (s TokenSource) Identify(token string) (string, string, error) {
+	tail := token[max(len(token)-maskedTailLength, 0):]
 
-func NewTokenSource() TokenSource {
-	return TokenSource{}
+	if jwt.IsJwt(token) {
+		id, err := jwt.UnverifiedId(token)
+		if err != nil {
+			return "", "", errors.WithMessage(err, "get jwt id")
+		}
+		return id, maskedPart + tail, nil
+	}
+
+	parts := strings.SplitN(token, "_", 3) //nolint:mnd
+	if len(parts) == 3 && parts[0] == tokenPrefix && parts[1] != "" && parts[2] != "" {
+		return parts[1], strings.Join([]string{tokenPrefix, parts[1], maskedPart + tail}, "_"), nil
+	}
+
+	id, err := randomHex(tokenIdBytes)
+	if err != nil {
+		return "", "", errors.WithMessage(err, "generate token id")
+	}
+	return id, maskedPart + tail, nil
 }
 
-func (s TokenSource) CreateApplicationToken() (string, error) {
-	cryptoRand := make([]byte, 128) //nolint:mnd
+func (s TokenSource) createJwt(ctx context.Context, id string, authData entity.AuthData) (string, error) {
+	claims := jwt.Claims{
+		Id:           id,
+		AppId:        authData.AppId,
+		AppName:      authData.AppName,
+		AppGroupId:   authData.ApplicationGroupId,
+		DomainId:     authData.DomainId,
+		SystemId:     authData.SystemId,
+		IssuedAt:     authData.CreatedAt.Unix(),
+		Scope:        strings.Join(authData.Scope, " "),
+		AllowedCidrs: authData.AllowedCidrs,
+	}
+	if authData.ExpiresAt.Valid {
+		claims.ExpiresAt = authData.ExpiresAt.Time.Unix()
+	}
+
+	token, err := s.jwtKeys.Sign(ctx, claims)
+	if err != nil {
+		return "", errors.WithMessage(err, "sign jwt")
+	}
+	return token, nil
+}
+
+// Kind returns entity.TokenKindJwt for JWT and entity.TokenKindOpaque otherwise
+func (s TokenSource) Kind(token string) string {
+	if jwt.IsJwt(token) {
+		return entity.TokenKindJwt
+	}
+	return entity.TokenKindOpaque
+}
+
+func randomHex(size int) (string, error) {
+	cryptoRand := make([]byte, size)
 	_, err := rand.Read(cryptoRand)
 	if err != nil {
 		return "", errors.WithMessage(err, "crypto/rand read")
 	}
-	random := hex.EncodeToString(cryptoRand)
-
-	return random, nil
+	return hex.EncodeToString(cryptoRand), nil
 }
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/log"
+)
+
+const (
+	tokenSweepLockKey = "isp-system-service.token_sweep"
+)
+
+type TokenSweepTx interface {
+	TryLock(ctx context.Context, key string) (bool, error)
+	DeleteExpiredTokens(ctx context.Context, gracePeriod time.Duration) (int, error)
+	DeleteExpiredRevokedTokens(ctx context.Context, gracePeriod time.Duration) (int, error)
+}
+
+type TokenSweepTxRunner interface {
+	TokenSweepTx(ctx context.Context, tx func(ctx context.Context, tx TokenSweepTx) error) error
+}
+
+type TokenSweepStatusRepo interface {
+	SaveTokenSweepStatus(ctx context.Context, status entity.TokenSweepStatus) error
+	GetTokenSweepStatus(ctx context.Context) (*entity.TokenSweepStatus, error)
+}
+
+// TokenSweeper deletes tokens and deny list entries expired longer than the grace period ago,
+// it is run by worker on every instance, but only one instance sweeps at a time
+type TokenSweeper struct {
+	enabled     bool
+	gracePeriod time.Duration
+	tx          TokenSweepTxRunner
+	statusRepo  TokenSweepStatusRepo
+	logger      log.Logger
+}
+
+func NewTokenSweeper(
+	enabled bool,
+	gracePeriod time.Duration,
+	tx TokenSweepTxRunner,
+	statusRepo TokenSweepStatusRepo,
+	logger log.Logger,
+) TokenSweeper {
+	return TokenSweeper{
+		enabled:     enabled,
+		gracePeriod: gracePeriod,
+		tx:          tx,
+		statusRepo:  statusRepo,
+		logger:      logger,
+	}
+}
+
+func (s TokenSweeper) Do(ctx context.Context) {
+	if !s.enabled {
+		return
+	}
+
+	ctx = log.ToContext(ctx, log.String("worker", "token_sweeper"))
+	status := entity.TokenSweepStatus{
+		StartedAt: time.Now().UTC(),
+	}
+	locked, err := s.sweep(ctx, &status)
+	if err == nil && !locked {
+		s.logger.Debug(ctx, "token sweep is locked by other instance, skip")
+		return
+	}
+	status.FinishedAt = time.Now().UTC()
+
+	if err != nil {
+		s.logger.Error(ctx, errors.WithMessage(err, "sweep expired tokens"))
+		status.Error = sql.NullString{String: err.Error(), Valid: true}
+	} else {
+		s.logger.Info(ctx, "expired tokens swept",
+			log.Int("deletedTokens", status.DeletedTokens),
+			log.Int("deletedRevokedTokens", status.DeletedRevokedTokens),
+		)
+	}
+
+	err = s.statusRepo.SaveTokenSweepStatus(ctx, status)
+	if err != nil {
+		s.logger.Error(ctx, errors.WithMessage(err, "save token sweep status"))
+	}
+}
+
+func (s TokenSweeper) Status(ctx context.Context) (*domain.TokenSweepStatus, error) {
+	status, err := s.statusRepo.GetTokenSweepStatus(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "get token sweep status")
+	}
+
+	result := &domain.TokenSweepStatus{
+		Enabled:          s.enabled,
+		GracePeriodHours: int(s.gracePeriod / time.Hour),
+	}
+	if status != nil {
+		result.LastRun = &domain.TokenSweepRun{
+			StartedAt:            status.StartedAt,
+			FinishedAt:           status.FinishedAt,
+			DeletedTokens:        status.DeletedTokens,
+			DeletedRevokedTokens: status.DeletedRevokedTokens,
+			Error:                status.Error.String,
+		}
+	}
+	return result, nil
+}
+
+func (s TokenSweeper) sweep(ctx context.Context, status *entity.TokenSweepStatus) (bool, error) {
+	locked := false
+	err := s.tx.TokenSweepTx(ctx, func(ctx context.Context, tx TokenSweepTx) error {
+		var err error
+		locked, err = tx.TryLock(ctx, tokenSweepLockKey)
+		if err != nil {
+			return errors.WithMessagef(err, "try lock %s", tokenSweepLockKey)
+		}
+		if !locked {
+			return nil
+		}
+
+		// deleted JWT are added to the deny list, so they are swept from it right away
+		status.DeletedTokens, err = tx.DeleteExpiredTokens(ctx, s.gracePeriod)
+		if err != nil {
+			return errors.WithMessage(err, "delete expired tokens")
+		}
+		status.DeletedRevokedTokens, err = tx.DeleteExpiredRevokedTokens(ctx, s.gracePeriod)
+		if err != nil {
+			return errors.WithMessage(err, "delete expired revoked tokens")
+		}
+		return nil
+	})
+	if err != nil {
+		return locked, errors.WithMessage(err, "token sweep transaction")
+	}
+
+	return locked, nil
+}
@@ -0,0 +1,33 @@
+package tokenhash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Hasher calculates HMAC-SHA256 digests of application tokens.
+// Only digests are stored in the database, so changing the secret invalidates all issued tokens
+type Hasher struct {
+	secret []byte
+}
+
+func New(secret string) Hasher {
+	return Hasher{
+		secret: []byte(secret),
+	}
+}
+
+func (h Hasher) Hash(token string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	_, _ = mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h Hasher) HashList(tokens []string) []string {
+	result := make([]string, len(tokens))
+	for i, token := range tokens {
+		result[i] = h.Hash(token)
+	}
+	return result
+}
@@ -0,0 +1,21 @@
+package tokenhash_test
+
+import (
+	"testing"
+
+	"isp-system-service/service/tokenhash"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasher_Hash(t *testing.T) {
+	t.Parallel()
+
+	hasher := tokenhash.New("secret")
+	hash := hasher.Hash("token")
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, hasher.Hash("token"))
+	require.NotEqual(t, hash, hasher.Hash("other_token"))
+	require.NotEqual(t, hash, tokenhash.New("other_secret").Hash("token"))
+	require.Equal(t, []string{hash}, hasher.HashList([]string{"token"}))
+}
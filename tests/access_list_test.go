@@ -12,6 +12,7 @@ import (
 	"isp-system-service/repository"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
 	"github.com/txix-open/isp-kit/grpc/client"
 	"github.com/txix-open/isp-kit/test"
 	"github.com/txix-open/isp-kit/test/dbt"
@@ -40,7 +41,8 @@ func (s *AccessListSuite) SetupTest() {
 	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
 
 	locator := assembly.NewLocator(s.testDb, s.test.Logger())
-	config := locator.Config(conf.Remote{})
+	config, err := locator.Config(conf.Remote{})
+	s.Require().NoError(err)
 	_, s.api = grpct.TestServer(s.test, config.Handler)
 	s.accessListRepo = repository.NewAccessList(s.testDb)
 
@@ -110,6 +112,62 @@ func (s *AccessListSuite) TestGetById_HappyPath() {
 	s.Require().ElementsMatch(expectedAccessList, actualAccessList)
 }
 
+func (s *AccessListSuite) TestGetById_Matched() {
+	accessList := []entity.AccessList{
+		{
+			AppId:  s.appId,
+			Method: "admin/*",
+			Value:  true,
+		},
+		{
+			AppId:  s.appId,
+			Method: "admin/user/*",
+			Value:  false,
+		},
+		{
+			AppId:  s.appId,
+			Method: "admin/**/get_*",
+			Value:  true,
+		},
+	}
+	err := s.accessListRepo.InsertArrayAccessList(s.T().Context(), accessList)
+	s.Require().NoError(err)
+
+	req := domain.AccessListGetByIdRequest{
+		Id:       s.appId,
+		Endpoint: "admin/user/delete",
+	}
+	var result []domain.AccessListItem
+	err = s.api.Invoke("system/access_list/get_by_id").
+		JsonRequestBody(&req).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(result, len(accessList))
+
+	matched := make([]string, 0)
+	for _, item := range result {
+		if item.Matched {
+			matched = append(matched, item.Method)
+		}
+	}
+	s.Require().Equal([]string{"admin/user/*"}, matched)
+}
+
+func (s *AccessListSuite) TestSetOne_InvalidPattern() {
+	req := domain.AccessListSetOneRequest{
+		AppId:  s.appId,
+		Method: "admin/**get",
+		Value:  true,
+	}
+	err := s.api.Invoke("system/access_list/set_one").
+		JsonRequestBody(&req).
+		Do(s.T().Context())
+	apiError := apierrors.FromError(err)
+	s.Require().NotNil(apiError)
+	s.Require().Equal(domain.ErrCodeInvalidMethodPattern, apiError.ErrorCode)
+}
+
 func (s *AccessListSuite) TestSetOne_HappyPath() {
 	expectedAccessList := []entity.AccessList{
 		{
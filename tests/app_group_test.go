@@ -40,7 +40,8 @@ func (s *AppGroupSuite) SetupTest() {
 	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
 
 	locator := assembly.NewLocator(s.testDb, s.test.Logger())
-	config := locator.Config(conf.Remote{})
+	config, err := locator.Config(conf.Remote{})
+	s.Require().NoError(err)
 	_, s.api = grpct.TestServer(s.test, config.Handler)
 	s.domainRepo = repository.NewDomain(s.testDb)
 	s.appGroupRepo = repository.NewAppGroup(s.testDb)
@@ -51,7 +52,7 @@ func (s *AppGroupSuite) SetupTest() {
 	VALUES ($1, $2, $3, $4)
 	RETURNING id, name, description, system_id, created_at, updated_at
 	`
-	_, err := s.testDb.Exec(s.T().Context(), q, 1, fake.It[string](), fake.It[string](), 1)
+	_, err = s.testDb.Exec(s.T().Context(), q, 1, fake.It[string](), fake.It[string](), 1)
 	s.Require().NoError(err)
 }
 
@@ -67,6 +68,7 @@ func (s *AppGroupSuite) TestGetByIdList() {
 			Description: appGroup.Description.String,
 			CreatedAt:   time.Time{},
 			UpdatedAt:   time.Time{},
+			Status:      domain.StatusActive,
 		})
 		ids = append(ids, appGroup.Id)
 	}
@@ -105,6 +107,7 @@ func (s *AppGroupSuite) TestGetByIdList_EmptyDescription() {
 			Description: "",
 			CreatedAt:   time.Time{},
 			UpdatedAt:   time.Time{},
+			Status:      domain.StatusActive,
 		})
 		ids = append(ids, appGroup.Id)
 	}
@@ -259,13 +262,17 @@ func (s *AppGroupSuite) TestGetAll() {
 			Description: appGroup.Description.String,
 			CreatedAt:   time.Time{},
 			UpdatedAt:   time.Time{},
+			Status:      domain.StatusActive,
 		})
 	}
-	result := []domain.AppGroup{}
+	response := domain.AppGroupListResponse{}
 	err := s.api.Invoke("system/application_group/get_all").
-		JsonResponseBody(&result).
+		JsonRequestBody(domain.ListRequest{}).
+		JsonResponseBody(&response).
 		Do(s.T().Context())
 	s.Require().NoError(err)
+	s.Require().Equal(len(appGroups), response.Total)
+	result := response.Items
 	for i := range result {
 		s.Require().NotEmpty(result[i].CreatedAt)
 		result[i].CreatedAt = time.Time{}
@@ -42,7 +42,8 @@ func (s *ApplicationSuite) SetupTest() {
 	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
 
 	locator := assembly.NewLocator(s.testDb, s.test.Logger())
-	config := locator.Config(conf.Remote{})
+	config, err := locator.Config(conf.Remote{})
+	s.Require().NoError(err)
 	_, s.api = grpct.TestServer(s.test, config.Handler)
 	s.domainRepo = repository.NewDomain(s.testDb)
 	s.appRepo = repository.NewApplication(s.testDb)
@@ -53,11 +54,15 @@ func (s *ApplicationSuite) SetupTest() {
 func (s *ApplicationSuite) TestGetAllApplications() {
 	expectedApps := s.insertApps(2)
 
-	result := []domain.Application{}
+	response := domain.ApplicationListResponse{}
 	err := s.api.Invoke("system/application/get_all").
-		JsonResponseBody(&result).
+		JsonRequestBody(domain.ListRequest{}).
+		JsonResponseBody(&response).
 		Do(s.T().Context())
 	s.Require().NoError(err)
+	s.Require().Equal(2, response.Total)
+	s.Require().Empty(response.NextCursor)
+	result := response.Items
 	for i := range result {
 		s.Require().NotEmpty(result[i].CreatedAt)
 		result[i].CreatedAt = time.Time{}
@@ -68,6 +73,70 @@ func (s *ApplicationSuite) TestGetAllApplications() {
 	s.Require().ElementsMatch(expectedApps, result)
 }
 
+func (s *ApplicationSuite) TestGetAllApplications_Pages() {
+	expectedApps := s.insertApps(3)
+
+	req := domain.ListRequest{Limit: 2, SortField: domain.ListSortId, SortDirection: domain.ListSortAsc}
+	first := s.listApplications(req)
+	s.Require().Equal(3, first.Total)
+	s.Require().Len(first.Items, 2)
+	s.Require().NotEmpty(first.NextCursor)
+
+	req.Cursor = first.NextCursor
+	second := s.listApplications(req)
+	s.Require().Equal(3, second.Total)
+	s.Require().Len(second.Items, 1)
+	s.Require().Empty(second.NextCursor)
+
+	idList := []int{first.Items[0].Id, first.Items[1].Id, second.Items[0].Id}
+	s.Require().IsIncreasing(idList)
+
+	filtered := s.listApplications(domain.ListRequest{Search: expectedApps[1].Name})
+	s.Require().Equal(1, filtered.Total)
+	s.Require().Equal(expectedApps[1].Id, filtered.Items[0].Id)
+
+	err := s.api.Invoke("system/application/get_all").
+		JsonRequestBody(domain.ListRequest{Cursor: first.NextCursor}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+}
+
+func (s *ApplicationSuite) TestGetApplications_Pages() {
+	expectedApps := s.insertApps(3)
+	idList := []int{expectedApps[0].Id, expectedApps[2].Id}
+
+	req := domain.ApplicationIdListRequest{
+		IdList: idList,
+		Page:   domain.ListRequest{Limit: 1, SortField: domain.ListSortId, SortDirection: domain.ListSortAsc},
+	}
+	first := domain.ApplicationWithTokensListResponse{}
+	err := s.api.Invoke("system/application/get_applications").
+		JsonRequestBody(req).
+		JsonResponseBody(&first).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(2, first.Total)
+	s.Require().Len(first.Items, 1)
+	s.Require().NotEmpty(first.NextCursor)
+	s.Require().Empty(first.Items[0].Tokens)
+
+	req.Page.Cursor = first.NextCursor
+	second := domain.ApplicationWithTokensListResponse{}
+	err = s.api.Invoke("system/application/get_applications").
+		JsonRequestBody(req).
+		JsonResponseBody(&second).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(second.Items, 1)
+	s.Require().Empty(second.NextCursor)
+	s.Require().ElementsMatch(idList, []int{first.Items[0].App.Id, second.Items[0].App.Id})
+
+	err = s.api.Invoke("system/application/get_applications").
+		JsonRequestBody(domain.ApplicationIdListRequest{}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+}
+
 func (s *ApplicationSuite) TestCreate_HappyPath() {
 	result := domain.ApplicationWithTokens{}
 	appGroup := s.createAppGroup()
@@ -97,6 +166,7 @@ func (s *ApplicationSuite) TestCreate_HappyPath() {
 		Description: apiReq.Description,
 		Type:        apiReq.Type,
 		ServiceId:   apiReq.ApplicationGroupId,
+		Status:      domain.StatusActive,
 	}
 	s.Require().Equal(expectedApp, result.App)
 	s.Require().Empty(result.Tokens)
@@ -182,6 +252,7 @@ func (s *ApplicationSuite) TestUpdate_HappyPath() {
 		Description: apiReq.Description,
 		Type:        inserted[0].Type,
 		ServiceId:   inserted[0].ServiceId,
+		Status:      domain.StatusActive,
 	}
 	s.Require().Equal(expectedApp, result.App)
 	s.Require().Empty(result.Tokens)
@@ -195,6 +266,7 @@ func (s *ApplicationSuite) TestUpdate_HappyPath() {
 		Description:        app.Description,
 		Type:               inserted[0].Type,
 		ApplicationGroupId: inserted[0].ServiceId,
+		Status:             domain.StatusActive,
 	}
 
 	s.Require().NotEmpty(app.CreatedAt)
@@ -263,7 +335,14 @@ func (s *ApplicationSuite) TestGetByToken() {
 		ApplicationId:      insertedApps[0].Id,
 		ApplicationGroupId: insertedApps[0].ServiceId,
 	}
-	_, err := s.tokenRepo.SaveToken(s.T().Context(), token, expectedApp.ApplicationId, fake.It[int]())
+	_, err := s.tokenRepo.SaveToken(s.T().Context(), entity.Token{
+		Id:          fake.It[string](),
+		TokenHash:   hashToken(token),
+		MaskedToken: "****",
+		Kind:        entity.TokenKindOpaque,
+		AppId:       expectedApp.ApplicationId,
+		ExpireTime:  fake.It[int](),
+	})
 	s.Require().NoError(err)
 
 	result := domain.GetApplicationByTokenResponse{}
@@ -336,7 +415,18 @@ func (s *ApplicationSuite) insertApps(appsCount uint) []domain.Application {
 			Type:        createdApp.Type,
 			CreatedAt:   time.Time{},
 			UpdatedAt:   time.Time{},
+			Status:      createdApp.Status,
 		})
 	}
 	return toExpect
 }
+
+func (s *ApplicationSuite) listApplications(req domain.ListRequest) domain.ApplicationListResponse {
+	result := domain.ApplicationListResponse{}
+	err := s.api.Invoke("system/application/get_all").
+		JsonRequestBody(req).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
@@ -0,0 +1,279 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"isp-system-service/assembly"
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+)
+
+func TestAuditSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &AuditSuite{})
+}
+
+type AuditSuite struct {
+	suite.Suite
+
+	test   *test.Test
+	testDb *dbt.TestDb
+	api    *client.Client
+}
+
+func (s *AuditSuite) SetupTest() {
+	s.test, _ = test.New(s.T())
+
+	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
+
+	config, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(conf.Remote{})
+	s.Require().NoError(err)
+	_, s.api = grpct.TestServer(s.test, config.Handler)
+
+	createdTime := time.Now().UTC()
+	InsertDomain(s.testDb, entity.Domain{
+		Id: 1, Name: "root", SystemId: 1, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+}
+
+func (s *AuditSuite) TestApplicationLifecycle() {
+	appGroup := domain.AppGroup{}
+	err := s.api.Invoke("system/application_group/create").
+		JsonRequestBody(domain.CreateAppGroupRequest{Name: "group"}).
+		JsonResponseBody(&appGroup).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/application/create_application").
+		AppendMetadata("x-admin-id", "42").
+		JsonRequestBody(domain.CreateApplicationRequest{
+			Id:                 20,
+			Name:               "app",
+			ApplicationGroupId: appGroup.Id,
+			Type:               domain.ApplicationSystemType,
+		}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/application/update_application").
+		AppendMetadata("x-admin-id", "43").
+		JsonRequestBody(domain.UpdateApplicationRequest{OldId: 20, NewId: 20, Name: "renamed"}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/access_list/set_one").
+		JsonRequestBody(domain.AccessListSetOneRequest{AppId: 20, Method: "orders/get", Value: true}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/application/delete_applications").
+		JsonRequestBody([]int{20}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	result := s.search(domain.AuditSearchRequest{
+		EntityType: entity.AuditEntityApplication,
+		EntityId:   "20",
+	})
+	s.Require().Len(result.Items, 3)
+	s.Require().Zero(result.NextCursor)
+
+	deleted, updated, created := result.Items[0], result.Items[1], result.Items[2]
+	s.Require().Equal("application.create", created.Action)
+	s.Require().Equal("admin:42", created.Actor)
+	s.Require().NotEmpty(created.RequestId)
+	s.Require().Nil(s.application(created.Before))
+	s.Require().Equal("app", s.application(created.After).Name)
+	s.Require().Equal(appGroup.Id, s.application(created.After).ServiceId)
+
+	s.Require().Equal("application.update", updated.Action)
+	s.Require().Equal("admin:43", updated.Actor)
+	s.Require().Equal("app", s.application(updated.Before).Name)
+	s.Require().Equal("renamed", s.application(updated.After).Name)
+
+	s.Require().Equal("application.delete", deleted.Action)
+	s.Require().Equal("renamed", s.application(deleted.Before).Name)
+	s.Require().Nil(s.application(deleted.After))
+
+	result = s.search(domain.AuditSearchRequest{
+		EntityType: entity.AuditEntityAccessList,
+		EntityId:   "application:20",
+	})
+	s.Require().Len(result.Items, 1)
+	s.Require().Equal("access_list.set_one", result.Items[0].Action)
+	methods := []domain.MethodInfo{}
+	err = json.Unmarshal(result.Items[0].After, &methods)
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.MethodInfo{{Method: "orders/get", Value: true}}, methods)
+}
+
+func (s *AuditSuite) TestRolesAndRateLimits() {
+	appGroup := domain.AppGroup{}
+	err := s.api.Invoke("system/application_group/create").
+		JsonRequestBody(domain.CreateAppGroupRequest{Name: "group"}).
+		JsonResponseBody(&appGroup).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	role := domain.Role{}
+	err = s.api.Invoke("system/role/create").
+		JsonRequestBody(domain.CreateRoleRequest{
+			Name:    "reader",
+			Methods: []domain.MethodInfo{{Method: "orders/get", Value: true}},
+		}).
+		JsonResponseBody(&role).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/role/update").
+		JsonRequestBody(domain.UpdateRoleRequest{Id: role.Id, Name: "writer"}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/role/set_for_application_group").
+		JsonRequestBody(domain.SetAppGroupRolesRequest{AppGroupId: appGroup.Id, RoleIdList: []int{role.Id}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/role/delete_list").
+		JsonRequestBody(domain.IdListRequest{IdList: []int{role.Id}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	result := s.search(domain.AuditSearchRequest{
+		EntityType: entity.AuditEntityRole,
+		EntityId:   strconv.Itoa(role.Id),
+	})
+	s.Require().Len(result.Items, 3)
+	deleted, updated, created := result.Items[0], result.Items[1], result.Items[2]
+	s.Require().Equal("role.create", created.Action)
+	s.Require().Equal("reader", s.role(created.After).Name)
+	s.Require().Equal("role.update", updated.Action)
+	s.Require().Len(s.role(updated.Before).Methods, 1)
+	s.Require().Equal("writer", s.role(updated.After).Name)
+	s.Require().Equal("role.delete", deleted.Action)
+	s.Require().Nil(s.role(deleted.After))
+
+	result = s.search(domain.AuditSearchRequest{
+		EntityType: entity.AuditEntityAppGroup,
+		Action:     "application_group.set_roles",
+	})
+	s.Require().Len(result.Items, 1)
+	s.Require().JSONEq("[]", string(result.Items[0].Before))
+	s.Require().JSONEq("["+strconv.Itoa(role.Id)+"]", string(result.Items[0].After))
+
+	rateLimit := domain.RateLimit{}
+	err = s.api.Invoke("system/rate_limit/set").
+		JsonRequestBody(domain.SetRateLimitRequest{AppGroupId: appGroup.Id, Method: "orders/*", RequestsPerSecond: 10}).
+		JsonResponseBody(&rateLimit).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/rate_limit/set").
+		JsonRequestBody(domain.SetRateLimitRequest{AppGroupId: appGroup.Id, Method: "orders/*", RequestsPerSecond: 20}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/rate_limit/delete_list").
+		JsonRequestBody(domain.IdListRequest{IdList: []int{rateLimit.Id}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	result = s.search(domain.AuditSearchRequest{
+		EntityType: entity.AuditEntityRateLimit,
+		EntityId:   strconv.Itoa(rateLimit.Id),
+	})
+	s.Require().Len(result.Items, 3)
+	deleted, replaced, created := result.Items[0], result.Items[1], result.Items[2]
+	s.Require().Equal("rate_limit.set", created.Action)
+	s.Require().Nil(s.rateLimit(created.Before))
+	s.Require().Equal("rate_limit.set", replaced.Action)
+	s.Require().Equal(10, s.rateLimit(replaced.Before).RequestsPerSecond)
+	s.Require().Equal(20, s.rateLimit(replaced.After).RequestsPerSecond)
+	s.Require().Equal("rate_limit.delete", deleted.Action)
+	s.Require().Nil(s.rateLimit(deleted.After))
+}
+
+func (s *AuditSuite) TestSearch_Pagination() {
+	for i := range 3 {
+		err := s.api.Invoke("system/application_group/create").
+			JsonRequestBody(domain.CreateAppGroupRequest{Name: "group" + strconv.Itoa(i)}).
+			Do(s.T().Context())
+		s.Require().NoError(err)
+	}
+
+	first := s.search(domain.AuditSearchRequest{EntityType: entity.AuditEntityAppGroup, Limit: 2})
+	s.Require().Len(first.Items, 2)
+	s.Require().NotZero(first.NextCursor)
+	s.Require().Greater(first.Items[0].Id, first.Items[1].Id)
+
+	second := s.search(domain.AuditSearchRequest{
+		EntityType: entity.AuditEntityAppGroup,
+		Limit:      2,
+		Cursor:     first.NextCursor,
+	})
+	s.Require().Len(second.Items, 1)
+	s.Require().Zero(second.NextCursor)
+	s.Require().Less(second.Items[0].Id, first.Items[1].Id)
+
+	exact := s.search(domain.AuditSearchRequest{EntityType: entity.AuditEntityAppGroup, Limit: 3})
+	s.Require().Len(exact.Items, 3)
+	s.Require().Zero(exact.NextCursor)
+
+	empty := s.search(domain.AuditSearchRequest{EntityType: entity.AuditEntityAppGroup, Action: "application_group.delete"})
+	s.Require().Empty(empty.Items)
+}
+
+func (s *AuditSuite) TestAppendOnly() {
+	err := s.api.Invoke("system/application_group/create").
+		JsonRequestBody(domain.CreateAppGroupRequest{Name: "group"}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	_, err = s.testDb.Exec(s.T().Context(), "UPDATE audit_event SET actor = 'someone'")
+	s.Require().Error(err)
+	_, err = s.testDb.Exec(s.T().Context(), "DELETE FROM audit_event")
+	s.Require().Error(err)
+}
+
+func (s *AuditSuite) search(req domain.AuditSearchRequest) domain.AuditSearchResponse {
+	result := domain.AuditSearchResponse{}
+	err := s.api.Invoke("system/audit/search").
+		JsonRequestBody(req).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
+
+func (s *AuditSuite) application(state json.RawMessage) *domain.Application {
+	var app *domain.Application
+	err := json.Unmarshal(state, &app)
+	s.Require().NoError(err)
+	return app
+}
+
+func (s *AuditSuite) role(state json.RawMessage) *domain.Role {
+	var role *domain.Role
+	err := json.Unmarshal(state, &role)
+	s.Require().NoError(err)
+	return role
+}
+
+func (s *AuditSuite) rateLimit(state json.RawMessage) *domain.RateLimit {
+	var rateLimit *domain.RateLimit
+	err := json.Unmarshal(state, &rateLimit)
+	s.Require().NoError(err)
+	return rateLimit
+}
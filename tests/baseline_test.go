@@ -10,7 +10,9 @@ import (
 	"github.com/txix-open/isp-kit/test/grpct"
 	"isp-system-service/assembly"
 	"isp-system-service/conf"
+	"isp-system-service/domain"
 	"isp-system-service/service/baseline"
+	"slices"
 	"testing"
 )
 
@@ -34,11 +36,12 @@ func (s *BaselineSuite) SetupSuite() {
 	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
 
 	locator := assembly.NewLocator(s.testDb, s.test.Logger())
-	config := locator.Config(conf.Remote{
+	config, err := locator.Config(conf.Remote{
 		Baseline: conf.Baseline{
 			InitialAdminUiToken: fake.It[string](),
 		},
 	})
+	s.Require().NoError(err)
 	s.baseline = config.Baseline
 	_, s.api = grpct.TestServer(s.test, config.Handler)
 }
@@ -50,3 +53,72 @@ func (s *BaselineSuite) TestBaselineSuccess() {
 	err = s.baseline.Do(s.T().Context())
 	s.Require().NoError(err)
 }
+
+func (s *BaselineSuite) TestBaselineSeed() {
+	cfg := conf.Baseline{
+		Applications: []conf.BaselineApplication{{
+			Domain:     "internal",
+			AppGroup:   "services",
+			Id:         20,
+			Name:       "gateway",
+			Type:       domain.ApplicationSystemType,
+			AccessList: []conf.DesiredAccessRule{{Method: "orders/*", Value: true}},
+			Tokens:     []string{fake.It[string]()},
+		}},
+	}
+	config, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(conf.Remote{Baseline: cfg})
+	s.Require().NoError(err)
+	err = config.Baseline.Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/access_list/set_one").
+		JsonRequestBody(domain.AccessListSetOneRequest{AppId: 20, Method: "health/*", Value: true}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	_, err = s.testDb.Exec(s.T().Context(), "UPDATE application SET name = 'renamed' WHERE id = 20")
+	s.Require().NoError(err)
+
+	err = config.Baseline.Do(s.T().Context())
+	s.Require().NoError(err)
+
+	app := domain.ApplicationWithTokens{}
+	err = s.api.Invoke("system/application/get_application_by_id").
+		JsonRequestBody(domain.Identity{Id: 20}).
+		JsonResponseBody(&app).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal("gateway", app.App.Name)
+	s.Require().Len(app.Tokens, 1)
+
+	accessList := make([]domain.MethodInfo, 0)
+	err = s.api.Invoke("system/access_list/get_by_id").
+		JsonRequestBody(domain.AccessListGetByIdRequest{Id: 20}).
+		JsonResponseBody(&accessList).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	methods := make([]string, 0)
+	for _, rule := range accessList {
+		methods = append(methods, rule.Method)
+	}
+	slices.Sort(methods)
+	s.Require().Equal([]string{"health/*", "orders/*"}, methods)
+
+	tokenCreated := 0
+	err = s.testDb.SelectRow(s.T().Context(), &tokenCreated,
+		"SELECT count(*) FROM change_event WHERE type = $1 AND payload->>'tokenId' = $2",
+		domain.ChangeEventTokenCreated, app.Tokens[0].Id,
+	)
+	s.Require().NoError(err)
+	s.Require().Equal(1, tokenCreated)
+
+	updated := struct {
+		Actor  string
+		Before string
+	}{}
+	err = s.testDb.SelectRow(s.T().Context(), &updated,
+		"SELECT actor, before->>'name' AS before FROM audit_event WHERE action = 'application.update' AND entity_id = '20'",
+	)
+	s.Require().NoError(err)
+	s.Require().Equal("baseline", updated.Actor)
+	s.Require().Equal("renamed", updated.Before)
+}
@@ -0,0 +1,142 @@
+package tests_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"isp-system-service/assembly"
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+	"isp-system-service/repository"
+	"isp-system-service/service/secure"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+)
+
+func TestCacheSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &CacheSuite{})
+}
+
+type CacheSuite struct {
+	suite.Suite
+
+	test   *test.Test
+	testDb *dbt.TestDb
+	api    *client.Client
+	other  *client.Client
+}
+
+type testDbProvider struct {
+	db *dbt.TestDb
+}
+
+func (p testDbProvider) DB() (*dbx.Client, error) {
+	return p.db.Client, nil
+}
+
+func (s *CacheSuite) SetupTest() {
+	s.test, _ = test.New(s.T())
+
+	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
+
+	cfg := conf.Remote{
+		Cache: conf.Cache{MaxSize: 100, TtlSeconds: 600},
+	}
+	config, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(cfg)
+	s.Require().NoError(err)
+	_, s.api = grpct.TestServer(s.test, config.Handler)
+
+	listener := secure.NewCacheListener(repository.NewNotificationListener(testDbProvider{db: s.testDb}), s.test.Logger())
+	listener.Upgrade(config.SecureCache)
+	ctx, cancel := context.WithCancel(s.T().Context())
+	s.T().Cleanup(cancel)
+	go listener.Run(ctx)
+
+	otherConfig, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(cfg)
+	s.Require().NoError(err)
+	_, s.other = grpct.TestServer(s.test, otherConfig.Handler)
+
+	createdTime := time.Now().UTC()
+	InsertDomain(s.testDb, entity.Domain{
+		Id: 3, Name: "test_domain", SystemId: 1, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertAppGroup(s.testDb, entity.AppGroup{
+		Id: 5, Name: "test_application_group", DomainId: 3, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertApplication(s.testDb, entity.Application{
+		Id: 7, Name: "test_application", ApplicationGroupId: 5, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertToken(s.testDb, entity.Token{
+		Id: "cached_token_id", TokenHash: hashToken("cached_token"), AppId: 7, ExpireTime: -1, CreatedAt: createdTime,
+	})
+}
+
+func (s *CacheSuite) TestRevoke_LocalInvalidation() {
+	s.Require().True(s.authenticate(s.api).Authenticated)
+
+	err := s.api.Invoke("system/token/revoke_tokens").
+		JsonRequestBody(domain.TokenRevokeRequest{AppId: 7, TokenIdList: []string{"cached_token_id"}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	result := s.authenticate(s.api)
+	s.Require().False(result.Authenticated)
+	s.Require().Equal(domain.ErrTokenNotFound.Error(), result.ErrorReason)
+}
+
+func (s *CacheSuite) TestRevoke_CrossInstanceInvalidation() {
+	s.Require().True(s.authenticate(s.api).Authenticated)
+
+	err := s.other.Invoke("system/token/revoke_tokens_for_app").
+		JsonRequestBody(domain.Identity{Id: 7}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	s.Require().Eventually(func() bool {
+		return !s.authenticate(s.api).Authenticated
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func (s *CacheSuite) TestAccessList_Invalidation() {
+	s.Require().False(s.authorize().Authorized)
+
+	err := s.api.Invoke("system/access_list/set_one").
+		JsonRequestBody(domain.AccessListSetOneRequest{AppId: 7, Method: "cached/method", Value: true}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().True(s.authorize().Authorized)
+
+	err = s.api.Invoke("system/access_list/set_one").
+		JsonRequestBody(domain.AccessListSetOneRequest{AppId: 7, Method: "cached/method", Value: false}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().False(s.authorize().Authorized)
+}
+
+func (s *CacheSuite) authenticate(api *client.Client) domain.AuthenticateResponse {
+	result := domain.AuthenticateResponse{}
+	err := api.Invoke("system/secure/authenticate").
+		JsonRequestBody(domain.AuthenticateRequest{Token: "cached_token"}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
+
+func (s *CacheSuite) authorize() domain.AuthorizeResponse {
+	result := domain.AuthorizeResponse{}
+	err := s.api.Invoke("system/secure/authorize").
+		JsonRequestBody(domain.AuthorizeRequest{ApplicationId: 7, Endpoint: "cached/method"}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
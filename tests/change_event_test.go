@@ -0,0 +1,254 @@
+package tests_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"isp-system-service/assembly"
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+)
+
+func TestChangeEventSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &ChangeEventSuite{})
+}
+
+type ChangeEventSuite struct {
+	suite.Suite
+
+	test   *test.Test
+	testDb *dbt.TestDb
+	config *assembly.Config
+	api    *client.Client
+}
+
+func (s *ChangeEventSuite) SetupTest() {
+	s.test, _ = test.New(s.T())
+
+	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
+
+	var err error
+	s.config, err = assembly.NewLocator(s.testDb, s.test.Logger()).Config(conf.Remote{})
+	s.Require().NoError(err)
+	_, s.api = grpct.TestServer(s.test, s.config.Handler)
+
+	createdTime := time.Now().UTC()
+	InsertDomain(s.testDb, entity.Domain{
+		Id: 3, Name: "test_domain", SystemId: 1, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertAppGroup(s.testDb, entity.AppGroup{
+		Id: 5, Name: "test_application_group", DomainId: 3, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertApplication(s.testDb, entity.Application{
+		Id: 7, Name: "test_application", ApplicationGroupId: 5, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+}
+
+func (s *ChangeEventSuite) TestSubscribe() {
+	created := domain.TokenCreateResponse{}
+	err := s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 7, ExpireTimeMs: -1}).
+		JsonResponseBody(&created).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(created.Tokens, 1)
+	tokenId := created.Tokens[0].Id
+
+	err = s.api.Invoke("system/access_list/set_one").
+		JsonRequestBody(domain.AccessListSetOneRequest{AppId: 7, Method: "orders/get", Value: true}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/token/revoke_tokens").
+		JsonRequestBody(domain.TokenRevokeRequest{AppId: 7, TokenIdList: []string{tokenId}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/application/update_application").
+		JsonRequestBody(domain.UpdateApplicationRequest{OldId: 7, NewId: 8, Name: "renamed"}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/application/delete_applications").
+		JsonRequestBody([]int{8}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	// events are not available until they are relayed
+	result := s.subscribe(domain.SubscribeEventsRequest{})
+	s.Require().Empty(result.Events)
+	s.Require().Zero(result.NextOffset)
+
+	s.config.EventRelay.Do(s.T().Context())
+
+	result = s.subscribe(domain.SubscribeEventsRequest{Limit: 3})
+	s.Require().Len(result.Events, 3)
+	s.Require().EqualValues(3, result.NextOffset)
+	next := s.subscribe(domain.SubscribeEventsRequest{Offset: result.NextOffset})
+	s.Require().Len(next.Events, 2)
+	s.Require().EqualValues(5, next.NextOffset)
+
+	events := append(result.Events, next.Events...)
+	for i, event := range events {
+		s.Require().EqualValues(i+1, event.Offset)
+	}
+
+	s.Require().Equal(domain.ChangeEventTokenCreated, events[0].Type)
+	s.Require().Equal(domain.TokenChange{TokenId: tokenId, AppId: 7}, payload[domain.TokenChange](s, events[0]))
+	s.Require().Equal(domain.ChangeEventAccessListChanged, events[1].Type)
+	s.Require().Equal(domain.AccessListChange{AppId: 7}, payload[domain.AccessListChange](s, events[1]))
+	s.Require().Equal(domain.ChangeEventTokenRevoked, events[2].Type)
+	s.Require().Equal(domain.TokenChange{TokenId: tokenId, AppId: 7}, payload[domain.TokenChange](s, events[2]))
+	s.Require().Equal(domain.ChangeEventApplicationRenamed, events[3].Type)
+	s.Require().Equal(domain.ApplicationRenamed{
+		OldAppId: 7,
+		AppId:    8,
+		OldName:  "test_application",
+		Name:     "renamed",
+	}, payload[domain.ApplicationRenamed](s, events[3]))
+	s.Require().Equal(domain.ChangeEventApplicationDeleted, events[4].Type)
+	s.Require().Equal(domain.ApplicationDeleted{AppId: 8, Name: "renamed"}, payload[domain.ApplicationDeleted](s, events[4]))
+
+	empty := s.subscribe(domain.SubscribeEventsRequest{Offset: next.NextOffset})
+	s.Require().Empty(empty.Events)
+	s.Require().EqualValues(5, empty.NextOffset)
+}
+
+func (s *ChangeEventSuite) TestSubscribe_CascadeDelete() {
+	created := domain.TokenCreateResponse{}
+	err := s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 7, ExpireTimeMs: -1}).
+		JsonResponseBody(&created).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	tokenId := created.Tokens[0].Id
+
+	err = s.api.Invoke("system/domain/delete_domains").
+		JsonRequestBody([]int{3}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	s.config.EventRelay.Do(s.T().Context())
+
+	result := s.subscribe(domain.SubscribeEventsRequest{})
+	s.Require().Len(result.Events, 3)
+	s.Require().Equal(domain.ChangeEventTokenRevoked, result.Events[1].Type)
+	s.Require().Equal(domain.TokenChange{TokenId: tokenId, AppId: 7}, payload[domain.TokenChange](s, result.Events[1]))
+	s.Require().Equal(domain.ChangeEventApplicationDeleted, result.Events[2].Type)
+	s.Require().Equal(domain.ApplicationDeleted{AppId: 7, Name: "test_application"}, payload[domain.ApplicationDeleted](s, result.Events[2]))
+}
+
+func (s *ChangeEventSuite) TestSubscribe_DeleteApplication() {
+	created := domain.TokenCreateResponse{}
+	err := s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 7, ExpireTimeMs: -1}).
+		JsonResponseBody(&created).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	tokenId := created.Tokens[0].Id
+
+	err = s.api.Invoke("system/application/delete_applications").
+		JsonRequestBody([]int{7}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	s.config.EventRelay.Do(s.T().Context())
+
+	result := s.subscribe(domain.SubscribeEventsRequest{})
+	s.Require().Len(result.Events, 3)
+	s.Require().Equal(domain.ChangeEventTokenRevoked, result.Events[1].Type)
+	s.Require().Equal(domain.TokenChange{TokenId: tokenId, AppId: 7}, payload[domain.TokenChange](s, result.Events[1]))
+	s.Require().Equal(domain.ChangeEventApplicationDeleted, result.Events[2].Type)
+	s.Require().Equal(domain.ApplicationDeleted{AppId: 7, Name: "test_application"}, payload[domain.ApplicationDeleted](s, result.Events[2]))
+}
+
+func (s *ChangeEventSuite) TestSubscribe_RolesAndRateLimits() {
+	role := domain.Role{}
+	err := s.api.Invoke("system/role/create").
+		JsonRequestBody(domain.CreateRoleRequest{Name: "reader", Methods: []domain.MethodInfo{{Method: "orders/get", Value: true}}}).
+		JsonResponseBody(&role).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/role/set_for_application").
+		JsonRequestBody(domain.SetApplicationRolesRequest{AppId: 7, RoleIdList: []int{role.Id}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/role/delete_list").
+		JsonRequestBody(domain.IdListRequest{IdList: []int{role.Id}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	err = s.api.Invoke("system/rate_limit/set").
+		JsonRequestBody(domain.SetRateLimitRequest{AppGroupId: 5, Method: "orders/*", RequestsPerSecond: 10}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	s.config.EventRelay.Do(s.T().Context())
+
+	result := s.subscribe(domain.SubscribeEventsRequest{})
+	s.Require().Len(result.Events, 3)
+	for _, event := range result.Events {
+		s.Require().Equal(domain.ChangeEventAccessListChanged, event.Type)
+	}
+	s.Require().Equal(domain.AccessListChange{AppId: 7}, payload[domain.AccessListChange](s, result.Events[0]))
+	s.Require().Equal(domain.AccessListChange{AppId: 7}, payload[domain.AccessListChange](s, result.Events[1]))
+	s.Require().Equal(domain.AccessListChange{AppGroupId: 5}, payload[domain.AccessListChange](s, result.Events[2]))
+}
+
+func (s *ChangeEventSuite) TestSubscribe_WaitsForEvents() {
+	received := make(chan domain.SubscribeEventsResponse, 1)
+	go func() {
+		result := domain.SubscribeEventsResponse{}
+		err := s.api.Invoke("system/events/subscribe").
+			JsonRequestBody(domain.SubscribeEventsRequest{WaitSeconds: 10}).
+			JsonResponseBody(&result).
+			Do(s.T().Context())
+		s.NoError(err)
+		received <- result
+	}()
+
+	err := s.api.Invoke("system/access_list/set_one").
+		JsonRequestBody(domain.AccessListSetOneRequest{AppId: 7, Method: "orders/get", Value: true}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	// the subscriber must be woken by the relay before the wait expires
+	s.Require().Eventually(func() bool {
+		s.config.EventRelay.Do(s.T().Context())
+		return len(received) > 0
+	}, 5*time.Second, 100*time.Millisecond)
+
+	result := <-received
+	s.Require().Len(result.Events, 1)
+	s.Require().Equal(domain.ChangeEventAccessListChanged, result.Events[0].Type)
+	s.Require().EqualValues(1, result.NextOffset)
+}
+
+func (s *ChangeEventSuite) subscribe(req domain.SubscribeEventsRequest) domain.SubscribeEventsResponse {
+	result := domain.SubscribeEventsResponse{}
+	err := s.api.Invoke("system/events/subscribe").
+		JsonRequestBody(req).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
+
+func payload[T any](s *ChangeEventSuite, event domain.ChangeEvent) T {
+	var result T
+	err := json.Unmarshal(event.Payload, &result)
+	s.Require().NoError(err)
+	return result
+}
@@ -2,6 +2,7 @@ package tests_test
 
 import (
 	"isp-system-service/entity"
+	"isp-system-service/service/tokenhash"
 
 	"github.com/txix-open/isp-kit/test/dbt"
 )
@@ -39,19 +40,26 @@ func InsertApplication(db *dbt.TestDb, value entity.Application) {
 func InsertToken(db *dbt.TestDb, value entity.Token) {
 	q := `
 	INSERT INTO token 
-		(token, created_at, app_id, expire_time) 
+		(id, token_hash, masked_token, created_at, app_id, expire_time, expires_at, scope, allowed_cidrs) 
 	VALUES 
-		(:token, :created_at, :app_id, :expire_time)
+		(:id, :token_hash, :masked_token, :created_at, :app_id, :expire_time, :expires_at, :scope, :allowed_cidrs)
 `
+	if value.Id == "" {
+		value.Id = value.TokenHash
+	}
 	db.Must().ExecNamed(q, value)
 }
 
+func hashToken(token string) string {
+	return tokenhash.New("").Hash(token)
+}
+
 func InsertAccessList(db *dbt.TestDb, value entity.AccessList) {
 	q := `
 	INSERT INTO access_list
-	    (app_id, method, value)
+	    (app_id, http_method, method, value)
 	VALUES
-		(:app_id, :method, :value)
+		(:app_id, :http_method, :method, :value)
 `
 	db.Must().ExecNamed(q, value)
 }
@@ -0,0 +1,171 @@
+package tests_test
+
+import (
+	"testing"
+	"time"
+
+	"isp-system-service/assembly"
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+)
+
+func TestRateLimitSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RateLimitSuite{})
+}
+
+type RateLimitSuite struct {
+	suite.Suite
+
+	test   *test.Test
+	testDb *dbt.TestDb
+	api    *client.Client
+}
+
+func (s *RateLimitSuite) SetupTest() {
+	s.test, _ = test.New(s.T())
+
+	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
+
+	config, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(conf.Remote{})
+	s.Require().NoError(err)
+	_, s.api = grpct.TestServer(s.test, config.Handler)
+
+	createdTime := time.Now().UTC()
+	InsertDomain(s.testDb, entity.Domain{
+		Id: 3, Name: "test_domain", SystemId: 1, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertAppGroup(s.testDb, entity.AppGroup{
+		Id: 5, Name: "test_application_group", DomainId: 3, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertApplication(s.testDb, entity.Application{
+		Id: 7, Name: "test_application", ApplicationGroupId: 5, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertToken(s.testDb, entity.Token{
+		TokenHash: hashToken("test_token"), AppId: 7, ExpireTime: -1, CreatedAt: createdTime,
+	})
+}
+
+func (s *RateLimitSuite) TestSet_AppOverridesAppGroup() {
+	s.set(domain.SetRateLimitRequest{AppGroupId: 5, RequestsPerSecond: 10, Burst: 20})
+	s.set(domain.SetRateLimitRequest{AppGroupId: 5, Method: "orders/*", RequestsPerMinute: 60})
+	appLimit := s.set(domain.SetRateLimitRequest{AppId: 7, Method: "orders/*", RequestsPerSecond: 5})
+	s.Require().Equal(7, appLimit.AppId)
+	s.Require().Equal(0, appLimit.AppGroupId)
+
+	updated := s.set(domain.SetRateLimitRequest{AppId: 7, Method: "orders/*", RequestsPerSecond: 3, Burst: 6})
+	s.Require().Equal(appLimit.Id, updated.Id)
+
+	var effective []domain.RateLimit
+	err := s.api.Invoke("system/rate_limit/get_effective").
+		JsonRequestBody(domain.Identity{Id: 7}).
+		JsonResponseBody(&effective).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(effective, 2)
+	s.Require().Equal("", effective[0].Method)
+	s.Require().Equal(5, effective[0].AppGroupId)
+	s.Require().Equal(10, effective[0].RequestsPerSecond)
+	s.Require().Equal("orders/*", effective[1].Method)
+	s.Require().Equal(7, effective[1].AppId)
+	s.Require().Equal(3, effective[1].RequestsPerSecond)
+	s.Require().Equal(6, effective[1].Burst)
+
+	result := domain.AuthenticateResponse{}
+	err = s.api.Invoke("system/secure/authenticate").
+		JsonRequestBody(domain.AuthenticateRequest{Token: "test_token"}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().True(result.Authenticated)
+	s.Require().Equal([]domain.RateLimitPolicy{{
+		Source:            entity.AccessRuleSourceAppGroup,
+		RequestsPerSecond: 10,
+		Burst:             20,
+	}, {
+		Source:            entity.AccessRuleSourceApplication,
+		Method:            "orders/*",
+		RequestsPerSecond: 3,
+		Burst:             6,
+	}}, result.AuthData.RateLimits)
+}
+
+func (s *RateLimitSuite) TestGetByApplicationAndGroup() {
+	appGroupLimit := s.set(domain.SetRateLimitRequest{AppGroupId: 5, RequestsPerSecond: 10})
+	appLimit := s.set(domain.SetRateLimitRequest{AppId: 7, Method: "orders/*", RequestsPerSecond: 5})
+
+	var byApp []domain.RateLimit
+	err := s.api.Invoke("system/rate_limit/get_by_application_id").
+		JsonRequestBody(domain.Identity{Id: 7}).
+		JsonResponseBody(&byApp).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(byApp, 1)
+	s.Require().Equal(appLimit.Id, byApp[0].Id)
+
+	var byAppGroup []domain.RateLimit
+	err = s.api.Invoke("system/rate_limit/get_by_application_group_id").
+		JsonRequestBody(domain.Identity{Id: 5}).
+		JsonResponseBody(&byAppGroup).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(byAppGroup, 1)
+	s.Require().Equal(appGroupLimit.Id, byAppGroup[0].Id)
+}
+
+func (s *RateLimitSuite) TestDeleteList() {
+	appLimit := s.set(domain.SetRateLimitRequest{AppId: 7, RequestsPerSecond: 5})
+
+	result := domain.AuthenticateResponse{}
+	err := s.api.Invoke("system/secure/authenticate").
+		JsonRequestBody(domain.AuthenticateRequest{Token: "test_token"}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(result.AuthData.RateLimits, 1)
+
+	deleted := domain.DeleteResponse{}
+	err = s.api.Invoke("system/rate_limit/delete_list").
+		JsonRequestBody(domain.IdListRequest{IdList: []int{appLimit.Id}}).
+		JsonResponseBody(&deleted).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(1, deleted.Deleted)
+
+	result = domain.AuthenticateResponse{}
+	err = s.api.Invoke("system/secure/authenticate").
+		JsonRequestBody(domain.AuthenticateRequest{Token: "test_token"}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Empty(result.AuthData.RateLimits)
+}
+
+func (s *RateLimitSuite) TestSet_AppNotFound() {
+	err := s.api.Invoke("system/rate_limit/set").
+		JsonRequestBody(domain.SetRateLimitRequest{AppId: 100, RequestsPerSecond: 5}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+	apiError := apierrors.FromError(err)
+	s.Require().NotNil(apiError)
+	s.Require().Equal(domain.ErrCodeApplicationNotFound, apiError.ErrorCode)
+}
+
+func (s *RateLimitSuite) set(req domain.SetRateLimitRequest) domain.RateLimit {
+	result := domain.RateLimit{}
+	err := s.api.Invoke("system/rate_limit/set").
+		JsonRequestBody(req).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
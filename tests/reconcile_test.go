@@ -0,0 +1,190 @@
+package tests_test
+
+import (
+	"slices"
+	"testing"
+
+	"isp-system-service/assembly"
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+)
+
+func TestReconcileSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &ReconcileSuite{})
+}
+
+type ReconcileSuite struct {
+	suite.Suite
+
+	test   *test.Test
+	testDb *dbt.TestDb
+	api    *client.Client
+}
+
+func (s *ReconcileSuite) SetupTest() {
+	s.test, _ = test.New(s.T())
+
+	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
+
+	config, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(conf.Remote{})
+	s.Require().NoError(err)
+	_, s.api = grpct.TestServer(s.test, config.Handler)
+}
+
+func (s *ReconcileSuite) TestReconcile() {
+	s.reconcile(desiredState())
+	s.reconcile(desiredState())
+
+	app := s.application(10)
+	s.Require().Equal("app", app.App.Name)
+	s.Require().True(app.App.ManagedByConfig)
+
+	appGroups := s.appGroups()
+	s.Require().Len(appGroups, 1)
+	s.Require().Equal("managed", appGroups[0].Name)
+	s.Require().True(appGroups[0].ManagedByConfig)
+
+	accessList := make([]domain.MethodInfo, 0)
+	err := s.api.Invoke("system/access_list/get_by_application_group_id").
+		JsonRequestBody(domain.Identity{Id: appGroups[0].Id}).
+		JsonResponseBody(&accessList).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.MethodInfo{{Method: "health/*", Value: true}}, accessList)
+
+	audit := domain.AuditSearchResponse{}
+	err = s.api.Invoke("system/audit/search").
+		JsonRequestBody(domain.AuditSearchRequest{EntityType: entity.AuditEntityRegistry}).
+		JsonResponseBody(&audit).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(audit.Items, 1)
+	s.Require().Equal("registry.reconcile", audit.Items[0].Action)
+	s.Require().Equal("baseline", audit.Items[0].Actor)
+}
+
+func (s *ReconcileSuite) TestReconcile_RejectsChanges() {
+	s.reconcile(desiredState())
+
+	err := s.api.Invoke("system/application/update_application").
+		JsonRequestBody(domain.UpdateApplicationRequest{OldId: 10, NewId: 10, Name: "renamed"}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+
+	err = s.api.Invoke("system/access_list/set_one").
+		JsonRequestBody(domain.AccessListSetOneRequest{AppId: 10, Method: "orders/delete", Value: true}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+
+	err = s.api.Invoke("system/application/delete_applications").
+		JsonRequestBody([]int{10}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+
+	created := domain.TokenCreateResponse{}
+	err = s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 10, ExpireTimeMs: -1}).
+		JsonResponseBody(&created).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	tokenId := created.Tokens[0].Id
+
+	desired := desiredState()
+	desired.AppGroups[0].Applications[0].TokenIdList = []string{tokenId, "not_issued"}
+	s.reconcile(desired)
+
+	err = s.api.Invoke("system/token/revoke_tokens").
+		JsonRequestBody(domain.TokenRevokeRequest{AppId: 10, TokenIdList: []string{tokenId}}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+	s.Require().True(s.application(10).Tokens[0].ManagedByConfig)
+}
+
+func (s *ReconcileSuite) TestReconcile_RevertsDrift() {
+	s.reconcile(desiredState())
+
+	_, err := s.testDb.Exec(s.T().Context(), "UPDATE application SET name = 'drifted' WHERE id = 10")
+	s.Require().NoError(err)
+	_, err = s.testDb.Exec(s.T().Context(), "DELETE FROM access_list WHERE app_id = 10")
+	s.Require().NoError(err)
+
+	s.reconcile(desiredState())
+
+	s.Require().Equal("app", s.application(10).App.Name)
+	accessList := make([]domain.AccessListItem, 0)
+	err = s.api.Invoke("system/access_list/get_by_id").
+		JsonRequestBody(domain.AccessListGetByIdRequest{Id: 10}).
+		JsonResponseBody(&accessList).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().True(slices.ContainsFunc(accessList, func(item domain.AccessListItem) bool {
+		return item.Method == "orders/get" && item.Source == entity.AccessRuleSourceApplication
+	}))
+}
+
+func (s *ReconcileSuite) TestReconcile_DeletesUndeclared() {
+	s.reconcile(desiredState())
+
+	s.reconcile(conf.Desired{Enabled: true})
+
+	err := s.api.Invoke("system/application/get_application_by_id").
+		JsonRequestBody(domain.Identity{Id: 10}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+
+	appGroups := s.appGroups()
+	s.Require().Empty(appGroups)
+}
+
+func (s *ReconcileSuite) reconcile(desired conf.Desired) {
+	config, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(conf.Remote{Desired: desired})
+	s.Require().NoError(err)
+	err = config.Baseline.Do(s.T().Context())
+	s.Require().NoError(err)
+}
+
+func (s *ReconcileSuite) application(id int) domain.ApplicationWithTokens {
+	result := domain.ApplicationWithTokens{}
+	err := s.api.Invoke("system/application/get_application_by_id").
+		JsonRequestBody(domain.Identity{Id: id}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
+
+func (s *ReconcileSuite) appGroups() []domain.AppGroup {
+	result := domain.AppGroupListResponse{}
+	err := s.api.Invoke("system/application_group/get_all").
+		JsonRequestBody(domain.ListRequest{}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result.Items
+}
+
+func desiredState() conf.Desired {
+	return conf.Desired{
+		Enabled: true,
+		AppGroups: []conf.DesiredAppGroup{{
+			Domain:     "root",
+			Name:       "managed",
+			AccessList: []conf.DesiredAccessRule{{Method: "health/*", Value: true}},
+			Applications: []conf.DesiredApplication{{
+				Id:         10,
+				Name:       "app",
+				Type:       domain.ApplicationSystemType,
+				AccessList: []conf.DesiredAccessRule{{Method: "orders/get", Value: true}},
+			}},
+		}},
+	}
+}
@@ -0,0 +1,191 @@
+package tests_test
+
+import (
+	"testing"
+	"time"
+
+	"isp-system-service/assembly"
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+)
+
+func TestRegistrySuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RegistrySuite{})
+}
+
+type RegistrySuite struct {
+	suite.Suite
+
+	test   *test.Test
+	testDb *dbt.TestDb
+	api    *client.Client
+}
+
+func (s *RegistrySuite) SetupTest() {
+	s.test, _ = test.New(s.T())
+
+	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
+
+	config, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(conf.Remote{})
+	s.Require().NoError(err)
+	_, s.api = grpct.TestServer(s.test, config.Handler)
+
+	createdTime := time.Now().UTC()
+	InsertDomain(s.testDb, entity.Domain{
+		Id: 1, Name: "root", SystemId: 1, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertAppGroup(s.testDb, entity.AppGroup{
+		Id: 2, Name: "group", DomainId: 1, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertApplication(s.testDb, entity.Application{
+		Id: 3, Name: "app", ApplicationGroupId: 2, Type: domain.ApplicationSystemType,
+		CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertApplication(s.testDb, entity.Application{
+		Id: 4, Name: "old", ApplicationGroupId: 2, Type: domain.ApplicationSystemType,
+		CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	err = s.api.Invoke("system/access_list/set_one").
+		JsonRequestBody(domain.AccessListSetOneRequest{AppId: 3, Method: "orders/get", Value: true}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+}
+
+func (s *RegistrySuite) TestExportImport_Idempotent() {
+	exported := s.export(domain.RegistryFormatYaml)
+	s.Require().Contains(exported.Yaml, "orders/get")
+
+	result := s.importDocument(domain.RegistryImportRequest{Yaml: exported.Yaml})
+	s.Require().Empty(result.Changes)
+}
+
+func (s *RegistrySuite) TestImport_DryRun() {
+	document := s.export(domain.RegistryFormatJson).Document
+	document.Domains[0].AppGroups[0].Applications = document.Domains[0].AppGroups[0].Applications[:1]
+
+	result := s.importDocument(domain.RegistryImportRequest{Document: document, DryRun: true})
+	s.Require().True(result.DryRun)
+	s.Require().Equal([]domain.RegistryChange{{
+		Action:     domain.RegistryChangeDelete,
+		EntityType: entity.AuditEntityApplication,
+		Key:        "4",
+	}}, result.Changes)
+
+	after := s.export(domain.RegistryFormatJson).Document
+	s.Require().Len(after.Domains[0].AppGroups[0].Applications, 2)
+}
+
+func (s *RegistrySuite) TestImport_Apply() {
+	document := &domain.RegistryDocument{
+		Version: domain.RegistryDocumentVersion,
+		Domains: []domain.RegistryDomain{{
+			Name:       "root",
+			AccessList: []domain.RegistryAccessRule{{Method: "health/*", Value: true}},
+			AppGroups: []domain.RegistryAppGroup{{
+				Name: "group",
+				Applications: []domain.RegistryApplication{{
+					Id:          3,
+					Name:        "app",
+					Description: "main",
+					Type:        domain.ApplicationSystemType,
+				}},
+			}, {
+				Name: "new_group",
+				Applications: []domain.RegistryApplication{{
+					Id:         5,
+					Name:       "new",
+					Type:       domain.ApplicationMobileType,
+					AccessList: []domain.RegistryAccessRule{{Method: "orders/list", Value: true}},
+				}},
+			}},
+		}},
+	}
+
+	result := s.importDocument(domain.RegistryImportRequest{Document: document})
+	s.Require().False(result.DryRun)
+	s.Require().ElementsMatch([]domain.RegistryChange{
+		{Action: domain.RegistryChangeCreate, EntityType: entity.AuditEntityAccessList, Key: "domain:root"},
+		{Action: domain.RegistryChangeUpdate, EntityType: entity.AuditEntityApplication, Key: "3", Fields: []string{"description"}},
+		{Action: domain.RegistryChangeDelete, EntityType: entity.AuditEntityAccessList, Key: "application:3"},
+		{Action: domain.RegistryChangeCreate, EntityType: entity.AuditEntityAppGroup, Key: "root/new_group"},
+		{Action: domain.RegistryChangeCreate, EntityType: entity.AuditEntityApplication, Key: "5"},
+		{Action: domain.RegistryChangeCreate, EntityType: entity.AuditEntityAccessList, Key: "application:5"},
+		{Action: domain.RegistryChangeDelete, EntityType: entity.AuditEntityApplication, Key: "4"},
+	}, result.Changes)
+
+	s.Require().Equal(document, s.export(domain.RegistryFormatJson).Document)
+
+	again := s.importDocument(domain.RegistryImportRequest{Document: document})
+	s.Require().Empty(again.Changes)
+}
+
+func (s *RegistrySuite) TestImport_InvalidDocument() {
+	err := s.api.Invoke("system/registry/import").
+		JsonRequestBody(domain.RegistryImportRequest{Yaml: "version: 2"}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+
+	err = s.api.Invoke("system/registry/import").
+		JsonRequestBody(domain.RegistryImportRequest{Document: &domain.RegistryDocument{
+			Version: domain.RegistryDocumentVersion,
+			Domains: []domain.RegistryDomain{{Name: "root"}, {Name: "root"}},
+		}}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+}
+
+func (s *RegistrySuite) TestImport_ManagedByConfig() {
+	s.testDb.Must().Exec("UPDATE application SET managed_by_config = true WHERE id = 4")
+
+	removed := s.export(domain.RegistryFormatJson).Document
+	removed.Domains[0].AppGroups[0].Applications = removed.Domains[0].AppGroups[0].Applications[:1]
+	renamed := s.export(domain.RegistryFormatJson).Document
+	renamed.Domains[0].AppGroups[0].Applications[1].Name = "renamed"
+
+	for _, req := range []domain.RegistryImportRequest{
+		{Document: removed},
+		{Document: removed, DryRun: true},
+		{Document: renamed},
+	} {
+		err := s.api.Invoke("system/registry/import").
+			JsonRequestBody(req).
+			Do(s.T().Context())
+		apiErr := apierrors.FromError(err)
+		s.Require().NotNil(apiErr)
+		s.Require().Equal(domain.ErrCodeManagedByConfig, apiErr.ErrorCode)
+	}
+
+	after := s.export(domain.RegistryFormatJson).Document
+	s.Require().Len(after.Domains[0].AppGroups[0].Applications, 2)
+	s.Require().Equal("old", after.Domains[0].AppGroups[0].Applications[1].Name)
+}
+
+func (s *RegistrySuite) export(format string) domain.RegistryExportResponse {
+	result := domain.RegistryExportResponse{}
+	err := s.api.Invoke("system/registry/export").
+		JsonRequestBody(domain.RegistryExportRequest{Format: format}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
+
+func (s *RegistrySuite) importDocument(req domain.RegistryImportRequest) domain.RegistryImportResponse {
+	result := domain.RegistryImportResponse{}
+	err := s.api.Invoke("system/registry/import").
+		JsonRequestBody(req).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
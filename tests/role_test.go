@@ -0,0 +1,163 @@
+package tests_test
+
+import (
+	"testing"
+	"time"
+
+	"isp-system-service/assembly"
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+)
+
+func TestRoleSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &RoleSuite{})
+}
+
+type RoleSuite struct {
+	suite.Suite
+
+	test   *test.Test
+	testDb *dbt.TestDb
+	api    *client.Client
+}
+
+func (s *RoleSuite) SetupTest() {
+	s.test, _ = test.New(s.T())
+
+	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
+
+	locator := assembly.NewLocator(s.testDb, s.test.Logger())
+	config, err := locator.Config(conf.Remote{})
+	s.Require().NoError(err)
+	_, s.api = grpct.TestServer(s.test, config.Handler)
+
+	createdTime := time.Now().UTC()
+	InsertDomain(s.testDb, entity.Domain{
+		Id: 3, Name: "test_domain", SystemId: 1, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertAppGroup(s.testDb, entity.AppGroup{
+		Id: 5, Name: "test_application_group", DomainId: 3, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertApplication(s.testDb, entity.Application{
+		Id: 7, Name: "test_application", ApplicationGroupId: 5, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+}
+
+func (s *RoleSuite) TestCreateUpdate() {
+	role := s.createRole("reader", domain.MethodInfo{Method: "admin/user/get", Value: true})
+	s.Require().Equal("reader", role.Name)
+	s.Require().Equal([]domain.MethodInfo{{Method: "admin/user/get", Value: true}}, role.Methods)
+
+	result := domain.Role{}
+	err := s.api.Invoke("system/role/update").
+		JsonRequestBody(domain.UpdateRoleRequest{
+			Id:   role.Id,
+			Name: "reader",
+			Methods: []domain.MethodInfo{
+				{Method: "admin/*", Value: true},
+				{Method: "admin/user/delete", Value: false},
+			},
+		}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal([]domain.MethodInfo{
+		{Method: "admin/*", Value: true},
+		{Method: "admin/user/delete", Value: false},
+	}, result.Methods)
+
+	all := make([]domain.Role, 0)
+	err = s.api.Invoke("system/role/get_all").
+		JsonResponseBody(&all).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(all, 1)
+	s.Require().Len(all[0].Methods, 2)
+}
+
+func (s *RoleSuite) TestCreate_DuplicateName() {
+	s.createRole("duplicate")
+
+	err := s.api.Invoke("system/role/create").
+		JsonRequestBody(domain.CreateRoleRequest{Name: "duplicate"}).
+		Do(s.T().Context())
+	apiErr := apierrors.FromError(err)
+	s.Require().NotNil(apiErr)
+	s.Require().Equal(domain.ErrCodeRoleDuplicateName, apiErr.ErrorCode)
+}
+
+func (s *RoleSuite) TestSetForApplication_RoleNotFound() {
+	err := s.api.Invoke("system/role/set_for_application").
+		JsonRequestBody(domain.SetApplicationRolesRequest{AppId: 7, RoleIdList: []int{100}}).
+		Do(s.T().Context())
+	apiErr := apierrors.FromError(err)
+	s.Require().NotNil(apiErr)
+	s.Require().Equal(domain.ErrCodeRoleNotFound, apiErr.ErrorCode)
+}
+
+func (s *RoleSuite) TestAuthorize_ApplicationRole() {
+	role := s.createRole("app_role", domain.MethodInfo{Method: "report/*", Value: true})
+
+	roles := make([]domain.Role, 0)
+	err := s.api.Invoke("system/role/set_for_application").
+		JsonRequestBody(domain.SetApplicationRolesRequest{AppId: 7, RoleIdList: []int{role.Id}}).
+		JsonResponseBody(&roles).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(roles, 1)
+
+	s.Require().True(s.authorize("report/daily/export").Authorized)
+
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "report/daily/export", Value: false})
+	s.Require().False(s.authorize("report/daily/export").Authorized)
+	s.Require().True(s.authorize("report/weekly/export").Authorized)
+}
+
+func (s *RoleSuite) TestAuthorize_AppGroupRole() {
+	role := s.createRole("group_role", domain.MethodInfo{Method: "catalog/get", Value: true})
+
+	err := s.api.Invoke("system/role/set_for_application_group").
+		JsonRequestBody(domain.SetAppGroupRolesRequest{AppGroupId: 5, RoleIdList: []int{role.Id}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().True(s.authorize("catalog/get").Authorized)
+
+	deleted := domain.DeleteResponse{}
+	err = s.api.Invoke("system/role/delete_list").
+		JsonRequestBody(domain.IdListRequest{IdList: []int{role.Id}}).
+		JsonResponseBody(&deleted).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(1, deleted.Deleted)
+	s.Require().False(s.authorize("catalog/get").Authorized)
+}
+
+func (s *RoleSuite) createRole(name string, methods ...domain.MethodInfo) domain.Role {
+	result := domain.Role{}
+	err := s.api.Invoke("system/role/create").
+		JsonRequestBody(domain.CreateRoleRequest{Name: name, Methods: methods}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
+
+func (s *RoleSuite) authorize(endpoint string) domain.AuthorizeResponse {
+	result := domain.AuthorizeResponse{}
+	err := s.api.Invoke("system/secure/authorize").
+		JsonRequestBody(domain.AuthorizeRequest{ApplicationId: 7, Endpoint: endpoint}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
@@ -1,6 +1,7 @@
 package tests_test
 
 import (
+	"database/sql"
 	"net/http"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"isp-system-service/entity"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
 	"github.com/txix-open/isp-kit/grpc/client"
 	"github.com/txix-open/isp-kit/test"
 	"github.com/txix-open/isp-kit/test/dbt"
@@ -38,7 +40,8 @@ func (s *SecureSuite) SetupSuite() {
 	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
 
 	locator := assembly.NewLocator(s.testDb, s.test.Logger())
-	config := locator.Config(conf.Remote{})
+	config, err := locator.Config(conf.Remote{})
+	s.Require().NoError(err)
 	_, s.api = grpct.TestServer(s.test, config.Handler)
 
 	createdTime := time.Now().UTC()
@@ -55,7 +58,7 @@ func (s *SecureSuite) SetupSuite() {
 
 func (s *SecureSuite) TestAuthenticate_Success() {
 	InsertToken(s.testDb, entity.Token{
-		Token: "test_token_success", AppId: 7, ExpireTime: -1, CreatedAt: time.Now().UTC(),
+		TokenHash: hashToken("test_token_success"), AppId: 7, ExpireTime: -1, CreatedAt: time.Now().UTC(),
 	})
 
 	result := domain.AuthenticateResponse{}
@@ -97,7 +100,8 @@ func (s *SecureSuite) TestAuthenticate_NotFound() {
 
 func (s *SecureSuite) TestAuthenticate_NotExpired() {
 	InsertToken(s.testDb, entity.Token{
-		Token: "test_token_not_expired", AppId: 7, ExpireTime: int((time.Hour).Milliseconds()), CreatedAt: time.Now().UTC(),
+		TokenHash: hashToken("test_token_not_expired"), AppId: 7, ExpireTime: int((time.Hour).Milliseconds()), CreatedAt: time.Now().UTC(),
+		ExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(time.Hour), Valid: true},
 	})
 
 	result := domain.AuthenticateResponse{}
@@ -123,7 +127,8 @@ func (s *SecureSuite) TestAuthenticate_NotExpired() {
 
 func (s *SecureSuite) TestAuthenticate_Expired() {
 	InsertToken(s.testDb, entity.Token{
-		Token: "test_token_expired", AppId: 7, ExpireTime: 0, CreatedAt: time.Now().UTC(),
+		TokenHash: hashToken("test_token_expired"), AppId: 7, ExpireTime: 0, CreatedAt: time.Now().UTC(),
+		ExpiresAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
 	})
 
 	result := domain.AuthenticateResponse{}
@@ -159,6 +164,12 @@ func (s *SecureSuite) TestAuthorize_Success_True() {
 	s.Require().NoError(err)
 	s.Require().Equal(domain.AuthorizeResponse{
 		Authorized: true,
+		Rule: &domain.AccessRule{
+			Source:   entity.AccessRuleSourceApplication,
+			SourceId: 7,
+			Method:   "endpoint/available",
+			Value:    true,
+		},
 	}, result)
 }
 
@@ -180,6 +191,12 @@ func (s *SecureSuite) TestAuthorize_Success_False() {
 	s.Require().NoError(err)
 	s.Require().Equal(domain.AuthorizeResponse{
 		Authorized: false,
+		Rule: &domain.AccessRule{
+			Source:   entity.AccessRuleSourceApplication,
+			SourceId: 7,
+			Method:   "endpoint/not_available",
+			Value:    false,
+		},
 	}, result)
 }
 
@@ -218,5 +235,493 @@ func (s *SecureSuite) TestAuthorize_WithHttpMethod_Success_True() {
 	s.Require().NoError(err)
 	s.Require().Equal(domain.AuthorizeResponse{
 		Authorized: true,
+		Rule: &domain.AccessRule{
+			Source:     entity.AccessRuleSourceApplication,
+			SourceId:   7,
+			HttpMethod: http.MethodGet,
+			Method:     "endpoint/available_http",
+			Value:      true,
+		},
 	}, result)
 }
+
+func (s *SecureSuite) TestAuthorize_Pattern_Precedence() {
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "pattern/*", Value: true})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "pattern/user/*", Value: false})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "pattern/user/get", Value: true})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "wildcard/**/get_*", Value: true})
+
+	cases := map[string]bool{
+		"pattern/user/get":           true,
+		"pattern/user/delete":        false,
+		"pattern/role/get_all":       true,
+		"wildcard/user/role/get_all": true,
+		"wildcard/user/delete":       false,
+	}
+	for endpoint, expected := range cases {
+		result := domain.AuthorizeResponse{}
+		err := s.api.Invoke("system/secure/authorize").
+			JsonRequestBody(domain.AuthorizeRequest{
+				ApplicationId: 7,
+				Endpoint:      endpoint,
+			}).
+			JsonResponseBody(&result).
+			Do(s.T().Context())
+		s.Require().NoError(err)
+		s.Require().Equal(expected, result.Authorized, endpoint)
+	}
+}
+
+func (s *SecureSuite) TestAuthorize_DenyOverrides() {
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "deny/*", Value: true})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "deny/user/delete", Value: false})
+
+	result := domain.AuthorizeResponse{}
+	err := s.api.Invoke("system/secure/authorize").
+		JsonRequestBody(domain.AuthorizeRequest{
+			ApplicationId: 7,
+			Endpoint:      "deny/user/delete",
+		}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(domain.AuthorizeResponse{
+		Authorized: false,
+		Rule: &domain.AccessRule{
+			Source:   entity.AccessRuleSourceApplication,
+			SourceId: 7,
+			Method:   "deny/user/delete",
+			Value:    false,
+		},
+	}, result)
+
+	result = domain.AuthorizeResponse{}
+	err = s.api.Invoke("system/secure/authorize").
+		JsonRequestBody(domain.AuthorizeRequest{
+			ApplicationId: 7,
+			Endpoint:      "deny/user/get",
+		}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(domain.AuthorizeResponse{
+		Authorized: true,
+		Rule: &domain.AccessRule{
+			Source:   entity.AccessRuleSourceApplication,
+			SourceId: 7,
+			Method:   "deny/*",
+			Value:    true,
+		},
+	}, result)
+}
+
+func (s *SecureSuite) TestAuthorize_Inheritance() {
+	err := s.api.Invoke("system/access_list/set_list_for_domain").
+		JsonRequestBody(domain.AccessListSetListForDomainRequest{
+			DomainId: 3,
+			Methods: []domain.MethodInfo{
+				{Method: "inherit/*", Value: true},
+				{Method: "inherit/group/*", Value: true},
+			},
+		}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	err = s.api.Invoke("system/access_list/set_list_for_application_group").
+		JsonRequestBody(domain.AccessListSetListForAppGroupRequest{
+			AppGroupId: 5,
+			Methods: []domain.MethodInfo{
+				{Method: "inherit/group/*", Value: false},
+			},
+		}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	InsertAccessList(s.testDb, entity.AccessList{
+		AppId:  7,
+		Method: "inherit/group/allowed",
+		Value:  true,
+	})
+
+	tests := []struct {
+		endpoint string
+		expected domain.AuthorizeResponse
+	}{
+		{
+			endpoint: "inherit/group/allowed",
+			expected: domain.AuthorizeResponse{Authorized: true, Rule: &domain.AccessRule{
+				Source: entity.AccessRuleSourceApplication, SourceId: 7, Method: "inherit/group/allowed", Value: true,
+			}},
+		},
+		{
+			endpoint: "inherit/group/denied",
+			expected: domain.AuthorizeResponse{Authorized: false, Rule: &domain.AccessRule{
+				Source: entity.AccessRuleSourceAppGroup, SourceId: 5, Method: "inherit/group/*", Value: false,
+			}},
+		},
+		{
+			endpoint: "inherit/domain",
+			expected: domain.AuthorizeResponse{Authorized: true, Rule: &domain.AccessRule{
+				Source: entity.AccessRuleSourceDomain, SourceId: 3, Method: "inherit/*", Value: true,
+			}},
+		},
+	}
+	for _, test := range tests {
+		result := domain.AuthorizeResponse{}
+		err := s.api.Invoke("system/secure/authorize").
+			JsonRequestBody(domain.AuthorizeRequest{
+				ApplicationId: 7,
+				Endpoint:      test.endpoint,
+			}).
+			JsonResponseBody(&result).
+			Do(s.T().Context())
+		s.Require().NoError(err)
+		s.Require().Equal(test.expected, result, test.endpoint)
+	}
+}
+
+func (s *SecureSuite) TestAuthorizeBatch() {
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "batch/*", Value: true})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "batch/delete", Value: false})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, HttpMethod: http.MethodPost, Method: "batch/post", Value: false})
+
+	result := make([]domain.AuthorizeBatchResult, 0)
+	err := s.api.Invoke("system/secure/authorize_batch").
+		JsonRequestBody(domain.AuthorizeBatchRequest{
+			ApplicationId: 7,
+			Endpoints: []domain.AuthorizeEndpoint{
+				{Endpoint: "batch/get"},
+				{Endpoint: "batch/delete"},
+				{HttpMethod: http.MethodPost, Endpoint: "batch/post"},
+				{HttpMethod: http.MethodGet, Endpoint: "batch/post"},
+				{Endpoint: "unknown/batch"},
+			},
+		}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(result, 5)
+
+	authorized := make([]bool, len(result))
+	for i, r := range result {
+		authorized[i] = r.Authorized
+	}
+	s.Require().Equal([]bool{true, false, false, true, false}, authorized)
+	s.Require().Equal("batch/delete", result[1].Rule.Method)
+	s.Require().Equal("unknown/batch", result[4].Endpoint)
+	s.Require().Nil(result[4].Rule)
+}
+
+func (s *SecureSuite) TestScopedToken() {
+	InsertToken(s.testDb, entity.Token{
+		TokenHash: hashToken("test_token_scoped"), AppId: 7, ExpireTime: -1, CreatedAt: time.Now().UTC(),
+		Scope: entity.TokenScope{"scope/deploy/*"},
+	})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "scope/*", Value: true})
+
+	authenticated := domain.AuthenticateResponse{}
+	err := s.api.Invoke("system/secure/authenticate").
+		JsonRequestBody(domain.AuthenticateRequest{Token: "test_token_scoped"}).
+		JsonResponseBody(&authenticated).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().True(authenticated.Authenticated)
+	s.Require().Equal([]string{"scope/deploy/*"}, authenticated.AuthData.Scope)
+
+	authorized := domain.AuthorizeResponse{}
+	err = s.api.Invoke("system/secure/authorize").
+		JsonRequestBody(domain.AuthorizeRequest{
+			ApplicationId: 7, Endpoint: "scope/deploy/start", Scope: authenticated.AuthData.Scope,
+		}).
+		JsonResponseBody(&authorized).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().True(authorized.Authorized)
+
+	authorized = domain.AuthorizeResponse{}
+	err = s.api.Invoke("system/secure/authorize").
+		JsonRequestBody(domain.AuthorizeRequest{
+			ApplicationId: 7, Endpoint: "scope/users/delete", Scope: authenticated.AuthData.Scope,
+		}).
+		JsonResponseBody(&authorized).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(domain.AuthorizeResponse{OutOfScope: true}, authorized)
+
+	checked := domain.CheckResponse{}
+	err = s.api.Invoke("system/secure/check").
+		JsonRequestBody(domain.CheckRequest{Token: "test_token_scoped", Endpoint: "scope/deploy/start"}).
+		JsonResponseBody(&checked).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().True(checked.Authorized)
+
+	checked = domain.CheckResponse{}
+	err = s.api.Invoke("system/secure/check").
+		JsonRequestBody(domain.CheckRequest{Token: "test_token_scoped", Endpoint: "scope/users/delete"}).
+		JsonResponseBody(&checked).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().True(checked.Authenticated)
+	s.Require().False(checked.Authorized)
+	s.Require().Equal(domain.ErrOutOfTokenScope.Error(), checked.ErrorReason)
+}
+
+func (s *SecureSuite) TestAllowedCidrs() {
+	InsertApplication(s.testDb, entity.Application{
+		Id: 9, Name: "restricted_application", ApplicationGroupId: 5, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(),
+	})
+	InsertToken(s.testDb, entity.Token{
+		TokenHash: hashToken("test_token_restricted"), AppId: 9, ExpireTime: -1, CreatedAt: time.Now().UTC(),
+		AllowedCidrs: entity.AddressList{"10.0.0.0/8"},
+	})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 9, Method: "cidr/*", Value: true})
+
+	err := s.api.Invoke("system/application/set_allowed_cidrs").
+		JsonRequestBody(domain.SetAllowedCidrsRequest{AppId: 9, AllowedCidrs: []string{"10.1.0.0/16", "2001:db8::/32"}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	tests := []struct {
+		clientAddress string
+		expected      string
+	}{
+		{clientAddress: "10.1.2.3"},
+		{clientAddress: "10.1.2.3:5000"},
+		{clientAddress: "10.2.0.1", expected: domain.ErrSourceAddressNotAllowed.Error()},
+		{clientAddress: "2001:db8::1", expected: domain.ErrSourceAddressNotAllowed.Error()},
+		{clientAddress: "unknown", expected: domain.ErrSourceAddressNotAllowed.Error()},
+		{clientAddress: "", expected: domain.ErrSourceAddressNotAllowed.Error()},
+	}
+	for _, test := range tests {
+		result := domain.AuthenticateResponse{}
+		err := s.api.Invoke("system/secure/authenticate").
+			JsonRequestBody(domain.AuthenticateRequest{Token: "test_token_restricted", ClientAddress: test.clientAddress}).
+			JsonResponseBody(&result).
+			Do(s.T().Context())
+		s.Require().NoError(err)
+		s.Require().Equal(test.expected == "", result.Authenticated, test.clientAddress)
+		s.Require().Equal(test.expected, result.ErrorReason, test.clientAddress)
+	}
+
+	// the forwarded address is set by the client, so it is not trusted for the restricted token
+	result := domain.AuthenticateResponse{}
+	err = s.api.Invoke("system/secure/authenticate").
+		AppendMetadata("x-forwarded-for", "10.1.2.3").
+		JsonRequestBody(domain.AuthenticateRequest{Token: "test_token_restricted"}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().False(result.Authenticated)
+	s.Require().Equal(domain.ErrSourceAddressNotAllowed.Error(), result.ErrorReason)
+
+	checked := domain.CheckResponse{}
+	err = s.api.Invoke("system/secure/check").
+		JsonRequestBody(domain.CheckRequest{Token: "test_token_restricted", Endpoint: "cidr/get", ClientAddress: "10.2.0.1"}).
+		JsonResponseBody(&checked).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().False(checked.Authenticated)
+	s.Require().Equal(domain.ErrSourceAddressNotAllowed.Error(), checked.ErrorReason)
+
+	err = s.api.Invoke("system/application/set_allowed_cidrs").
+		JsonRequestBody(domain.SetAllowedCidrsRequest{AppId: 9}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	checked = domain.CheckResponse{}
+	err = s.api.Invoke("system/secure/check").
+		JsonRequestBody(domain.CheckRequest{Token: "test_token_restricted", Endpoint: "cidr/get", ClientAddress: "10.2.0.1"}).
+		JsonResponseBody(&checked).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().True(checked.Authorized)
+
+	err = s.api.Invoke("system/application/set_allowed_cidrs").
+		JsonRequestBody(domain.SetAllowedCidrsRequest{AppId: 9, AllowedCidrs: []string{"10.1.0.0"}}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+}
+
+func (s *SecureSuite) TestCheck() {
+	InsertToken(s.testDb, entity.Token{
+		TokenHash: hashToken("test_token_check"), AppId: 7, ExpireTime: -1, CreatedAt: time.Now().UTC(),
+	})
+	InsertToken(s.testDb, entity.Token{
+		TokenHash: hashToken("test_token_check_expired"), AppId: 7, ExpireTime: 1000, CreatedAt: time.Now().UTC().Add(-time.Minute),
+		ExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(-time.Minute).Add(time.Second), Valid: true},
+	})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "check/*", Value: true})
+	InsertAccessList(s.testDb, entity.AccessList{AppId: 7, Method: "check/denied", Value: false})
+	authData := &domain.AuthData{
+		AppName:       "test_application",
+		SystemId:      1,
+		DomainId:      3,
+		ServiceId:     5,
+		ApplicationId: 7,
+	}
+
+	tests := []struct {
+		token    string
+		endpoint string
+		expected domain.CheckResponse
+	}{
+		{
+			token:    "test_token_check",
+			endpoint: "check/allowed",
+			expected: domain.CheckResponse{
+				Authenticated: true,
+				Authorized:    true,
+				AuthData:      authData,
+				Rule: &domain.AccessRule{
+					Source: entity.AccessRuleSourceApplication, SourceId: 7, Method: "check/*", Value: true,
+				},
+			},
+		},
+		{
+			token:    "test_token_check",
+			endpoint: "check/denied",
+			expected: domain.CheckResponse{
+				Authenticated: true,
+				ErrorReason:   domain.ErrAccessDenied.Error(),
+				AuthData:      authData,
+				Rule: &domain.AccessRule{
+					Source: entity.AccessRuleSourceApplication, SourceId: 7, Method: "check/denied", Value: false,
+				},
+			},
+		},
+		{
+			token:    "test_token_check",
+			endpoint: "unknown/check",
+			expected: domain.CheckResponse{
+				Authenticated: true,
+				ErrorReason:   domain.ErrAccessListNotFound.Error(),
+				AuthData:      authData,
+			},
+		},
+		{
+			token:    "test_token_check_not_found",
+			endpoint: "check/allowed",
+			expected: domain.CheckResponse{
+				ErrorReason: domain.ErrTokenNotFound.Error(),
+			},
+		},
+		{
+			token:    "test_token_check_expired",
+			endpoint: "check/allowed",
+			expected: domain.CheckResponse{
+				ErrorReason: domain.ErrTokenExpired.Error(),
+			},
+		},
+	}
+	for _, test := range tests {
+		result := domain.CheckResponse{}
+		err := s.api.Invoke("system/secure/check").
+			JsonRequestBody(domain.CheckRequest{
+				Token:    test.token,
+				Endpoint: test.endpoint,
+			}).
+			JsonResponseBody(&result).
+			Do(s.T().Context())
+		s.Require().NoError(err)
+		s.Require().Equal(test.expected, result, test.endpoint)
+	}
+}
+
+func (s *SecureSuite) TestApplicationStatus() {
+	InsertApplication(s.testDb, entity.Application{
+		Id: 10, Name: "suspended_application", ApplicationGroupId: 5, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(),
+	})
+	InsertToken(s.testDb, entity.Token{
+		TokenHash: hashToken("test_token_suspended"), AppId: 10, ExpireTime: -1, CreatedAt: time.Now().UTC(),
+	})
+
+	app := domain.ApplicationWithTokens{}
+	err := s.api.Invoke("system/application/suspend").
+		AppendMetadata("x-admin-id", "42").
+		JsonRequestBody(domain.ChangeStatusRequest{Id: 10, Reason: "abuse"}).
+		JsonResponseBody(&app).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(domain.StatusSuspended, app.App.Status)
+	s.Require().Equal("abuse", app.App.StatusReason)
+	s.Require().Equal("admin:42", app.App.StatusChangedBy)
+	s.Require().NotNil(app.App.StatusChangedAt)
+	s.Require().Len(app.Tokens, 1)
+
+	s.requireAuthenticateError("test_token_suspended", domain.ErrApplicationSuspended.Error())
+
+	checked := domain.CheckResponse{}
+	err = s.api.Invoke("system/secure/check").
+		JsonRequestBody(domain.CheckRequest{Token: "test_token_suspended", Endpoint: "status/get"}).
+		JsonResponseBody(&checked).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().False(checked.Authenticated)
+	s.Require().Equal(domain.ErrApplicationSuspended.Error(), checked.ErrorReason)
+
+	err = s.api.Invoke("system/application/disable").
+		JsonRequestBody(domain.ChangeStatusRequest{Id: 10, Reason: "contract terminated"}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.requireAuthenticateError("test_token_suspended", domain.ErrApplicationDisabled.Error())
+
+	app = domain.ApplicationWithTokens{}
+	err = s.api.Invoke("system/application/resume").
+		JsonRequestBody(domain.Identity{Id: 10}).
+		JsonResponseBody(&app).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(domain.StatusActive, app.App.Status)
+	s.Require().Empty(app.App.StatusReason)
+	s.requireAuthenticateError("test_token_suspended", "")
+
+	err = s.api.Invoke("system/application/suspend").
+		JsonRequestBody(domain.ChangeStatusRequest{Id: 10}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+}
+
+func (s *SecureSuite) TestAppGroupStatus() {
+	InsertAppGroup(s.testDb, entity.AppGroup{
+		Id: 11, Name: "suspended_application_group", DomainId: 3, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(),
+	})
+	InsertApplication(s.testDb, entity.Application{
+		Id: 12, Name: "grouped_application", ApplicationGroupId: 11, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(),
+	})
+	InsertToken(s.testDb, entity.Token{
+		TokenHash: hashToken("test_token_group_suspended"), AppId: 12, ExpireTime: -1, CreatedAt: time.Now().UTC(),
+	})
+
+	appGroup := domain.AppGroup{}
+	err := s.api.Invoke("system/application_group/suspend").
+		JsonRequestBody(domain.ChangeStatusRequest{Id: 11, Reason: "maintenance"}).
+		JsonResponseBody(&appGroup).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(domain.StatusSuspended, appGroup.Status)
+	s.Require().Equal("maintenance", appGroup.StatusReason)
+	s.requireAuthenticateError("test_token_group_suspended", domain.ErrApplicationSuspended.Error())
+
+	err = s.api.Invoke("system/application_group/resume").
+		JsonRequestBody(domain.Identity{Id: 11}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.requireAuthenticateError("test_token_group_suspended", "")
+
+	err = s.api.Invoke("system/application_group/disable").
+		JsonRequestBody(domain.ChangeStatusRequest{Id: 100, Reason: "unknown"}).
+		Do(s.T().Context())
+	s.Require().Error(err)
+	apiError := apierrors.FromError(err)
+	s.Require().NotNil(apiError)
+	s.Require().Equal(domain.ErrCodeAppGroupNotFound, apiError.ErrorCode)
+}
+
+func (s *SecureSuite) requireAuthenticateError(token string, expected string) {
+	result := domain.AuthenticateResponse{}
+	err := s.api.Invoke("system/secure/authenticate").
+		JsonRequestBody(domain.AuthenticateRequest{Token: token}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(expected == "", result.Authenticated)
+	s.Require().Equal(expected, result.ErrorReason)
+}
@@ -0,0 +1,113 @@
+package tests_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"isp-system-service/assembly"
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+	"isp-system-service/service"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+)
+
+func TestTokenSweeperSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &TokenSweeperSuite{})
+}
+
+type TokenSweeperSuite struct {
+	suite.Suite
+
+	test    *test.Test
+	testDb  *dbt.TestDb
+	sweeper service.TokenSweeper
+	api     *client.Client
+}
+
+func (s *TokenSweeperSuite) SetupTest() {
+	s.test, _ = test.New(s.T())
+
+	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
+
+	config, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(conf.Remote{
+		Token: conf.Token{
+			Sweep: conf.Sweep{
+				Enabled:          true,
+				GracePeriodHours: 24,
+			},
+		},
+	})
+	s.Require().NoError(err)
+	s.sweeper = config.TokenSweeper
+	_, s.api = grpct.TestServer(s.test, config.Handler)
+
+	createdTime := time.Now().UTC()
+	InsertDomain(s.testDb, entity.Domain{
+		Id: 3, Name: "test_domain", SystemId: 1, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertAppGroup(s.testDb, entity.AppGroup{
+		Id: 5, Name: "test_application_group", DomainId: 3, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertApplication(s.testDb, entity.Application{
+		Id: 7, Name: "test_application", ApplicationGroupId: 5, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+}
+
+func (s *TokenSweeperSuite) TestSweep() {
+	now := time.Now().UTC()
+	InsertToken(s.testDb, entity.Token{
+		Id: "expired_long_ago", TokenHash: hashToken("expired_long_ago"), AppId: 7, CreatedAt: now.Add(-72 * time.Hour),
+		ExpireTime: int(time.Hour.Milliseconds()), ExpiresAt: sql.NullTime{Time: now.Add(-71 * time.Hour), Valid: true},
+	})
+	InsertToken(s.testDb, entity.Token{
+		Id: "expired_recently", TokenHash: hashToken("expired_recently"), AppId: 7, CreatedAt: now.Add(-2 * time.Hour),
+		ExpireTime: int(time.Hour.Milliseconds()), ExpiresAt: sql.NullTime{Time: now.Add(-time.Hour), Valid: true},
+	})
+	InsertToken(s.testDb, entity.Token{
+		Id: "not_expiring", TokenHash: hashToken("not_expiring"), AppId: 7, CreatedAt: now.Add(-72 * time.Hour), ExpireTime: -1,
+	})
+	s.testDb.Must().Exec("INSERT INTO revoked_token (id, expires_at) VALUES ($1, $2), ($3, NULL)",
+		"revoked_long_ago", now.Add(-48*time.Hour), "revoked_not_expiring",
+	)
+
+	status := s.status()
+	s.Require().True(status.Enabled)
+	s.Require().Equal(24, status.GracePeriodHours)
+	s.Require().Nil(status.LastRun)
+
+	s.sweeper.Do(s.T().Context())
+
+	var tokens []string
+	err := s.testDb.Select(s.T().Context(), &tokens, "SELECT id FROM token ORDER BY id")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"expired_recently", "not_expiring"}, tokens)
+	var revoked []string
+	err = s.testDb.Select(s.T().Context(), &revoked, "SELECT id FROM revoked_token")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"revoked_not_expiring"}, revoked)
+
+	status = s.status()
+	s.Require().NotNil(status.LastRun)
+	s.Require().Equal(1, status.LastRun.DeletedTokens)
+	s.Require().Equal(1, status.LastRun.DeletedRevokedTokens)
+	s.Require().Empty(status.LastRun.Error)
+	s.Require().False(status.LastRun.FinishedAt.Before(status.LastRun.StartedAt))
+}
+
+func (s *TokenSweeperSuite) status() domain.TokenSweepStatus {
+	result := domain.TokenSweepStatus{}
+	err := s.api.Invoke("system/token/get_sweep_status").
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
@@ -0,0 +1,287 @@
+package tests_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"isp-system-service/assembly"
+	"isp-system-service/conf"
+	"isp-system-service/domain"
+	"isp-system-service/entity"
+	"isp-system-service/service"
+	"isp-system-service/service/secure"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/txix-open/isp-kit/dbx"
+	"github.com/txix-open/isp-kit/grpc/apierrors"
+	"github.com/txix-open/isp-kit/grpc/client"
+	"github.com/txix-open/isp-kit/test"
+	"github.com/txix-open/isp-kit/test/dbt"
+	"github.com/txix-open/isp-kit/test/grpct"
+)
+
+func TestTokenSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, &TokenSuite{})
+}
+
+type TokenSuite struct {
+	suite.Suite
+
+	test         *test.Test
+	testDb       *dbt.TestDb
+	tokenService service.Token
+	usageFlush   secure.UsageFlushJob
+	api          *client.Client
+}
+
+func (s *TokenSuite) SetupTest() {
+	s.test, _ = test.New(s.T())
+
+	s.testDb = dbt.New(s.test, dbx.WithMigrationRunner("../migrations", s.test.Logger()))
+
+	config, err := assembly.NewLocator(s.testDb, s.test.Logger()).Config(conf.Remote{})
+	s.Require().NoError(err)
+	s.tokenService = config.Token
+	s.usageFlush = config.UsageFlushJob
+	_, s.api = grpct.TestServer(s.test, config.Handler)
+
+	createdTime := time.Now().UTC()
+	InsertDomain(s.testDb, entity.Domain{
+		Id: 3, Name: "test_domain", SystemId: 1, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertAppGroup(s.testDb, entity.AppGroup{
+		Id: 5, Name: "test_application_group", DomainId: 3, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+	InsertApplication(s.testDb, entity.Application{
+		Id: 7, Name: "test_application", ApplicationGroupId: 5, CreatedAt: createdTime, UpdatedAt: createdTime,
+	})
+}
+
+func (s *TokenSuite) TestCreate_OnlyHashIsStored() {
+	created := domain.TokenCreateResponse{}
+	err := s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 7, ExpireTimeMs: -1}).
+		JsonResponseBody(&created).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().NotEmpty(created.Token)
+	s.Require().Len(created.Tokens, 1)
+	listed := created.Tokens[0]
+	s.Require().Regexp(`^isp_[0-9a-f]{16}_[0-9a-f]{256}$`, created.Token)
+	s.Require().True(strings.HasPrefix(created.Token, "isp_"+listed.Id+"_"))
+	s.Require().Equal("isp_"+listed.Id+"_****"+created.Token[len(created.Token)-4:], listed.MaskedToken)
+	s.Require().NotContains(listed.MaskedToken, created.Token[len(created.Token)-8:])
+
+	var stored []string
+	err = s.testDb.Select(s.T().Context(), &stored, "SELECT token_hash FROM token")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{hashToken(created.Token)}, stored)
+
+	s.Require().True(s.authenticate(created.Token).Authenticated)
+
+	err = s.api.Invoke("system/token/revoke_tokens").
+		JsonRequestBody(domain.TokenRevokeRequest{AppId: 7, TokenIdList: []string{listed.Id}}).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().False(s.authenticate(created.Token).Authenticated)
+}
+
+func (s *TokenSuite) TestCreate_ExpiresAt() {
+	before := time.Now().UTC().Add(-time.Second)
+	created := domain.TokenCreateResponse{}
+	err := s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 7, ExpireTimeMs: int(time.Hour.Milliseconds())}).
+		JsonResponseBody(&created).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(created.Tokens, 1)
+	s.Require().NotNil(created.Tokens[0].ExpiresAt)
+	s.Require().WithinRange(*created.Tokens[0].ExpiresAt, before.Add(time.Hour), time.Now().UTC().Add(time.Hour))
+
+	err = s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 7, ExpireTimeMs: -1}).
+		JsonResponseBody(&created).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(created.Tokens, 2)
+	s.Require().Nil(created.Tokens[0].ExpiresAt)
+
+	var expiring int
+	err = s.testDb.SelectRow(s.T().Context(), &expiring, "SELECT count(*) FROM token WHERE expires_at > now() AT TIME ZONE 'utc'")
+	s.Require().NoError(err)
+	s.Require().Equal(1, expiring)
+}
+
+func (s *TokenSuite) TestCreate_Scope() {
+	created := domain.TokenCreateResponse{}
+	err := s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 7, ExpireTimeMs: -1, Scope: []string{"admin/deploy/*"}}).
+		JsonResponseBody(&created).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(created.Tokens, 1)
+	s.Require().Equal([]string{"admin/deploy/*"}, created.Tokens[0].Scope)
+	s.Require().Equal([]string{"admin/deploy/*"}, s.authenticate(created.Token).AuthData.Scope)
+
+	rotated := domain.TokenRotateResponse{}
+	err = s.api.Invoke("system/token/rotate").
+		JsonRequestBody(domain.TokenRotateRequest{AppId: 7, TokenId: created.Tokens[0].Id, ExpireTimeMs: -1}).
+		JsonResponseBody(&rotated).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"admin/deploy/*"}, rotated.NewToken.Scope)
+
+	err = s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 7, ExpireTimeMs: -1, Scope: []string{"admin/**get"}}).
+		Do(s.T().Context())
+	apiError := apierrors.FromError(err)
+	s.Require().NotNil(apiError)
+	s.Require().Equal(domain.ErrCodeInvalidMethodPattern, apiError.ErrorCode)
+}
+
+func (s *TokenSuite) TestRevoke_OnlyApplicationTokens() {
+	InsertApplication(s.testDb, entity.Application{
+		Id: 8, Name: "other_application", ApplicationGroupId: 5, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(),
+	})
+	InsertToken(s.testDb, entity.Token{
+		Id: "token_id", TokenHash: hashToken("token"), AppId: 7, ExpireTime: -1, CreatedAt: time.Now().UTC(),
+	})
+	InsertToken(s.testDb, entity.Token{
+		Id: "other_token_id", TokenHash: hashToken("other_token"), AppId: 8, ExpireTime: -1, CreatedAt: time.Now().UTC(),
+	})
+
+	result := domain.ApplicationWithTokens{}
+	err := s.api.Invoke("system/token/revoke_tokens").
+		JsonRequestBody(domain.TokenRevokeRequest{AppId: 7, TokenIdList: []string{"token_id", "other_token_id"}}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Empty(result.Tokens)
+	s.Require().True(s.authenticate("other_token").Authenticated)
+}
+
+func (s *TokenSuite) TestHashLegacyTokens() {
+	s.testDb.Must().Exec(
+		"INSERT INTO token (id, token_hash, masked_token, app_id, expire_time, hashed) VALUES ($1, $2, $3, $4, $5, false)",
+		"legacy_token_id", "legacy_token", "****", 7, -1,
+	)
+
+	err := s.tokenService.HashLegacyTokens(s.T().Context())
+	s.Require().NoError(err)
+	err = s.tokenService.HashLegacyTokens(s.T().Context())
+	s.Require().NoError(err)
+
+	var stored []string
+	err = s.testDb.Select(s.T().Context(), &stored, "SELECT token_hash FROM token WHERE hashed")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{hashToken("legacy_token")}, stored)
+
+	tokens := s.tokens(7)
+	s.Require().Len(tokens, 1)
+	s.Require().Equal("legacy_token_id", tokens[0].Id)
+	s.Require().Equal("****oken", tokens[0].MaskedToken)
+
+	s.Require().True(s.authenticate("legacy_token").Authenticated)
+}
+
+func (s *TokenSuite) TestRotate() {
+	created := domain.TokenCreateResponse{}
+	err := s.api.Invoke("system/token/create_token").
+		JsonRequestBody(domain.TokenCreateRequest{AppId: 7, ExpireTimeMs: -1}).
+		JsonResponseBody(&created).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+
+	before := time.Now().UTC().Add(-time.Second)
+	rotated := domain.TokenRotateResponse{}
+	err = s.api.Invoke("system/token/rotate").
+		JsonRequestBody(domain.TokenRotateRequest{AppId: 7, TokenId: created.Tokens[0].Id, ExpireTimeMs: -1, OverlapHours: 24}).
+		JsonResponseBody(&rotated).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Equal(created.Tokens[0].Id, rotated.PreviousToken.Id)
+	s.Require().NotNil(rotated.PreviousToken.ExpiresAt)
+	s.Require().WithinRange(*rotated.PreviousToken.ExpiresAt, before.Add(24*time.Hour), time.Now().UTC().Add(24*time.Hour))
+	s.Require().NotEqual(created.Tokens[0].Id, rotated.NewToken.Id)
+	s.Require().Nil(rotated.NewToken.ExpiresAt)
+	s.Require().True(s.authenticate(created.Token).Authenticated)
+	s.Require().True(s.authenticate(rotated.Token).Authenticated)
+
+	s.testDb.Must().Exec(
+		"UPDATE token SET expires_at = (now() AT TIME ZONE 'utc') - INTERVAL '1 minute' WHERE id = $1",
+		created.Tokens[0].Id,
+	)
+	result := s.authenticate(created.Token)
+	s.Require().False(result.Authenticated)
+	s.Require().Equal(domain.ErrTokenExpired.Error(), result.ErrorReason)
+	s.Require().True(s.authenticate(rotated.Token).Authenticated)
+
+	err = s.api.Invoke("system/token/rotate").
+		JsonRequestBody(domain.TokenRotateRequest{AppId: 7, TokenId: "unknown", ExpireTimeMs: -1}).
+		Do(s.T().Context())
+	apiError := apierrors.FromError(err)
+	s.Require().NotNil(apiError)
+	s.Require().Equal(domain.ErrCodeTokenNotFound, apiError.ErrorCode)
+}
+
+func (s *TokenSuite) TestLastUsed() {
+	createdAt := time.Now().UTC().Add(-10 * 24 * time.Hour)
+	InsertToken(s.testDb, entity.Token{
+		Id: "used_token_id", TokenHash: hashToken("used_token"), AppId: 7, ExpireTime: -1, CreatedAt: createdAt,
+	})
+	InsertToken(s.testDb, entity.Token{
+		Id: "unused_token_id", TokenHash: hashToken("unused_token"), AppId: 7, ExpireTime: -1, CreatedAt: createdAt,
+	})
+
+	s.Require().True(s.authenticate("used_token").Authenticated)
+	s.Require().True(s.authenticate("used_token").Authenticated)
+	s.usageFlush.Do(s.T().Context())
+
+	tokens := s.tokens(7)
+	s.Require().Len(tokens, 2)
+	lastUsed := make(map[string]*time.Time)
+	for _, token := range tokens {
+		lastUsed[token.Id] = token.LastUsedAt
+	}
+	s.Require().NotNil(lastUsed["used_token_id"])
+	s.Require().WithinDuration(time.Now().UTC(), *lastUsed["used_token_id"], time.Minute)
+	s.Require().Nil(lastUsed["unused_token_id"])
+
+	unused := make([]domain.Token, 0)
+	err := s.api.Invoke("system/token/get_unused").
+		JsonRequestBody(domain.GetUnusedTokensRequest{AppId: 7, UnusedDays: 5}).
+		JsonResponseBody(&unused).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Len(unused, 1)
+	s.Require().Equal("unused_token_id", unused[0].Id)
+
+	err = s.api.Invoke("system/token/get_unused").
+		JsonRequestBody(domain.GetUnusedTokensRequest{UnusedDays: 30}).
+		JsonResponseBody(&unused).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	s.Require().Empty(unused)
+}
+
+func (s *TokenSuite) authenticate(token string) domain.AuthenticateResponse {
+	result := domain.AuthenticateResponse{}
+	err := s.api.Invoke("system/secure/authenticate").
+		JsonRequestBody(domain.AuthenticateRequest{Token: token}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result
+}
+
+func (s *TokenSuite) tokens(appId int) []domain.Token {
+	result := domain.TokenListResponse{}
+	err := s.api.Invoke("system/token/get_tokens_by_app_id").
+		JsonRequestBody(domain.TokenListRequest{Id: appId}).
+		JsonResponseBody(&result).
+		Do(s.T().Context())
+	s.Require().NoError(err)
+	return result.Items
+}
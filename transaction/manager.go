@@ -22,32 +22,99 @@ func NewManager(db db.Transactional) *Manager {
 
 type accessListSetOneTx struct {
 	repository.AccessList
+	repository.Audit
+	repository.ChangeEvent
 }
 
 func (m Manager) AccessListSetOneTx(ctx context.Context, msgTx func(ctx context.Context, tx service.AccessListSetOneTx) error) error {
 	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
 		accessListRepository := repository.NewAccessList(tx)
 		return msgTx(ctx, accessListSetOneTx{
-			AccessList: accessListRepository,
+			AccessList:  accessListRepository,
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
 		})
 	})
 }
 
 type accessListSetListTx struct {
 	repository.AccessList
+	repository.Audit
+	repository.ChangeEvent
 }
 
 func (m Manager) AccessListSetListTx(ctx context.Context, msgTx func(ctx context.Context, tx service.AccessListSetListTx) error) error {
 	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
 		accessListRep := repository.NewAccessList(tx)
 		return msgTx(ctx, accessListSetListTx{
-			AccessList: accessListRep,
+			AccessList:  accessListRep,
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type accessListSetForAppGroupTx struct {
+	repository.AccessList
+	repository.Audit
+	repository.ChangeEvent
+}
+
+func (m Manager) AccessListSetForAppGroupTx(
+	ctx context.Context,
+	msgTx func(ctx context.Context, tx service.AccessListSetForAppGroupTx) error,
+) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		accessListRep := repository.NewAccessList(tx)
+		return msgTx(ctx, accessListSetForAppGroupTx{
+			AccessList:  accessListRep,
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type accessListSetForDomainTx struct {
+	repository.AccessList
+	repository.Audit
+	repository.ChangeEvent
+}
+
+func (m Manager) AccessListSetForDomainTx(
+	ctx context.Context,
+	msgTx func(ctx context.Context, tx service.AccessListSetForDomainTx) error,
+) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		accessListRep := repository.NewAccessList(tx)
+		return msgTx(ctx, accessListSetForDomainTx{
+			AccessList:  accessListRep,
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type applicationSaveTx struct {
+	repository.Application
+	repository.Audit
+	repository.ChangeEvent
+}
+
+func (m Manager) ApplicationSaveTx(ctx context.Context, msgTx func(ctx context.Context, tx service.ApplicationSaveTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, applicationSaveTx{
+			Application: repository.NewApplication(tx),
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
 		})
 	})
 }
 
 type applicationDeleteTx struct {
 	repository.Application
+	repository.Token
+	repository.Audit
+	repository.ChangeEvent
 }
 
 func (m Manager) ApplicationDeleteTx(ctx context.Context, msgTx func(ctx context.Context, tx service.ApplicationDeleteTx) error) error {
@@ -55,36 +122,191 @@ func (m Manager) ApplicationDeleteTx(ctx context.Context, msgTx func(ctx context
 		applicationRep := repository.NewApplication(tx)
 		return msgTx(ctx, applicationDeleteTx{
 			Application: applicationRep,
+			Token:       repository.NewToken(tx),
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type appGroupSaveTx struct {
+	repository.AppGroup
+	repository.Audit
+}
+
+func (m Manager) AppGroupSaveTx(ctx context.Context, msgTx func(ctx context.Context, tx service.AppGroupSaveTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, appGroupSaveTx{
+			AppGroup: repository.NewAppGroup(tx),
+			Audit:    repository.NewAudit(tx),
+		})
+	})
+}
+
+type appGroupDeleteTx struct {
+	repository.AppGroup
+	repository.Application
+	repository.Token
+	repository.Audit
+	repository.ChangeEvent
+}
+
+func (m Manager) AppGroupDeleteTx(ctx context.Context, msgTx func(ctx context.Context, tx service.AppGroupDeleteTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, appGroupDeleteTx{
+			AppGroup:    repository.NewAppGroup(tx),
+			Application: repository.NewApplication(tx),
+			Token:       repository.NewToken(tx),
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type domainSaveTx struct {
+	repository.Domain
+	repository.Audit
+}
+
+func (m Manager) DomainSaveTx(ctx context.Context, msgTx func(ctx context.Context, tx service.DomainSaveTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, domainSaveTx{
+			Domain: repository.NewDomain(tx),
+			Audit:  repository.NewAudit(tx),
+		})
+	})
+}
+
+type domainDeleteTx struct {
+	repository.Domain
+	repository.AppGroup
+	repository.Application
+	repository.Token
+	repository.Audit
+	repository.ChangeEvent
+}
+
+func (m Manager) DomainDeleteTx(ctx context.Context, msgTx func(ctx context.Context, tx service.DomainDeleteTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, domainDeleteTx{
+			Domain:      repository.NewDomain(tx),
+			AppGroup:    repository.NewAppGroup(tx),
+			Application: repository.NewApplication(tx),
+			Token:       repository.NewToken(tx),
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
 		})
 	})
 }
 
 type tokenCreateTx struct {
 	repository.Token
+	repository.Audit
+	repository.ChangeEvent
 }
 
 func (m Manager) TokenCreateTx(ctx context.Context, msgTx func(ctx context.Context, tx service.TokenCreateTx) error) error {
 	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
 		tokenRep := repository.NewToken(tx)
 		return msgTx(ctx, tokenCreateTx{
-			Token: tokenRep,
+			Token:       tokenRep,
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
 		})
 	})
 }
 
 type tokenRevokeTx struct {
 	repository.Token
+	repository.Audit
+	repository.ChangeEvent
 }
 
 func (m Manager) TokenRevokeTx(ctx context.Context, msgTx func(ctx context.Context, tx service.TokenRevokeTx) error) error {
 	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
 		tokenRep := repository.NewToken(tx)
 		return msgTx(ctx, tokenRevokeTx{
+			Token:       tokenRep,
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+func (m Manager) TokenRotateTx(ctx context.Context, msgTx func(ctx context.Context, tx service.TokenRotateTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		tokenRep := repository.NewToken(tx)
+		return msgTx(ctx, tokenRevokeTx{
+			Token:       tokenRep,
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type tokenHashTx struct {
+	repository.Token
+}
+
+func (m Manager) TokenHashTx(ctx context.Context, msgTx func(ctx context.Context, tx service.TokenHashTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		tokenRep := repository.NewToken(tx)
+		return msgTx(ctx, tokenHashTx{
 			Token: tokenRep,
 		})
 	})
 }
 
+type roleSaveTx struct {
+	repository.Role
+	repository.Audit
+	repository.ChangeEvent
+}
+
+func (m Manager) RoleSaveTx(ctx context.Context, msgTx func(ctx context.Context, tx service.RoleSaveTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		roleRep := repository.NewRole(tx)
+		return msgTx(ctx, roleSaveTx{
+			Role:        roleRep,
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type roleAssignTx struct {
+	repository.Role
+	repository.Audit
+	repository.ChangeEvent
+}
+
+func (m Manager) RoleAssignTx(ctx context.Context, msgTx func(ctx context.Context, tx service.RoleAssignTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		roleRep := repository.NewRole(tx)
+		return msgTx(ctx, roleAssignTx{
+			Role:        roleRep,
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type rateLimitTx struct {
+	repository.RateLimit
+	repository.Audit
+	repository.ChangeEvent
+}
+
+func (m Manager) RateLimitTx(ctx context.Context, msgTx func(ctx context.Context, tx service.RateLimitTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, rateLimitTx{
+			RateLimit:   repository.NewRateLimit(tx),
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
 type baselineTx struct {
 	repository.Locker
 	repository.Domain
@@ -92,6 +314,8 @@ type baselineTx struct {
 	repository.Application
 	repository.AccessList
 	repository.Token
+	repository.Audit
+	repository.ChangeEvent
 }
 
 func (m Manager) BaselineTx(ctx context.Context, txTx func(ctx context.Context, tx baseline.Transaction) error) error {
@@ -103,6 +327,72 @@ func (m Manager) BaselineTx(ctx context.Context, txTx func(ctx context.Context,
 			Application: repository.NewApplication(tx),
 			AccessList:  repository.NewAccessList(tx),
 			Token:       repository.NewToken(tx),
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type signingKeyTx struct {
+	repository.SigningKey
+}
+
+func (m Manager) SigningKeyTx(ctx context.Context, msgTx func(ctx context.Context, tx service.SigningKeyTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, signingKeyTx{
+			SigningKey: repository.NewSigningKey(tx),
+		})
+	})
+}
+
+type tokenSweepTx struct {
+	repository.Locker
+	repository.Token
+}
+
+func (m Manager) TokenSweepTx(ctx context.Context, msgTx func(ctx context.Context, tx service.TokenSweepTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, tokenSweepTx{
+			Locker: repository.NewLocker(tx),
+			Token:  repository.NewToken(tx),
+		})
+	})
+}
+
+type changeEventRelayTx struct {
+	repository.Locker
+	repository.ChangeEvent
+}
+
+func (m Manager) ChangeEventRelayTx(ctx context.Context, msgTx func(ctx context.Context, tx service.ChangeEventRelayTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, changeEventRelayTx{
+			Locker:      repository.NewLocker(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
+		})
+	})
+}
+
+type registryTx struct {
+	repository.Domain
+	repository.AppGroup
+	repository.Application
+	repository.Token
+	repository.AccessList
+	repository.Audit
+	repository.ChangeEvent
+}
+
+func (m Manager) RegistryTx(ctx context.Context, msgTx func(ctx context.Context, tx service.RegistryTx) error) error {
+	return m.db.RunInTransaction(ctx, func(ctx context.Context, tx *db.Tx) error {
+		return msgTx(ctx, registryTx{
+			Domain:      repository.NewDomain(tx),
+			AppGroup:    repository.NewAppGroup(tx),
+			Application: repository.NewApplication(tx),
+			Token:       repository.NewToken(tx),
+			AccessList:  repository.NewAccessList(tx),
+			Audit:       repository.NewAudit(tx),
+			ChangeEvent: repository.NewChangeEvent(tx),
 		})
 	})
 }